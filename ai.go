@@ -7,6 +7,7 @@ import (
 	"errors"
 	"github.com/alh1m1k/ump"
 	"math"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,6 +20,7 @@ var (
 	top                      = Point{0, -1}
 	right                    = Point{1, 0}
 	left                     = Point{-1, 0}
+	directions               = [4]Point{top, right, bottom, left} //fixed order to walk direction maps
 )
 
 type BehaviorControlBuilder struct {
@@ -26,7 +28,13 @@ type BehaviorControlBuilder struct {
 	*collider.Collider
 	*Location
 	*Navigation
+	Difficulty      *AiDifficulty
+	Influence       *InfluenceMap
+	Lockstep        bool //controls drive avatars in game loop, repeatable gym runs
 	projectileProto map[string]*Projectile
+	trees           map[string]*BehaviorTree
+	squads          map[int8]*Squad
+	treeMutex       sync.Mutex
 }
 
 func (receiver *BehaviorControlBuilder) RegisterProjectile(projectile *Projectile) error {
@@ -42,10 +50,43 @@ func (receiver *BehaviorControlBuilder) Build() (*BehaviorControl, error) {
 	instance.Location = receiver.Location
 	instance.Navigation = receiver.Navigation
 	instance.projectileProto = receiver.projectileProto
+	instance.Difficulty = receiver.Difficulty
+	instance.Influence = receiver.Influence
 	instance.builder = receiver
 	return instance, nil
 }
 
+// Squad of team, created on first use
+func (receiver *BehaviorControlBuilder) Squad(team int8) *Squad {
+	receiver.treeMutex.Lock()
+	defer receiver.treeMutex.Unlock()
+	squad, ok := receiver.squads[team]
+	if !ok {
+		squad, _ = NewSquad(team)
+		receiver.squads[team] = squad
+	}
+	return squad
+}
+
+// Tree load tree file once, blueprints referencing same file share it
+func (receiver *BehaviorControlBuilder) Tree(name string) (*BehaviorTree, error) {
+	receiver.treeMutex.Lock()
+	defer receiver.treeMutex.Unlock()
+	if tree, ok := receiver.trees[name]; ok {
+		return tree, nil
+	}
+	payload, err := loadTree(name)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := NewBehaviorTree(name, payload)
+	if err != nil {
+		return nil, err
+	}
+	receiver.trees[name] = tree
+	return tree, nil
+}
+
 type BehaviorControl struct {
 	*controller.Control
 	idle *controller.Control
@@ -53,7 +94,20 @@ type BehaviorControl struct {
 	*Location
 	*Navigation
 	*Behavior
+	Difficulty                   *AiDifficulty
+	Influence                    *InfluenceMap
+	retreat                      Zone    //withdraw destination instead of target, NoZone if none
+	pathJob                      *NavJob //last scheduled, canceled by next one
 	builder                      *BehaviorControlBuilder
+	reactAt                      time.Time //reaction switch postponed until, see ReactionDelay
+	reactBehavior                *Behavior //switch scheduled by See or hear, other switches are not delayed
+	squad                        *Squad
+	slots                        *AiSlots
+	slot                         int
+	seen                         map[*Unit]bool //seen by own eyes, rest of targets reported by squad
+	evaded                       map[*Projectile]bool //evade roll of incoming projectiles
+	tree                         *BehaviorTree
+	treeBehavior                 *Behavior //replace chose pattern and idle when tree is set
 	nextBehavior                 *Behavior
 	avatar                       *Unit
 	target                       *Unit
@@ -70,6 +124,8 @@ type BehaviorControl struct {
 	blockerMap                   map[Point][]collider.Collideable
 	pathCalculated, noPath       bool
 	aiCtx                        context.Context
+	wandering                    context.CancelFunc //lockstep idle, clock driven instead of idle control
+	wanderCommand                controller.Command
 }
 
 func (receiver *BehaviorControl) AttachTo(object *Unit) {
@@ -80,10 +136,14 @@ func (receiver *BehaviorControl) AttachTo(object *Unit) {
 		receiver.Deattach()
 	}
 	receiver.avatar = object
+	if receiver.builder != nil {
+		receiver.squad = receiver.builder.Squad(object.GetAttr().Team)
+		receiver.squad.Join(receiver)
+	}
 
 	if !receiver.disabled {
 		receiver.attach(object)
-		receiver.Next(IdleBehavior)
+		receiver.Next(receiver.decide(receiver.rest()))
 	}
 }
 
@@ -91,14 +151,101 @@ func (receiver *BehaviorControl) Deattach() {
 	receiver.Next(IdleBehavior)
 	receiver.deattach()
 	receiver.Disable()
+	receiver.releaseSlot()
+	if receiver.squad != nil {
+		for unit := range receiver.seen {
+			receiver.squad.Unsighted(receiver, unit)
+		}
+		receiver.squad.Leave(receiver)
+		receiver.squad = nil
+	}
+	receiver.seen = make(map[*Unit]bool)
+	//knowledge belong to avatar, control may be attached to other one later
+	receiver.target, receiver.availableTargets = nil, receiver.availableTargets[0:0]
+	receiver.lastPath, receiver.newPath = nil, nil
+	receiver.solutionCalculated, receiver.pathCalculated, receiver.noPath = false, false, false
 	receiver.avatar = nil
 }
 
+// send command to avatar, in lockstep avatar execute it at once
+func (receiver *BehaviorControl) send(command controller.Command) {
+	if receiver.lockstep() && receiver.avatar != nil {
+		receiver.avatar.Execute(command)
+		return
+	}
+	receiver.commandChanel <- command
+}
+
+// background run fn in own goroutine, in lockstep at once
+func (receiver *BehaviorControl) background(fn func()) {
+	if receiver.lockstep() {
+		fn()
+		return
+	}
+	go fn()
+}
+
+// wander switch random idle commands, in lockstep game clock and seeded rand drive them
+func (receiver *BehaviorControl) wander(enable bool) {
+	if !receiver.lockstep() {
+		if enable {
+			receiver.idle.Enable()
+		} else {
+			receiver.idle.Disable()
+		}
+		return
+	}
+	if enable == (receiver.wandering != nil) {
+		return
+	}
+	if !enable {
+		receiver.wandering()
+		receiver.wandering = nil
+		return
+	}
+	ctx, cancel := context.WithCancel(receiver.aiCtx)
+	receiver.wandering = cancel
+	var step func()
+	step = func() {
+		if ctx.Err() != nil {
+			return
+		}
+		receiver.wanderCommand = controller.IdleCommand(receiver.wanderCommand, rand.Intn)
+		if receiver.avatar != nil {
+			receiver.send(receiver.wanderCommand)
+		}
+		Clock.AfterFunc(controller.IdleDelay(rand.Intn), step)
+	}
+	Clock.AfterFunc(controller.IdleDelay(rand.Intn), step)
+}
+
+// directions to walk direction maps, fixed order in lockstep, map one (random) otherwise
+func (receiver *BehaviorControl) directions() []Point {
+	if receiver.lockstep() {
+		return directions[:]
+	}
+	result := make([]Point, 0, len(directions))
+	for direction := range receiver.blockedDirection {
+		result = append(result, direction)
+	}
+	return result
+}
+
+func (receiver *BehaviorControl) lockstep() bool {
+	return receiver.builder != nil && receiver.builder.Lockstep
+}
+
 func (receiver *BehaviorControl) GetCommandChanel() controller.CommandChanel {
 	return receiver.commandChanel
 }
 
 func (receiver *BehaviorControl) See(object *Unit) {
+	if !receiver.seen[object] {
+		receiver.seen[object] = true
+		if receiver.squad != nil {
+			receiver.squad.Sighted(receiver, object)
+		}
+	}
 	if receiver.target != nil && receiver.target.destroyed {
 		if DEBUG_AI_BEHAVIOR {
 			logger.Printf("object id %d reset target due it destruction")
@@ -123,16 +270,76 @@ func (receiver *BehaviorControl) See(object *Unit) {
 		if DEBUG_AI_BEHAVIOR {
 			logger.Printf("object id %d see object id %d", receiver.avatar.ID, object.ID)
 		}
+		receiver.react(receiver.decide(ChosePatternBehavior))
+		return
 	}
-	receiver.Next(ChosePatternBehavior)
+	receiver.Next(receiver.decide(ChosePatternBehavior))
 }
 
 func (receiver *BehaviorControl) UnSee(object *Unit) {
 	if DEBUG_AI_BEHAVIOR {
 		logger.Printf("object id %d unsee object id %d", receiver.avatar.ID, object.ID)
 	}
+	if receiver.seen[object] {
+		delete(receiver.seen, object)
+		if receiver.squad != nil {
+			receiver.squad.Unsighted(receiver, object) //squad still may see it
+		}
+	}
 	receiver.forget(object)
-	receiver.Next(ChosePatternBehavior)
+	receiver.Next(receiver.decide(ChosePatternBehavior))
+}
+
+// hear about enemy seen by squad member
+func (receiver *BehaviorControl) hear(object *Unit) {
+	if receiver.avatar == nil || receiver.disabled {
+		return
+	}
+	for _, candidate := range receiver.availableTargets {
+		if candidate == object {
+			return
+		}
+	}
+	receiver.memorize(object)
+	if receiver.target == nil || receiver.target.destroyed {
+		if DEBUG_AI_BEHAVIOR {
+			logger.Printf("object id %d hear about object id %d", receiver.avatar.ID, object.ID)
+		}
+		receiver.react(receiver.decide(ChosePatternBehavior))
+	}
+}
+
+// react schedule behavior switch after reaction delay
+func (receiver *BehaviorControl) react(behavior *Behavior) {
+	receiver.reactAt = Clock.Now().Add(receiver.Difficulty.ReactionDelay)
+	receiver.reactBehavior = behavior
+}
+
+// lose enemy nobody in squad see anymore
+func (receiver *BehaviorControl) lose(object *Unit) {
+	if receiver.avatar == nil || receiver.disabled || receiver.seen[object] {
+		return
+	}
+	receiver.forget(object)
+	if receiver.target == object {
+		receiver.Next(receiver.decide(ChosePatternBehavior))
+	}
+}
+
+// reserveSlot take attack position around target, hunt and siege follow it
+func (receiver *BehaviorControl) reserveSlot() {
+	receiver.releaseSlot()
+	if receiver.squad == nil || receiver.target == nil {
+		return
+	}
+	receiver.slots, receiver.slot = receiver.squad.Reserve(receiver.target)
+}
+
+func (receiver *BehaviorControl) releaseSlot() {
+	if receiver.slots != nil {
+		receiver.squad.Release(receiver.slots, receiver.slot)
+		receiver.slots, receiver.slot = nil, AI_SLOT_NONE
+	}
 }
 
 func (receiver *BehaviorControl) UnSeeAll() {
@@ -140,19 +347,19 @@ func (receiver *BehaviorControl) UnSeeAll() {
 }
 
 func (receiver *BehaviorControl) Enable() error {
-	receiver.idle.Enable()
+	receiver.wander(true)
 	if receiver.avatar != nil {
 		receiver.attach(receiver.avatar)
 	}
 	if receiver.IsNeedRecalculateSolution() {
 		receiver.CalculateFireSolution()
 	}
-	receiver.Next(IdleBehavior)
+	receiver.Next(receiver.decide(receiver.rest()))
 	return nil
 }
 
 func (receiver *BehaviorControl) Disable() error {
-	receiver.idle.Disable()
+	receiver.wander(false)
 	if receiver.Behavior != nil {
 		receiver.Behavior.Leave(receiver)
 	}
@@ -182,6 +389,11 @@ func (receiver *BehaviorControl) Update(timeLeft time.Duration) error {
 		receiver.newPath = nil
 		receiver.pathCalculated = true
 	}
+	receiver.watchProjectiles()
+	if behavior := receiver.reactBehavior; behavior != nil && !Clock.Now().Before(receiver.reactAt) {
+		receiver.reactBehavior = nil
+		receiver.Next(behavior)
+	}
 	if behavior := receiver.nextBehavior; behavior != nil {
 		receiver.nextBehavior = nil
 		receiver.next(behavior)
@@ -223,9 +435,25 @@ func (receiver *BehaviorControl) Next(behavior *Behavior) {
 
 func (receiver *BehaviorControl) Copy() controller.Controller {
 	instance, _ := receiver.builder.Build()
+	instance.SetTree(receiver.tree)
 	return instance
 }
 
+// SetTree let behavior tree choose what to do, nil restore hardcoded pattern choice
+func (receiver *BehaviorControl) SetTree(tree *BehaviorTree) {
+	receiver.tree, receiver.treeBehavior = tree, nil
+	if tree != nil {
+		receiver.treeBehavior = tree.Behavior()
+	}
+}
+
+func (receiver *BehaviorControl) decide(fallback *Behavior) *Behavior {
+	if receiver.treeBehavior != nil {
+		return receiver.treeBehavior
+	}
+	return fallback
+}
+
 func (receiver *BehaviorControl) next(behavior *Behavior) {
 	if receiver.Behavior != nil {
 		if DEBUG_AI_BEHAVIOR {
@@ -318,7 +546,7 @@ func (receiver *BehaviorControl) AlignToZone(zone Zone) (done bool) {
 		return true
 	}
 
-	receiver.commandChanel <- moveCommand
+	receiver.send(moveCommand)
 
 	return false
 }
@@ -338,7 +566,7 @@ func (receiver *BehaviorControl) AlignToDirection(direction Point) (done bool) {
 		Pos:   controller.Point(direction),
 	}
 
-	receiver.commandChanel <- moveCommand
+	receiver.send(moveCommand)
 
 	return false
 }
@@ -360,20 +588,27 @@ func (receiver *BehaviorControl) AlignToPoint(point Point) (done bool) {
 		Pos:   controller.Point(direction),
 	}
 
-	receiver.commandChanel <- moveCommand
+	receiver.send(moveCommand)
 
 	return false
 }
 
 func (receiver *BehaviorControl) GetFollowZone() Zone {
+	if receiver.retreat != NoZone {
+		return receiver.retreat
+	}
 	if receiver.target == nil {
 		return NoZone
 	}
 	tzone := receiver.target.GetZone()
-	return Zone{
-		X: tzone.X + receiver.targetOffset.X,
-		Y: tzone.Y + receiver.targetOffset.Y,
-	}
+	offset := receiver.targetOffset
+	if receiver.slots != nil && receiver.slots.Unit == receiver.target {
+		slotOffset := receiver.slots.Offset(receiver.slot)
+		offset.X += slotOffset.X
+		offset.Y += slotOffset.Y
+	}
+	//slot offset may push it out of map
+	return receiver.Location.ClampZone(Zone{X: tzone.X + offset.X, Y: tzone.Y + offset.Y})
 }
 
 func (receiver *BehaviorControl) GetDirection2Zone(zone Zone) Point {
@@ -435,11 +670,11 @@ func (receiver *BehaviorControl) Stop() (done bool) {
 	if receiver.IsStop() {
 		return true
 	}
-	receiver.commandChanel <- controller.Command{
+	receiver.send(controller.Command{
 		CType:  controller.CTYPE_MOVE,
 		Pos:    controller.PosIrrelevant,
 		Action: false,
-	}
+	})
 	return false
 }
 
@@ -495,11 +730,11 @@ func (receiver *BehaviorControl) MoveToZone(zone Zone, timeLeft time.Duration) (
 	}
 
 	if moveCommand.Pos.X == 0 && moveCommand.Pos.Y == 0 {
-		receiver.commandChanel <- controller.Command{
+		receiver.send(controller.Command{
 			CType:  controller.CTYPE_MOVE,
 			Pos:    controller.PosIrrelevant,
 			Action: false,
-		}
+		})
 		return true, nil
 	} else {
 		if ok := receiver.blockedDirection[Point{moveCommand.Pos.X, 0}]; ok {
@@ -519,8 +754,8 @@ func (receiver *BehaviorControl) MoveToZone(zone Zone, timeLeft time.Duration) (
 		moveCommand.Pos.X = 0
 	}
 
-	receiver.commandChanel <- moveCommand
-	receiver.commandChanel <- speedCommand
+	receiver.send(moveCommand)
+	receiver.send(speedCommand)
 
 	return false, nil
 }
@@ -566,6 +801,12 @@ func (receiver *BehaviorControl) CountBlockedDirection() (counter int8) {
 	return counter
 }
 
+// IsBreakable zone has obstacle planned path may shoot through
+func (receiver *BehaviorControl) IsBreakable(zone Zone) bool {
+	wall, ok := receiver.Location.ZoneObject(zone, LOCATION_LAYER_UNIT).(*Wall)
+	return ok && !wall.destroyed && wall.HasTag("obstacle") && !wall.HasTag("low") && wall.HasTag("vulnerable")
+}
+
 func (receiver *BehaviorControl) CanHit(point Point) bool {
 	return true
 }
@@ -577,11 +818,17 @@ func (receiver *BehaviorControl) Fire() bool {
 	if receiver.avatar.IsReloading() {
 		return true
 	}
-	receiver.commandChanel <- controller.Command{
+	if receiver.Difficulty.HoldFire() {
+		return true
+	}
+	if receiver.slots != nil && !receiver.squad.FireTurn(receiver.slots, receiver) {
+		return true //stagger squad fire
+	}
+	receiver.send(controller.Command{
 		CType:  controller.CTYPE_FIRE,
 		Pos:    controller.PosIrrelevant,
 		Action: true,
-	}
+	})
 	return false
 }
 
@@ -593,13 +840,19 @@ func (receiver *BehaviorControl) OnIndexUpdate(tracker *Tracker) {
 	//tx, ty := receiver.target.GetTracker().GetIndexes()
 	follow := receiver.GetFollowZone()
 	receiver.pathCalculated = false
-	receiver.Navigation.SchedulePath(Zone{
+	if receiver.target.HasTag("base") && receiver.retreat == NoZone { //base doesn't move, whole siege share flow field
+		if path, ok := receiver.Navigation.Flow(follow).Path(Zone{X: ax, Y: ay}); ok {
+			receiver.newPath = path
+			return
+		}
+	}
+	receiver.schedulePath(Zone{
 		X: ax,
 		Y: ay,
 	}, Zone{
 		X: follow.X,
 		Y: follow.Y,
-	}, receiver)
+	})
 }
 
 func (receiver *BehaviorControl) NewPath() {
@@ -609,17 +862,58 @@ func (receiver *BehaviorControl) NewPath() {
 	ax, ay := receiver.avatar.GetTracker().GetIndexes()
 	tx, ty := receiver.target.GetTracker().GetIndexes()
 	receiver.pathCalculated = false
-	if err := receiver.Navigation.SchedulePath(Zone{
+	if err := receiver.schedulePath(Zone{
 		X: ax,
 		Y: ay,
 	}, Zone{
 		X: tx,
 		Y: ty,
-	}, receiver); err != nil {
+	}); err != nil {
 		logger.Println(err)
 	}
 }
 
+// schedulePath replacing previous one, it is stale by now
+func (receiver *BehaviorControl) schedulePath(from, to Zone) error {
+	receiver.cancelPath()
+	job, err := receiver.Navigation.SchedulePath(from, to, receiver, receiver.pathPriority())
+	if err != nil {
+		return err
+	}
+	receiver.pathJob = job
+	return nil
+}
+
+func (receiver *BehaviorControl) cancelPath() {
+	if job := receiver.pathJob; job != nil {
+		job.Cancel()
+		receiver.pathJob = nil
+	}
+	if receiver.Navigation != nil {
+		receiver.Navigation.Forget(receiver)
+	}
+}
+
+// PathPosition zone of avatar, navigation repair the path while avatar follows it
+func (receiver *BehaviorControl) PathPosition() (Zone, Trackable) {
+	avatar := receiver.avatar
+	if avatar == nil {
+		return NoZone, nil
+	}
+	return avatar.GetZone(), avatar
+}
+
+// pathPriority units after player or base first, allies keeping post last
+func (receiver *BehaviorControl) pathPriority() int {
+	switch target := receiver.target; {
+	case target != nil && (target.HasTag("player") || target.HasTag("base")):
+		return NAV_PRIORITY_HIGH
+	case target == nil && receiver.ally():
+		return NAV_PRIORITY_LOW
+	}
+	return NAV_PRIORITY_NORMAL
+}
+
 func (receiver *BehaviorControl) ReceivePath(path []Zone, jobId int64) {
 	for {
 		if jobId <= receiver.newPathId {
@@ -634,6 +928,7 @@ func (receiver *BehaviorControl) ReceivePath(path []Zone, jobId int64) {
 }
 
 func (receiver *BehaviorControl) LookupFireSolution(solution []*FireSolutionSample, distance float64) *FireSolutionSample {
+	distance = receiver.Difficulty.SolutionDistance(distance)
 	if distance < 0 || len(solution) <= int(distance) {
 		return nil
 	}
@@ -671,6 +966,7 @@ func (receiver *BehaviorControl) deattach() {
 	if receiver.avatar == nil {
 		return
 	}
+	receiver.cancelPath()
 	if receiver.avatar.VisionInteractions != nil {
 		//deatach
 	}
@@ -774,6 +1070,9 @@ func NewAIControlBuilder(collider *collider.Collider, location *Location, nav *N
 		Location:        location,
 		Navigation:      nav,
 		projectileProto: make(map[string]*Projectile),
+		trees:           make(map[string]*BehaviorTree),
+		squads:          make(map[int8]*Squad),
+		Difficulty:      AiDifficulties[AI_DIFFICULTY_DEFAULT],
 	}, nil
 }
 
@@ -789,6 +1088,10 @@ func NewAIControl() (*BehaviorControl, error) {
 		Navigation:         nil,
 		Behavior:           nil,
 		availableTargets:   make([]*Unit, 0),
+		slot:               AI_SLOT_NONE,
+		retreat:            NoZone,
+		seen:               make(map[*Unit]bool),
+		evaded:             make(map[*Projectile]bool),
 		disabled:           true,
 		solutionCalculated: false,
 		commandChanel:      make(chan controller.Command),
@@ -805,7 +1108,8 @@ func NewAIControl() (*BehaviorControl, error) {
 			top:    make([]collider.Collideable, 0),
 			bottom: make([]collider.Collideable, 0),
 		},
-		aiCtx: context.Background(),
+		Difficulty: AiDifficulties[AI_DIFFICULTY_DEFAULT],
+		aiCtx:      context.Background(),
 	}
 	go func(instance *BehaviorControl, input controller.CommandChanel, output chan controller.Command) {
 		for {
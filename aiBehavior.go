@@ -11,6 +11,8 @@ import (
 	"time"
 )
 
+const AI_BLOCKED_WAIT = 2 * time.Second //tank or disappearing wall in the way is waited for that long
+
 var (
 	aiBuf, _        = os.OpenFile("ai.txt", os.O_CREATE|os.O_TRUNC, 644)
 	aiLogger        = log.New(aiBuf, "logger: ", log.Lshortfile)
@@ -34,23 +36,23 @@ var (
 		Check: OkOp,
 		Enter: func(control *BehaviorControl) {
 			control.target = nil
-			control.idle.Enable()
+			control.wander(true)
 		},
 		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
 			if control.blockedDirection[control.avatar.Direction] {
 				if CycleID%3 == 0 {
-					go control.Fire()
+					control.background(func() { control.Fire() })
 					return false
 				}
-				for dir, blocked := range control.blockedDirection {
-					if !blocked {
-						go func() { //todo remove
-							control.commandChanel <- controller.Command{
+				for _, dir := range control.directions() {
+					if !control.blockedDirection[dir] {
+						control.background(func() { //todo remove
+							control.send(controller.Command{
 								CType:  controller.CTYPE_DIRECTION,
 								Pos:    controller.Point(dir),
 								Action: true,
-							}
-						}()
+							})
+						})
 						break
 					}
 				}
@@ -58,7 +60,7 @@ var (
 			return false
 		},
 		Leave: func(control *BehaviorControl) {
-			control.idle.Disable()
+			control.wander(false)
 		},
 		Next: NoOp,
 	}
@@ -66,35 +68,8 @@ var (
 		name:  "chose",
 		Check: OkOp,
 		Enter: func(control *BehaviorControl) {
-			if len(control.availableTargets) > 0 {
-				var base, unit *Unit
-				control.target = nil
-				for _, targetCandidate := range control.availableTargets {
-					if targetCandidate == nil || targetCandidate.destroyed {
-						continue
-					}
-					if targetCandidate.HasTag("base") && base == nil {
-						base = targetCandidate
-					} else if unit == nil {
-						unit = targetCandidate
-					}
-				}
-				if base != nil && unit != nil {
-					if rand.Intn(3) >= 2 {
-						control.target = base
-					} else {
-						control.target = unit
-					}
-				} else {
-					if base != nil {
-						control.target = base
-					} else {
-						control.target = unit
-					}
-				}
-			}
-			if control.target == nil {
-				control.Next(IdleBehavior)
+			if !chooseTarget(control) {
+				control.Next(control.rest())
 				return
 			}
 			if control.IsNeedRecalculateSolution() {
@@ -109,6 +84,7 @@ var (
 								control.targetOffset.Y *= rand.Intn(2) - 1*/
 				//aiLogger.Print(control.targetOffset)
 			}
+			control.targetOffset = control.Difficulty.AimOffset()
 			if control.target.HasTag("base") {
 				control.Next(NewSiegeBehavior())
 			} else {
@@ -123,7 +99,7 @@ var (
 		name: "opportunityFire",
 		Check: func(control *BehaviorControl) bool {
 			if control.IsNeedRecalculateSolution() {
-				go control.CalculateFireSolution()
+				control.background(func() { control.CalculateFireSolution() })
 				return false
 			}
 			target := control.target
@@ -152,7 +128,7 @@ var (
 			var weaponSolution *FireSolution
 
 			if control.IsNeedRecalculateSolution() {
-				go control.CalculateFireSolution()
+				control.background(func() { control.CalculateFireSolution() })
 				return false
 			}
 
@@ -224,12 +200,69 @@ var (
 	}
 )
 
+// blockerGone check of idle after move is blocked: tank or disappearing wall in the way is waited for until it
+// moves off, path is repaired around it or AI_BLOCKED_WAIT passes
+func blockerGone(control *BehaviorControl) func(control *BehaviorControl) bool {
+	next := control.lastPath[0]
+	if next == control.avatar.GetZone() && len(control.lastPath) > 1 { //centering in own zone
+		next = control.lastPath[1]
+	}
+	blocker := control.Location.ZoneObject(next, LOCATION_LAYER_UNIT)
+	until := Clock.Now().Add(AI_BLOCKED_WAIT)
+	return func(control *BehaviorControl) bool {
+		//path is dropped on leave, new one may go around
+		if blocker != nil && blocker != Trackable(control.avatar) && Clock.Now().Before(until) &&
+			(len(control.lastPath) == 0 || zoneIndex(control.lastPath, next) != -1) &&
+			control.Location.ZoneObject(next, LOCATION_LAYER_UNIT) == blocker {
+			return false
+		}
+		return !control.IsFullBlock()
+	}
+}
+
+// chooseTarget pick target from seen ones, base not always preferred
+func chooseTarget(control *BehaviorControl) bool {
+	if ordered := control.orderedTarget(); ordered != nil {
+		control.target = ordered
+		return true
+	}
+	if len(control.availableTargets) > 0 {
+		var base, unit *Unit
+		control.target = nil
+		for _, targetCandidate := range control.availableTargets {
+			if targetCandidate == nil || targetCandidate.destroyed {
+				continue
+			}
+			if targetCandidate.HasTag("base") && base == nil {
+				base = targetCandidate
+			} else if unit == nil {
+				unit = targetCandidate
+			}
+		}
+		if base != nil && unit != nil {
+			if control.Difficulty.PreferSiege() {
+				control.target = base
+			} else {
+				control.target = unit
+			}
+		} else {
+			if base != nil {
+				control.target = base
+			} else {
+				control.target = unit
+			}
+		}
+	}
+	return control.target != nil
+}
+
 func NewHuntBehavior() *Behavior {
 	pursuitBehavior := NewPursuitBehavior()
 	return &Behavior{
 		name:  "hunt",
 		Check: OkOp,
 		Enter: func(control *BehaviorControl) {
+			control.reserveSlot()
 			pursuitBehavior.Enter(control)
 		},
 		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
@@ -277,6 +310,7 @@ func NewHuntBehavior() *Behavior {
 		},
 		Leave: func(control *BehaviorControl) {
 			pursuitBehavior.Leave(control)
+			control.releaseSlot()
 		},
 		Next: NoOp,
 	}
@@ -290,13 +324,15 @@ func NewSiegeBehavior() *Behavior {
 	behavior.Enter = func(control *BehaviorControl) {
 		oldOffset = control.targetOffset
 		control.targetOffset.X, control.targetOffset.Y = 0, 0 //hardcode
-		switch rand.Intn(3) {
-		case 0: //left
-			control.targetOffset.X -= 2
-		case 1: //top
-			control.targetOffset.Y -= 2
-		case 2: //right
-			control.targetOffset.X = 2
+		if control.squad == nil { //otherwise side is squad slot
+			switch rand.Intn(3) {
+			case 0: //left
+				control.targetOffset.X -= 2
+			case 1: //top
+				control.targetOffset.Y -= 2
+			case 2: //right
+				control.targetOffset.X = 2
+			}
 		}
 		oldEnter(control)
 	}
@@ -326,6 +362,10 @@ func NewPursuitBehavior() *Behavior {
 				return
 			}
 			if len(control.lastPath) > 0 {
+				if next := control.lastPath[0]; control.IsBreakable(next) && next != control.avatar.GetZone() {
+					control.Next(NewBreachBehavior(next, control.Behavior)) //path planned through it
+					return false
+				}
 				if done, err := control.MoveToZone(control.lastPath[0], duration); done {
 					control.lastPath = control.lastPath[1:]
 					if len(control.lastPath) == 0 {
@@ -343,9 +383,7 @@ func NewPursuitBehavior() *Behavior {
 					if DEBUG_AI_PATH {
 						logger.Printf("cycleId: %d, objectId: %d moving blocked -> %t, %t\n", CycleID, control.avatar.ID, control.avatar.GetZone(), control.lastPath[0])
 					}
-					control.Next(NewIdleUntilBehavior(func(control *BehaviorControl) bool {
-						return !control.IsFullBlock()
-					}, control.Behavior, true))
+					control.Next(NewIdleUntilBehavior(blockerGone(control), control.Behavior, true))
 				}
 			} else {
 				if control.noPath {
@@ -379,6 +417,7 @@ func NewPursuitBehavior() *Behavior {
 		},
 		Leave: func(control *BehaviorControl) {
 			pathBehavior.Leave(control)
+			control.cancelPath()
 			control.lastPath = nil
 			control.newPath = nil
 		},
@@ -393,7 +432,10 @@ func NewPathBehavior(updateDl time.Duration) *Behavior {
 		Enter: func(control *BehaviorControl) {
 			control.target.GetTracker().Subscribe(control)
 			control.OnIndexUpdate(nil)
-			everyFunc(updateDl, func() { //todo respect lastUpdate time
+			if control.Difficulty.ReplanInterval > 0 {
+				updateDl = control.Difficulty.ReplanInterval
+			}
+			Clock.Every(updateDl, func() { //todo respect lastUpdate time
 				control.OnIndexUpdate(nil)
 			}, ctx)
 		},
@@ -410,7 +452,7 @@ func NewIdleUntilBehavior(check func(control *BehaviorControl) bool, toBehavior
 		name:  "idleUntil",
 		Check: OkOp,
 		Enter: func(control *BehaviorControl) {
-			control.idle.Enable()
+			control.wander(true)
 			if keepTrack {
 				control.target.GetTracker().Subscribe(control)
 				control.OnIndexUpdate(nil)
@@ -437,7 +479,7 @@ func NewMove2Behavior(zone Zone, toBehavior *Behavior, keepTrack bool) *Behavior
 		Enter: func(control *BehaviorControl) {
 			control.lastPath = nil
 			control.newPath = nil
-			control.Navigation.SchedulePath(control.avatar.GetZone(), zone, control)
+			control.schedulePath(control.avatar.GetZone(), zone)
 		},
 		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
 			if control.lastPath == nil { //wait for the path
@@ -462,9 +504,7 @@ func NewMove2Behavior(zone Zone, toBehavior *Behavior, keepTrack bool) *Behavior
 					}
 				} else if err == MoveBlockedError {
 					logger.Printf("objectId: %d moving blocked -> %t, %t\n", control.avatar.ID, control.avatar.GetZone(), control.lastPath[0])
-					control.Next(NewIdleUntilBehavior(func(control *BehaviorControl) bool {
-						return !control.IsFullBlock()
-					}, control.Behavior, true))
+					control.Next(NewIdleUntilBehavior(blockerGone(control), control.Behavior, true))
 				}
 			} else {
 				if control.noPath {
@@ -482,31 +522,6 @@ func NewMove2Behavior(zone Zone, toBehavior *Behavior, keepTrack bool) *Behavior
 		Next: NoOp,
 	}
 }
-func NewEvadeBehavior(check func(control *BehaviorControl) bool, toBehavior *Behavior, keepTrack bool) *Behavior {
-	return &Behavior{
-		name:  "evade",
-		Check: OkOp,
-		Enter: func(control *BehaviorControl) {
-			control.idle.Enable()
-			if keepTrack {
-				control.target.GetTracker().Subscribe(control)
-				control.OnIndexUpdate(nil)
-			}
-		},
-		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
-			if check(control) {
-				control.Next(toBehavior)
-				return true
-			} else {
-				return false
-			}
-		},
-		Leave: func(control *BehaviorControl) {
-			control.target.GetTracker().Unsubscribe(control)
-		},
-		Next: NoOp,
-	}
-}
 func NewClearPathBehavior(next func(control *BehaviorControl), blockedThreshold int8) *Behavior {
 	blockedThreshold = int8(minInt(maxInt(0, int(blockedThreshold)), 4))
 	return &Behavior{
@@ -529,8 +544,8 @@ func NewClearPathBehavior(next func(control *BehaviorControl), blockedThreshold
 				}
 			}
 			if target != nil {
-				for cdir, candidates := range control.blockerMap {
-					for _, candidate := range candidates {
+				for _, cdir := range control.directions() {
+					for _, candidate := range control.blockerMap[cdir] {
 						if candidate.HasTag("vulnerable") && !candidate.HasTag("explosive") {
 							target = candidate
 							direction = cdir
@@ -556,6 +571,30 @@ func NewClearPathBehavior(next func(control *BehaviorControl), blockedThreshold
 		Next:  next,
 	}
 }
+// NewBreachBehavior shoot destructible obstacle path goes through, then back to behavior
+func NewBreachBehavior(zone Zone, toBehavior *Behavior) *Behavior {
+	return &Behavior{
+		name:  "breach",
+		Check: OkOp,
+		Enter: NoOp,
+		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
+			if !control.IsBreakable(zone) {
+				control.Next(toBehavior)
+				return false
+			}
+			if control.avatar.moving {
+				control.send(controller.Command{CType: controller.CTYPE_MOVE, Pos: controller.PosIrrelevant, Action: false})
+				return false
+			}
+			if control.AlignToZone(zone) {
+				control.Fire()
+			}
+			return false
+		},
+		Leave: NoOp,
+		Next:  NoOp,
+	}
+}
 func NewAttackBehavior(next func(control *BehaviorControl)) *Behavior {
 	return &Behavior{
 		name:  "attack",
@@ -598,11 +637,18 @@ func NewAttackBehavior(next func(control *BehaviorControl)) *Behavior {
 	}
 }
 func NewWithdrawalBehavior() *Behavior {
+	const WITHDRAW_RADIUS = 6
 	pursuitBehavior := NewPursuitBehavior()
 	return &Behavior{
 		name:  "widraw",
 		Check: OkOp,
 		Enter: func(control *BehaviorControl) {
+			if control.Influence != nil {
+				zone := control.avatar.GetZone()
+				if safe, ok := control.Influence.SafestZone(control.avatar.GetAttr().Team, zone, WITHDRAW_RADIUS); ok && safe != zone {
+					control.retreat = safe
+				}
+			}
 			pursuitBehavior.Enter(control)
 		},
 		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
@@ -610,6 +656,7 @@ func NewWithdrawalBehavior() *Behavior {
 		},
 		Leave: func(control *BehaviorControl) {
 			pursuitBehavior.Leave(control)
+			control.retreat = NoZone
 		},
 		Next: NoOp,
 	}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	direct "github.com/buger/goterm"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	AI_DEBUG_ZINDEX       = 500 //over units, under ui
+	AI_DEBUG_SOLUTION_LEN = 12  //gun line samples shown
+	AI_DEBUG_LINE_GAP     = 2   //target line start and end that far from units
+)
+
+var aiDebugArrows = map[Point]string{top: "↑", bottom: "↓", left: "←", right: "→"}
+
+// AiDebugOverlay draws what ai is thinking over the map: behavior and blocked directions above tank, target line,
+// planned path and fire solution samples along the gun line (flight time in tenths of second)
+type AiDebugOverlay struct {
+	spawner  *SpawnManager
+	location *Location
+	render   Renderer
+	enabled  bool
+	markers  []*overlayMarker
+	used     int
+	mutex    sync.Mutex
+}
+
+func (receiver *AiDebugOverlay) Enable(enabled bool) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.enabled = enabled
+}
+
+func (receiver *AiDebugOverlay) Toggle() bool {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.enabled = !receiver.enabled
+	return receiver.enabled
+}
+
+func (receiver *AiDebugOverlay) Update(timeLeft time.Duration) error {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	previous := receiver.used
+	receiver.used = 0
+	if receiver.enabled {
+		for _, object := range receiver.spawner.QuerySpawnedByTag("ai") {
+			unit, ok := object.(*Unit)
+			if !ok || unit.destroyed {
+				continue
+			}
+			if control, ok := unit.Control.(*BehaviorControl); ok && control.avatar == unit {
+				receiver.draw(control)
+			}
+		}
+	}
+	for i := receiver.used; i < previous; i++ {
+		receiver.markers[i].Buf.Reset()
+	}
+	return nil
+}
+
+func (receiver *AiDebugOverlay) draw(control *BehaviorControl) {
+	avatar := control.avatar
+	acenter := avatar.GetCenter()
+
+	for _, zone := range control.lastPath {
+		if center, err := receiver.location.CenterByIndex(zone.X, zone.Y); err == nil {
+			receiver.mark(Point(center), "∙", direct.BLUE)
+		}
+	}
+
+	if target := control.target; target != nil && !target.destroyed {
+		tcenter := target.GetCenter()
+		dx, dy := tcenter.X-acenter.X, tcenter.Y-acenter.Y
+		steps := int(math.Max(math.Abs(dx), math.Abs(dy)))
+		for i := AI_DEBUG_LINE_GAP; i <= steps-AI_DEBUG_LINE_GAP; i++ {
+			fraction := float64(i) / float64(steps)
+			receiver.mark(Point{X: acenter.X + dx*fraction, Y: acenter.Y + dy*fraction}, "·", direct.YELLOW)
+		}
+	}
+
+	if control.solutionCalculated && control.solution != nil && avatar.Direction != NoPos {
+		samples := control.solution.sampleY
+		if avatar.Direction.X != 0 {
+			samples = control.solution.sampleX
+		}
+		for i := 1; i < len(samples) && i <= AI_DEBUG_SOLUTION_LEN; i++ {
+			sample := samples[i]
+			point := Point{X: acenter.X + avatar.Direction.X*sample.distance, Y: acenter.Y + avatar.Direction.Y*sample.distance}
+			receiver.mark(point, fmt.Sprint(int(sample.enter/(100*time.Millisecond))%10), direct.MAGENTA)
+		}
+	}
+
+	behavior := "-"
+	if control.Behavior != nil {
+		behavior = control.Behavior.Name()
+	}
+	label := fmt.Sprintf("%d %s", avatar.GetAttr().ID, behavior)
+	if control.target != nil {
+		label += fmt.Sprintf(">%d", control.target.GetAttr().ID)
+	}
+	blocked := ""
+	for _, direction := range []Point{top, bottom, left, right} {
+		if control.blockedDirection[direction] {
+			blocked += aiDebugArrows[direction]
+		}
+	}
+	if control.noPath {
+		blocked += "nopath"
+	}
+	position := avatar.GetXY()
+	position.Y--
+	text := direct.Color(label, direct.CYAN)
+	if blocked != "" {
+		text += " " + direct.Color(blocked, direct.RED)
+	}
+	receiver.place(position, text, len([]rune(label))+len([]rune(blocked))+1)
+}
+
+func (receiver *AiDebugOverlay) mark(point Point, char string, color int) {
+	receiver.place(point, direct.Color(char, color), 1)
+}
+
+// place take next marker from pool, pool grow on demand and never shrink
+func (receiver *AiDebugOverlay) place(point Point, text string, width int) {
+	if receiver.used == len(receiver.markers) {
+		marker := &overlayMarker{Sprite: NewSprite(), zIndex: AI_DEBUG_ZINDEX}
+		receiver.markers = append(receiver.markers, marker)
+		receiver.render.Add(marker)
+	}
+	marker := receiver.markers[receiver.used]
+	receiver.used++
+	marker.Point = point
+	marker.Size.W, marker.Size.H = width, 1
+	marker.Buf.Reset()
+	marker.Buf.WriteString(text)
+}
+
+func NewAiDebugOverlay(spawner *SpawnManager, location *Location, render Renderer) (*AiDebugOverlay, error) {
+	return &AiDebugOverlay{
+		spawner:  spawner,
+		location: location,
+		render:   render,
+		markers:  make([]*overlayMarker, 0, 100),
+	}, nil
+}
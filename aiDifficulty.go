@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const AI_DIFFICULTY_DEFAULT = "normal"
+
+var UnknownDifficultyError = errors.New("unknown ai difficulty")
+
+// AiDifficultyOrder easiest first, every next level is better at everything
+var AiDifficultyOrder = []string{"easy", "normal", "hard", "insane"}
+
+// AiDifficulty tune how fast and how precise ai is, insane aims and reacts as original ai did
+type AiDifficulty struct {
+	Name           string
+	ReactionDelay  time.Duration //before ai react on new enemy in sight
+	AimError       float64       //max zones added to target offset, rounded
+	SolutionError  float64       //max distance error while lookup fire solution sample
+	FireChance     float64       //chance to pull the trigger when allowed, rest is held fire
+	ReplanInterval time.Duration //path to target recalculation
+	SiegeChance    float64       //chance to choose base over unit when both seen
+	EvadeHorizon   time.Duration //how early incoming projectile is noticed, 0 never evade
+	EvadeChance    float64       //chance to dodge noticed projectile
+}
+
+var AiDifficulties = map[string]*AiDifficulty{
+	"easy": {
+		Name:           "easy",
+		ReactionDelay:  800 * time.Millisecond,
+		AimError:       2,
+		SolutionError:  3,
+		FireChance:     0.4,
+		ReplanInterval: 2 * time.Second,
+		SiegeChance:    0.15,
+		EvadeHorizon:   250 * time.Millisecond,
+		EvadeChance:    0.25,
+	},
+	"normal": {
+		Name:           "normal",
+		ReactionDelay:  300 * time.Millisecond,
+		AimError:       1,
+		SolutionError:  1.5,
+		FireChance:     0.75,
+		ReplanInterval: time.Second,
+		SiegeChance:    1.0 / 3,
+		EvadeHorizon:   500 * time.Millisecond,
+		EvadeChance:    0.5,
+	},
+	"hard": {
+		Name:           "hard",
+		ReactionDelay:  120 * time.Millisecond,
+		AimError:       0.6,
+		SolutionError:  0.5,
+		FireChance:     0.9,
+		ReplanInterval: 500 * time.Millisecond,
+		SiegeChance:    0.45,
+		EvadeHorizon:   800 * time.Millisecond,
+		EvadeChance:    0.8,
+	},
+	"insane": {
+		Name:           "insane",
+		FireChance:     1,
+		ReplanInterval: 250 * time.Millisecond,
+		SiegeChance:    0.6,
+		EvadeHorizon:   1200 * time.Millisecond,
+		EvadeChance:    1,
+	},
+}
+
+func (receiver *AiDifficulty) AimOffset() Zone {
+	if receiver.AimError <= 0 {
+		return Zone{}
+	}
+	return Zone{
+		X: int(math.Round((rand.Float64()*2 - 1) * receiver.AimError)),
+		Y: int(math.Round((rand.Float64()*2 - 1) * receiver.AimError)),
+	}
+}
+
+func (receiver *AiDifficulty) SolutionDistance(distance float64) float64 {
+	if receiver.SolutionError <= 0 {
+		return distance
+	}
+	return distance + (rand.Float64()*2-1)*receiver.SolutionError
+}
+
+func (receiver *AiDifficulty) HoldFire() bool {
+	return receiver.FireChance < 1 && rand.Float64() >= receiver.FireChance
+}
+
+func (receiver *AiDifficulty) PreferSiege() bool {
+	return rand.Float64() < receiver.SiegeChance
+}
+
+func (receiver *AiDifficulty) Evade() bool {
+	return receiver.EvadeChance >= 1 || rand.Float64() < receiver.EvadeChance
+}
+
+func GetAiDifficulty(name string) (*AiDifficulty, error) {
+	if name == "" {
+		name = AI_DIFFICULTY_DEFAULT
+	}
+	if difficulty, ok := AiDifficulties[name]; ok {
+		return difficulty, nil
+	}
+	return nil, fmt.Errorf("%s: %w", name, UnknownDifficultyError)
+}
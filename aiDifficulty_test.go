@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestAiDifficultyOrder(t *testing.T) {
+	for i := 1; i < len(AiDifficultyOrder); i++ {
+		easier, _ := GetAiDifficulty(AiDifficultyOrder[i-1])
+		harder, err := GetAiDifficulty(AiDifficultyOrder[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !(harder.ReactionDelay < easier.ReactionDelay && harder.AimError < easier.AimError &&
+			harder.SolutionError < easier.SolutionError && harder.FireChance > easier.FireChance &&
+			harder.ReplanInterval < easier.ReplanInterval && harder.SiegeChance > easier.SiegeChance &&
+			harder.EvadeHorizon > easier.EvadeHorizon && harder.EvadeChance > easier.EvadeChance) {
+			t.Errorf("%s is not harder than %s: %+v %+v", harder.Name, easier.Name, harder, easier)
+		}
+	}
+	if len(AiDifficultyOrder) != len(AiDifficulties) {
+		t.Error("difficulty missing in order")
+	}
+	for _, name := range AiDifficultyOrder {
+		difficulty, _ := GetAiDifficulty(name)
+		limit := int(difficulty.AimError + 0.5)
+		for i := 0; i < 100; i++ {
+			if offset := difficulty.AimOffset(); absInt(offset.X) > limit || absInt(offset.Y) > limit {
+				t.Fatal(name, "aim offset", offset)
+			}
+		}
+	}
+}
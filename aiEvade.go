@@ -0,0 +1,212 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"math"
+	"time"
+)
+
+const (
+	EVADE_RANGE   = 30              //projectiles farther than that ignored whatever speed
+	EVADE_MARGIN  = 0.5             //extra width of projectile lane
+	EVADE_TIMEOUT = 2 * time.Second //give up dodge and return to previous behavior
+)
+
+// Incoming is enemy projectile predicted to hit avatar
+type Incoming struct {
+	*Projectile
+	Impact time.Duration
+}
+
+// projectileImpact time before projectile moving straight touch box around target, false if it miss or hit later than horizon
+func projectileImpact(position Center, velocity Point, size Size, target Center, targetSize Size, horizon time.Duration) (time.Duration, bool) {
+	from, to := 0.0, horizon.Seconds()
+	axis := func(position, velocity, target, extent float64) bool {
+		if velocity == 0 {
+			return math.Abs(position-target) <= extent
+		}
+		enter, leave := (target-extent-position)/velocity, (target+extent-position)/velocity
+		if enter > leave {
+			enter, leave = leave, enter
+		}
+		from, to = math.Max(from, enter), math.Min(to, leave)
+		return from <= to
+	}
+	if !axis(position.X, velocity.X, target.X, (size.W+targetSize.W)/2+EVADE_MARGIN) ||
+		!axis(position.Y, velocity.Y, target.Y, (size.H+targetSize.H)/2+EVADE_MARGIN) {
+		return 0, false
+	}
+	return time.Duration(from * float64(time.Second)), true
+}
+
+// impact of projectile on avatar, see projectileImpact
+func (receiver *BehaviorControl) impact(projectile *Projectile) (time.Duration, bool) {
+	if projectile.destroyed {
+		return 0, false
+	}
+	velocity := Point{
+		X: projectile.Direction.X * math.Max(projectile.Speed.X, projectile.MaxSpeed.X),
+		Y: projectile.Direction.Y * math.Max(projectile.Speed.Y, projectile.MaxSpeed.Y),
+	}
+	return projectileImpact(projectile.GetCenter(), velocity, projectile.GetWH(),
+		receiver.avatar.GetCenter(), receiver.avatar.GetWH(), receiver.Difficulty.EvadeHorizon)
+}
+
+// IncomingProjectile nearest enemy projectile that hit avatar within difficulty horizon. Every projectile is
+// rolled against EvadeChance once, ignored ones stay ignored
+func (receiver *BehaviorControl) IncomingProjectile() *Incoming {
+	if receiver.Difficulty.EvadeHorizon <= 0 {
+		return nil
+	}
+	for projectile := range receiver.evaded {
+		if projectile.destroyed {
+			delete(receiver.evaded, projectile)
+		}
+	}
+	center, team := receiver.avatar.GetCenter(), receiver.avatar.GetAttr().Team
+	var nearest *Incoming
+	for _, object := range receiver.Collider.QueryRect(center.X-EVADE_RANGE, center.Y-EVADE_RANGE, EVADE_RANGE*2, EVADE_RANGE*2) {
+		projectile, ok := object.(*Projectile)
+		if !ok || projectile.Owner == nil || projectile.Owner.GetAttr().Team == team {
+			continue
+		}
+		impact, hit := receiver.impact(projectile)
+		if !hit || (nearest != nil && impact >= nearest.Impact) {
+			continue
+		}
+		evade, rolled := receiver.evaded[projectile]
+		if !rolled {
+			evade = receiver.Difficulty.Evade()
+			receiver.evaded[projectile] = evade
+		}
+		if evade {
+			nearest = &Incoming{Projectile: projectile, Impact: impact}
+		}
+	}
+	return nearest
+}
+
+// DodgeDirection side step out of projectile lane, see rankSides, nearest side first otherwise. NoPos if both sides blocked or too slow to make it
+func (receiver *BehaviorControl) DodgeDirection(incoming *Incoming) Point {
+	pcenter, acenter := incoming.GetCenter(), receiver.avatar.GetCenter()
+	psize, asize := incoming.GetWH(), receiver.avatar.GetWH()
+	speed := receiver.avatar.MaxSpeed
+
+	sides := [2]Point{right, left}
+	offset, lane, sideSpeed := acenter.X-pcenter.X, (psize.W+asize.W)/2+EVADE_MARGIN, speed.X
+	if math.Abs(incoming.Direction.X) > math.Abs(incoming.Direction.Y) {
+		sides = [2]Point{bottom, top}
+		offset, lane, sideSpeed = acenter.Y-pcenter.Y, (psize.H+asize.H)/2+EVADE_MARGIN, speed.Y
+	}
+	if offset < 0 {
+		sides[0], sides[1] = sides[1], sides[0]
+	}
+	if sideSpeed <= 0 {
+		return NoPos
+	}
+	near := sides[0]
+	sides = receiver.rankSides(sides, incoming)
+	for _, side := range sides {
+		if receiver.blockedDirection[side] {
+			continue
+		}
+		distance := lane - math.Abs(offset)
+		if side != near {
+			distance = lane + math.Abs(offset)
+		}
+		if distance/sideSpeed <= incoming.Impact.Seconds() {
+			return side
+		}
+	}
+	return NoPos
+}
+
+// rankSides by influence map: side with line of fire on shooter first, then less threatened one
+func (receiver *BehaviorControl) rankSides(sides [2]Point, incoming *Incoming) [2]Point {
+	if receiver.Influence == nil {
+		return sides
+	}
+	team, zone := receiver.avatar.GetAttr().Team, receiver.avatar.GetZone()
+	step := func(side Point) Zone {
+		return Zone{X: zone.X + int(side.X), Y: zone.Y + int(side.Y)}
+	}
+	if shooter, ok := incoming.Owner.(*Unit); ok && !shooter.destroyed {
+		target := receiver.Influence.NearestZoneByCoordinate(Point(shooter.GetCenter()))
+		if line, ok := receiver.Influence.BestLineZone(team, zone, target, 1); ok {
+			if line == step(sides[1]) {
+				sides[0], sides[1] = sides[1], sides[0]
+			}
+			if line == step(sides[0]) {
+				return sides
+			}
+		}
+	}
+	if receiver.Influence.Threat(team, step(sides[1]))+INFLUENCE_NOISE < receiver.Influence.Threat(team, step(sides[0])) {
+		sides[0], sides[1] = sides[1], sides[0]
+	}
+	return sides
+}
+
+// watchProjectiles interrupt current behavior with evade, it is restored when projectile is gone
+func (receiver *BehaviorControl) watchProjectiles() {
+	if receiver.avatar == nil || receiver.avatar.destroyed || receiver.Behavior == nil ||
+		receiver.nextBehavior != nil || receiver.Behavior.Name() == "evade" {
+		return
+	}
+	incoming := receiver.IncomingProjectile()
+	if incoming == nil {
+		return
+	}
+	current := receiver.Behavior
+	receiver.Next(NewEvadeBehavior(incoming, func(control *BehaviorControl) {
+		control.Next(current)
+	}))
+}
+
+// NewEvadeBehavior step aside from incoming projectile, when there is no way out turn to it and return fire
+func NewEvadeBehavior(incoming *Incoming, next func(control *BehaviorControl)) *Behavior {
+	var dodge Point
+	var elapsed time.Duration
+	return &Behavior{
+		name:  "evade",
+		Check: OkOp,
+		Enter: func(control *BehaviorControl) {
+			dodge, elapsed = control.DodgeDirection(incoming), 0
+			if DEBUG_AI_BEHAVIOR {
+				logger.Printf("cycleId: %d, objectId: %d evade projectile %d impact %s dodge %v", CycleID, control.avatar.ID, incoming.ID, incoming.Impact, dodge)
+			}
+		},
+		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
+			if elapsed += duration; elapsed > EVADE_TIMEOUT {
+				return true
+			}
+			impact, hit := control.impact(incoming.Projectile)
+			if !hit {
+				control.Stop()
+				return true
+			}
+			incoming.Impact = impact
+			if dodge != NoPos && control.blockedDirection[dodge] {
+				dodge = control.DodgeDirection(incoming)
+			}
+			if dodge != NoPos {
+				control.send(controller.Command{
+					CType:  controller.CTYPE_MOVE,
+					Pos:    controller.Point(dodge),
+					Action: true,
+				})
+				return false
+			}
+			back := Point{X: -math.Copysign(1, incoming.Direction.X), Y: 0}
+			if math.Abs(incoming.Direction.Y) >= math.Abs(incoming.Direction.X) {
+				back = Point{X: 0, Y: -math.Copysign(1, incoming.Direction.Y)}
+			}
+			if control.AlignToDirection(back) {
+				control.Fire()
+			}
+			return false
+		},
+		Leave: NoOp,
+		Next:  next,
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProjectileImpact(t *testing.T) {
+	size, tank := Size{W: 1, H: 1}, Size{W: 3, H: 3}
+	impact, hit := projectileImpact(Center{X: 10, Y: 0}, Point{Y: 10}, size, Center{X: 10, Y: 10}, tank, time.Second)
+	if !hit {
+		t.Fatal("projectile in lane must hit")
+	}
+	if impact != 750*time.Millisecond {
+		t.Error("wrong impact time", impact)
+	}
+	if _, hit := projectileImpact(Center{X: 10, Y: 0}, Point{Y: 10}, size, Center{X: 10, Y: 10}, tank, 500*time.Millisecond); hit {
+		t.Error("impact beyond horizon")
+	}
+	if _, hit := projectileImpact(Center{X: 14, Y: 0}, Point{Y: 10}, size, Center{X: 10, Y: 10}, tank, time.Second); hit {
+		t.Error("projectile out of lane must miss")
+	}
+	if _, hit := projectileImpact(Center{X: 10, Y: 0}, Point{Y: -10}, size, Center{X: 10, Y: 10}, tank, time.Second); hit {
+		t.Error("projectile moving away must miss")
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"errors"
+	"math"
+	"time"
+)
+
+// orders of allied ai (players team), given by player keys
+const (
+	AI_ORDER_DEFEND = iota //default, keep around team base
+	AI_ORDER_FOLLOW        //escort player tank
+	AI_ORDER_HOLD          //keep around zone where order was given
+	AI_ORDER_ATTACK        //hunt enemy player faced, then back to previous order
+)
+
+const (
+	AI_ESCORT_REPLAN = time.Second //path to moving post recalculated that often
+	AI_ESCORT_NEAR   = 1           //zones from post considered arrived
+)
+
+var (
+	NoSquadError  = errors.New("no allied squad")
+	NoTargetError = errors.New("no enemy to attack")
+	//posts around anchor by squad member index
+	escortPosts = []Zone{{X: -2}, {X: 2}, {Y: 2}, {Y: -2}, {X: -2, Y: 2}, {X: 2, Y: 2}, {X: -2, Y: -2}, {X: 2, Y: -2}}
+)
+
+// AllyOrder is what allied ai does when it has no enemy to fight
+type AllyOrder struct {
+	Kind   int
+	Leader *Player //follow
+	Zone   Zone    //hold
+}
+
+// Guard set team base, allies defend it until ordered otherwise
+func (receiver *Squad) Guard(base *Unit) {
+	receiver.mutex.Lock()
+	receiver.base, receiver.attack = base, nil
+	receiver.order = AllyOrder{Kind: AI_ORDER_DEFEND}
+	receiver.mutex.Unlock()
+}
+
+func (receiver *Squad) Order(order AllyOrder) {
+	receiver.mutex.Lock()
+	receiver.order, receiver.attack = order, nil
+	members := receiver.others(nil)
+	receiver.mutex.Unlock()
+	for _, member := range members {
+		member.obey()
+	}
+}
+
+func (receiver *Squad) CurrentOrder() AllyOrder {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	return receiver.order
+}
+
+// Attack make allies hunt target, previous order is kept for after
+func (receiver *Squad) Attack(target *Unit) {
+	receiver.mutex.Lock()
+	receiver.attack = target
+	members := receiver.others(nil)
+	receiver.mutex.Unlock()
+	for _, member := range members {
+		member.obey()
+	}
+}
+
+// Target ordered to attack, nil if none or it is destroyed
+func (receiver *Squad) Target() *Unit {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.attack != nil && receiver.attack.destroyed {
+		receiver.attack = nil
+	}
+	return receiver.attack
+}
+
+// Post zone member keeps without enemy, anchor is unit it guards (nil on hold). Follow fall back to base
+// while leader is dead
+func (receiver *Squad) Post(member *BehaviorControl) (post Zone, anchor *Unit, ok bool) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	index := 0
+	for i, candidate := range receiver.members {
+		if candidate == member {
+			index = i
+		}
+	}
+	var zone Zone
+	leader := receiver.order.Leader
+	switch {
+	case receiver.order.Kind == AI_ORDER_HOLD:
+		zone = receiver.order.Zone
+	case receiver.order.Kind == AI_ORDER_FOLLOW && leader != nil && leader.Unit != nil && !leader.Unit.destroyed:
+		anchor = leader.Unit
+	case receiver.base != nil && !receiver.base.destroyed:
+		anchor = receiver.base
+	default:
+		return NoZone, nil, false
+	}
+	if anchor != nil {
+		zone = anchor.GetZone()
+	}
+	offset := escortPosts[index%len(escortPosts)]
+	return Zone{X: zone.X + offset.X, Y: zone.Y + offset.Y}, anchor, true
+}
+
+// ally is ai of players team not driving player tank (autopilot), it escorts instead of idle and obey orders
+func (receiver *BehaviorControl) ally() bool {
+	return receiver.squad != nil && receiver.avatar != nil &&
+		receiver.avatar.GetAttr().Team == PLAYER_TEAM && !receiver.avatar.HasTag("player")
+}
+
+// rest is what to do without enemy
+func (receiver *BehaviorControl) rest() *Behavior {
+	if _, _, ok := receiver.post(); ok {
+		return NewEscortBehavior()
+	}
+	return IdleBehavior
+}
+
+func (receiver *BehaviorControl) post() (Zone, *Unit, bool) {
+	if !receiver.ally() {
+		return NoZone, nil, false
+	}
+	post, anchor, ok := receiver.squad.Post(receiver)
+	if !ok {
+		return NoZone, nil, false
+	}
+	size := receiver.Location.ZoneSize()
+	if anchor != nil { //post behind map border goes to other side of anchor
+		zone := anchor.GetZone()
+		if post.X < 0 || post.X >= size.X {
+			post.X = 2*zone.X - post.X
+		}
+		if post.Y < 0 || post.Y >= size.Y {
+			post.Y = 2*zone.Y - post.Y
+		}
+	}
+	post.X, post.Y = maxInt(minInt(post.X, size.X-1), 0), maxInt(minInt(post.Y, size.Y-1), 0)
+	return post, anchor, true
+}
+
+func (receiver *BehaviorControl) orderedTarget() *Unit {
+	if !receiver.ally() {
+		return nil
+	}
+	return receiver.squad.Target()
+}
+
+// obey new squad order, current fight goes on unless there is target to attack
+func (receiver *BehaviorControl) obey() {
+	if receiver.avatar == nil || receiver.disabled || !receiver.ally() {
+		return
+	}
+	receiver.Next(receiver.decide(ChosePatternBehavior))
+}
+
+// NewEscortBehavior move to squad post and keep it: around leader, hold zone or base
+func NewEscortBehavior() *Behavior {
+	var replanAt time.Time
+	post := NoZone
+	return &Behavior{
+		name: "escort",
+		Check: func(control *BehaviorControl) bool {
+			_, _, ok := control.post()
+			return ok
+		},
+		Enter: func(control *BehaviorControl) {
+			control.target = nil
+			control.lastPath, control.newPath = nil, nil
+			post, replanAt = NoZone, time.Time{}
+		},
+		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
+			current, anchor, ok := control.post()
+			if !ok {
+				control.Next(IdleBehavior)
+				return false
+			}
+			zone := control.avatar.GetZone()
+			if absInt(zone.X-current.X) <= AI_ESCORT_NEAR && absInt(zone.Y-current.Y) <= AI_ESCORT_NEAR {
+				control.lastPath, post = nil, NoZone
+				if control.avatar.moving {
+					control.send(controller.Command{CType: controller.CTYPE_MOVE, Pos: controller.PosIrrelevant, Action: false})
+				} else if anchor != nil && !anchor.HasTag("base") {
+					control.AlignToDirection(anchor.Direction)
+				}
+				return false
+			}
+			if now := Clock.Now(); post == NoZone || now.After(replanAt) {
+				post, replanAt = current, now.Add(AI_ESCORT_REPLAN)
+				if err := control.schedulePath(zone, current); err != nil {
+					logger.Println(err)
+				}
+			}
+			if len(control.lastPath) > 0 {
+				if done, _ := control.MoveToZone(control.lastPath[0], duration); done {
+					control.lastPath = control.lastPath[1:]
+				}
+			}
+			return false
+		},
+		Leave: NoOp,
+		Next:  NoOp,
+	}
+}
+
+// Order allied ai of player team: follow player tank (again to defend base), hold where it stands or attack
+// enemy it faces
+func (receiver *Game) Order(player *Player, order int) error {
+	unit := player.Unit
+	if receiver.AiBuilder == nil || unit == nil || unit.destroyed {
+		return NoSquadError
+	}
+	squad := receiver.AiBuilder.Squad(unit.GetAttr().Team)
+	switch order {
+	case AI_ORDER_FOLLOW:
+		if current := squad.CurrentOrder(); current.Kind == AI_ORDER_FOLLOW && current.Leader == player {
+			order = AI_ORDER_DEFEND
+			squad.Order(AllyOrder{Kind: AI_ORDER_DEFEND})
+		} else {
+			squad.Order(AllyOrder{Kind: AI_ORDER_FOLLOW, Leader: player})
+		}
+	case AI_ORDER_HOLD:
+		squad.Order(AllyOrder{Kind: AI_ORDER_HOLD, Zone: unit.GetZone()})
+	case AI_ORDER_ATTACK:
+		target := receiver.enemyAhead(unit)
+		if target == nil {
+			return NoTargetError
+		}
+		squad.Attack(target)
+	default:
+		squad.Order(AllyOrder{Kind: AI_ORDER_DEFEND})
+	}
+	logger.Printf("cycleId: %d, player %s order %d \n", CycleID, player.Name, order)
+	return nil
+}
+
+// enemyAhead nearest enemy tank in front of unit, nearest at all if none in front
+func (receiver *Game) enemyAhead(unit *Unit) *Unit {
+	var (
+		ahead, nearest                 *Unit
+		aheadDistance, nearestDistance = math.MaxFloat64, math.MaxFloat64
+	)
+	from := unit.GetCenter()
+	for _, object := range receiver.SpawnManager.QuerySpawnedByTag("tank") {
+		enemy, ok := object.(*Unit)
+		if !ok || enemy.destroyed || enemy.GetAttr().Team == unit.GetAttr().Team {
+			continue
+		}
+		to := enemy.GetCenter()
+		distance := getDistance(from.X, from.Y, to.X, to.Y)
+		if distance < nearestDistance {
+			nearest, nearestDistance = enemy, distance
+		}
+		if (to.X-from.X)*unit.Direction.X+(to.Y-from.Y)*unit.Direction.Y > 0 && distance < aheadDistance {
+			ahead, aheadDistance = enemy, distance
+		}
+	}
+	if ahead != nil {
+		return ahead
+	}
+	return nearest
+}
+
+// isEnemyAi ai unit counted for win, allies on players team are not
+func isEnemyAi(object ObjectInterface) bool {
+	return object.HasTag("ai") && object.GetAttr().Team != PLAYER_TEAM
+}
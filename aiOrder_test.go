@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestSquadPost(t *testing.T) {
+	squad, _ := NewSquad(PLAYER_TEAM)
+	first, second := &BehaviorControl{}, &BehaviorControl{}
+	squad.Join(first)
+	squad.Join(second)
+	if _, _, ok := squad.Post(first); ok {
+		t.Error("post without base and order")
+	}
+
+	base := slotTarget(Point{Y: -1}, "base")
+	base.Tracker = &Tracker{xIndex: 6, yIndex: 14}
+	squad.Guard(base)
+	if post, anchor, ok := squad.Post(second); !ok || anchor != base || post != (Zone{X: 6 + escortPosts[1].X, Y: 14 + escortPosts[1].Y}) {
+		t.Error("second member not around base", post, anchor, ok)
+	}
+
+	leader := slotTarget(Point{X: 1})
+	leader.Tracker = &Tracker{xIndex: 2, yIndex: 3}
+	player := &Player{Unit: leader}
+	squad.Order(AllyOrder{Kind: AI_ORDER_FOLLOW, Leader: player})
+	if post, anchor, _ := squad.Post(first); anchor != leader || post != (Zone{X: 2 + escortPosts[0].X, Y: 3}) {
+		t.Error("first member does not follow leader", post, anchor)
+	}
+	player.Unit = nil //respawning
+	if _, anchor, _ := squad.Post(first); anchor != base {
+		t.Error("no fallback to base while leader is dead", anchor)
+	}
+
+	squad.Order(AllyOrder{Kind: AI_ORDER_HOLD, Zone: Zone{X: 8, Y: 8}})
+	if post, anchor, _ := squad.Post(first); anchor != nil || post != (Zone{X: 8 + escortPosts[0].X, Y: 8}) {
+		t.Error("hold zone not kept", post, anchor)
+	}
+}
+
+func TestSquadAttack(t *testing.T) {
+	squad, _ := NewSquad(PLAYER_TEAM)
+	enemy := slotTarget(Point{X: 1})
+	squad.Order(AllyOrder{Kind: AI_ORDER_HOLD, Zone: Zone{X: 1, Y: 1}})
+	squad.Attack(enemy)
+	if squad.Target() != enemy {
+		t.Error("attack target not set")
+	}
+	enemy.destroyed = true
+	if squad.Target() != nil || squad.CurrentOrder().Kind != AI_ORDER_HOLD {
+		t.Error("destroyed target kept or previous order lost")
+	}
+}
+
+func TestIsEnemyAi(t *testing.T) {
+	ally, enemy := slotTarget(Point{}, "ai"), slotTarget(Point{}, "ai")
+	ally.Attributes, enemy.Attributes = &Attributes{Team: PLAYER_TEAM}, &Attributes{Team: 1}
+	if isEnemyAi(ally) || !isEnemyAi(enemy) {
+		t.Error("allied ai counted as enemy or enemy missed")
+	}
+}
@@ -1,34 +1,206 @@
 package main
 
-var ()
+import (
+	"sync"
+	"time"
+)
 
+const (
+	AI_SLOT_FRONT = iota //original chase lane
+	AI_SLOT_LEFT
+	AI_SLOT_RIGHT
+	AI_SLOT_BEHIND
+	AI_SLOT_COUNT
+	AI_SLOT_NONE = -1
+)
+
+const (
+	AI_SLOT_DISTANCE     = 2                      //zones from target
+	AI_SLOT_FIRE_STAGGER = 400 * time.Millisecond //squad members do not fire at same target closer than that
+)
+
+// AiSlots attack positions around one target, reserved by ai of one team
 type AiSlots struct {
 	*Unit
-	subscribers []IndexTracker
+	occupants   [AI_SLOT_COUNT]int
+	lastFire    time.Time
+	lastShooter *BehaviorControl
+}
+
+// Reserve least occupied slot, front first. Base can't be approached from behind (map border)
+func (receiver *AiSlots) Reserve() int {
+	slot, count := AI_SLOT_NONE, 0
+	for candidate := AI_SLOT_FRONT; candidate < AI_SLOT_COUNT; candidate++ {
+		if candidate == AI_SLOT_BEHIND && receiver.HasTag("base") {
+			continue
+		}
+		if slot == AI_SLOT_NONE || receiver.occupants[candidate] < count {
+			slot, count = candidate, receiver.occupants[candidate]
+		}
+	}
+	receiver.occupants[slot]++
+	return slot
+}
+
+func (receiver *AiSlots) Release(slot int) {
+	if slot >= 0 && slot < AI_SLOT_COUNT && receiver.occupants[slot] > 0 {
+		receiver.occupants[slot]--
+	}
 }
 
-func (receiver *AiSlots) HasTag(value string) bool {
+func (receiver *AiSlots) empty() bool {
+	for _, count := range receiver.occupants {
+		if count > 0 {
+			return false
+		}
+	}
 	return true
 }
 
-func (receiver *AiSlots) indexUpdate() {
-	//todo make simpler
-	for _, subscriber := range receiver.subscribers {
-		if subscriber == nil {
-			continue
+// Offset of slot from target zone, unit slots follow its direction
+func (receiver *AiSlots) Offset(slot int) Zone {
+	if receiver.HasTag("base") {
+		switch slot {
+		case AI_SLOT_FRONT:
+			return Zone{Y: -AI_SLOT_DISTANCE}
+		case AI_SLOT_LEFT:
+			return Zone{X: -AI_SLOT_DISTANCE}
+		case AI_SLOT_RIGHT:
+			return Zone{X: AI_SLOT_DISTANCE}
 		}
-		//subscriber.OnIndexUpdate(receiver)
+		return Zone{}
+	}
+	dir := receiver.Direction
+	switch slot {
+	case AI_SLOT_LEFT:
+		return Zone{X: int(dir.Y) * AI_SLOT_DISTANCE, Y: -int(dir.X) * AI_SLOT_DISTANCE}
+	case AI_SLOT_RIGHT:
+		return Zone{X: -int(dir.Y) * AI_SLOT_DISTANCE, Y: int(dir.X) * AI_SLOT_DISTANCE}
+	case AI_SLOT_BEHIND:
+		return Zone{X: -int(dir.X) * AI_SLOT_DISTANCE, Y: -int(dir.Y) * AI_SLOT_DISTANCE}
+	}
+	return Zone{}
+}
+
+// fireTurn true if shooter may fire now, other members wait for stagger
+func (receiver *AiSlots) fireTurn(shooter *BehaviorControl) bool {
+	now := Clock.Now()
+	if receiver.lastShooter != shooter && now.Sub(receiver.lastFire) < AI_SLOT_FIRE_STAGGER {
+		return false
 	}
+	receiver.lastFire, receiver.lastShooter = now, shooter
+	return true
+}
+
+// Squad is all ai of one team: it share sightings and hand out attack slots
+type Squad struct {
+	Team      int8
+	members   []*BehaviorControl
+	slots     map[*Unit]*AiSlots
+	sightings map[*Unit]int //members seeing unit by own eyes
+	order     AllyOrder     //allies only, see aiOrder
+	base      *Unit
+	attack    *Unit
+	mutex     sync.Mutex
 }
 
-func (receiver *AiSlots) Subscribe(subscriber IndexTracker) {
-	receiver.subscribers = append(receiver.subscribers, subscriber)
+func (receiver *Squad) Join(member *BehaviorControl) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	for _, candidate := range receiver.members {
+		if candidate == member {
+			return
+		}
+	}
+	receiver.members = append(receiver.members, member)
 }
 
-func (receiver *AiSlots) Unsubscribe(subscriber IndexTracker) {
-	for index, candidate := range receiver.subscribers {
-		if subscriber == candidate {
-			receiver.subscribers[index] = nil
+func (receiver *Squad) Leave(member *BehaviorControl) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	for i, candidate := range receiver.members {
+		if candidate == member {
+			receiver.members = append(receiver.members[:i], receiver.members[i+1:]...)
+			break
 		}
 	}
 }
+
+// Reserve attack slot around target for member
+func (receiver *Squad) Reserve(target *Unit) (*AiSlots, int) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	slots, ok := receiver.slots[target]
+	if !ok {
+		slots = &AiSlots{Unit: target}
+		receiver.slots[target] = slots
+	}
+	return slots, slots.Reserve()
+}
+
+func (receiver *Squad) Release(slots *AiSlots, slot int) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	slots.Release(slot)
+	if slots.empty() {
+		delete(receiver.slots, slots.Unit)
+	}
+}
+
+func (receiver *Squad) FireTurn(slots *AiSlots, shooter *BehaviorControl) bool {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	return slots.fireTurn(shooter)
+}
+
+// Sighted first eyes on unit report it to whole squad
+func (receiver *Squad) Sighted(reporter *BehaviorControl, unit *Unit) {
+	receiver.mutex.Lock()
+	receiver.sightings[unit]++
+	first := receiver.sightings[unit] == 1
+	members := receiver.others(reporter)
+	receiver.mutex.Unlock()
+	if first {
+		for _, member := range members {
+			member.hear(unit)
+		}
+	}
+}
+
+// Unsighted when nobody see unit anymore squad forget it
+func (receiver *Squad) Unsighted(reporter *BehaviorControl, unit *Unit) {
+	receiver.mutex.Lock()
+	if receiver.sightings[unit] > 0 {
+		receiver.sightings[unit]--
+	}
+	lost := receiver.sightings[unit] == 0
+	if lost {
+		delete(receiver.sightings, unit)
+	}
+	members := receiver.others(reporter)
+	receiver.mutex.Unlock()
+	if lost {
+		for _, member := range members {
+			member.lose(unit)
+		}
+	}
+}
+
+func (receiver *Squad) others(member *BehaviorControl) []*BehaviorControl {
+	others := make([]*BehaviorControl, 0, len(receiver.members))
+	for _, candidate := range receiver.members {
+		if candidate != member {
+			others = append(others, candidate)
+		}
+	}
+	return others
+}
+
+func NewSquad(team int8) (*Squad, error) {
+	return &Squad{
+		Team:      team,
+		members:   make([]*BehaviorControl, 0, 10),
+		slots:     make(map[*Unit]*AiSlots),
+		sightings: make(map[*Unit]int),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func slotTarget(direction Point, tags ...string) *Unit {
+	unitTags, _ := NewTags()
+	unitTags.addTag(tags...)
+	return &Unit{MotionObject: &MotionObject{
+		Object: &Object{Tags: unitTags},
+		Moving: Moving{Direction: direction},
+	}}
+}
+
+func TestAiSlotsSpread(t *testing.T) {
+	squad, _ := NewSquad(1)
+	target := slotTarget(Point{X: 1})
+	taken := map[int]bool{}
+	for i := 0; i < AI_SLOT_COUNT; i++ {
+		_, slot := squad.Reserve(target)
+		if taken[slot] {
+			t.Fatalf("slot %d reserved twice while free slots left", slot)
+		}
+		taken[slot] = true
+	}
+	slots := squad.slots[target]
+	if offset := slots.Offset(AI_SLOT_BEHIND); offset != (Zone{X: -AI_SLOT_DISTANCE}) {
+		t.Errorf("behind offset %v", offset)
+	}
+	if left, right := slots.Offset(AI_SLOT_LEFT), slots.Offset(AI_SLOT_RIGHT); left.Y != -right.Y || left.X != 0 {
+		t.Errorf("flank offsets %v %v", left, right)
+	}
+	for slot := range taken {
+		squad.Release(slots, slot)
+	}
+	if _, ok := squad.slots[target]; ok {
+		t.Error("empty slots are kept")
+	}
+
+	base := slotTarget(Point{}, "base")
+	for i := 0; i < AI_SLOT_COUNT; i++ {
+		if _, slot := squad.Reserve(base); slot == AI_SLOT_BEHIND {
+			t.Error("base approached from behind")
+		}
+	}
+}
+
+func TestAiSlotsFireStagger(t *testing.T) {
+	slots := &AiSlots{Unit: slotTarget(Point{Y: 1})}
+	first, second := &BehaviorControl{}, &BehaviorControl{}
+	if !slots.fireTurn(first) || !slots.fireTurn(first) {
+		t.Error("shooter blocked by himself")
+	}
+	if slots.fireTurn(second) {
+		t.Error("second shooter fire without stagger")
+	}
+	slots.lastFire = Clock.Now().Add(-AI_SLOT_FIRE_STAGGER)
+	if !slots.fireTurn(second) {
+		t.Error("second shooter blocked after stagger")
+	}
+}
+
+func TestAiSlotsMapBorder(t *testing.T) {
+	location, _ := NewLocation(Point{}, Size{W: 100, H: 50})
+	location.SetupZones(Point{X: 10, Y: 5})
+	squad, _ := NewSquad(1)
+	target := slotTarget(Point{Y: 1})
+	target.Tracker = &Tracker{}
+	for i := 0; i < AI_SLOT_COUNT; i++ {
+		slots, slot := squad.Reserve(target)
+		control := &BehaviorControl{Location: location, target: target, slots: slots, slot: slot, retreat: NoZone}
+		if zone := control.GetFollowZone(); zone != location.ClampZone(zone) {
+			t.Errorf("slot %d follow zone %v out of map", slot, zone)
+		}
+	}
+	navigation, _ := NewNavigation(location, nil)
+	defer navigation.Close()
+	if _, err := navigation.SchedulePath(Zone{}, Zone{X: -2}, make(pathSink, 1), NAV_PRIORITY_NORMAL); !errors.Is(err, ZoneRangeError) {
+		t.Error("path out of map scheduled", err)
+	}
+}
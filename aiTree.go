@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	BT_SUCCESS = iota
+	BT_FAILURE
+	BT_RUNNING
+)
+
+var (
+	UnknownTreeNodeError = errors.New("unknown behavior tree node")
+	TreeNodeChildError   = errors.New("behavior tree node has wrong children count")
+)
+
+// BehaviorTreeConfig is node of tree file, ./ai/<name>.json
+type BehaviorTreeConfig struct {
+	Type     string                `json:"type"`           //sequence, selector, invert, succeed, repeat, condition, action
+	Name     string                `json:"name,omitempty"` //condition or action name
+	Value    float64               `json:"value,omitempty"`
+	Count    int                   `json:"count,omitempty"` //repeat, 0 forever
+	Child    *BehaviorTreeConfig   `json:"child,omitempty"`
+	Children []*BehaviorTreeConfig `json:"children,omitempty"`
+}
+
+type treeCondition func(control *BehaviorControl, config *BehaviorTreeConfig) bool
+
+type treeAction func(config *BehaviorTreeConfig) *Behavior
+
+var (
+	hasTarget = func(control *BehaviorControl) bool {
+		return control.target != nil && !control.target.destroyed
+	}
+	treeConditions = map[string]treeCondition{
+		"hasTarget": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			return hasTarget(control)
+		},
+		"targetIsBase": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			return hasTarget(control) && control.target.HasTag("base")
+		},
+		"seeEnemy": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			for _, candidate := range control.availableTargets {
+				if candidate != nil && !candidate.destroyed {
+					return true
+				}
+			}
+			return false
+		},
+		"canFire": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			return hasTarget(control) && control.CanFire(Point(control.target.GetCenter()))
+		},
+		"opportunity": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			return hasTarget(control) && OpportunityFireBehavior.Check(control)
+		},
+		"blocked": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			return control.CountBlockedDirection() >= int8(maxInt(int(config.Value), 1))
+		},
+		"fullBlock": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			return control.IsFullBlock()
+		},
+		"noPath": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			return control.noPath
+		},
+		"chance": func(control *BehaviorControl, config *BehaviorTreeConfig) bool {
+			return rand.Float64() < config.Value
+		},
+	}
+	treeActions = map[string]treeAction{
+		"idle": func(config *BehaviorTreeConfig) *Behavior {
+			return IdleBehavior
+		},
+		"escort": func(config *BehaviorTreeConfig) *Behavior {
+			return NewEscortBehavior()
+		},
+		"chooseTarget": func(config *BehaviorTreeConfig) *Behavior {
+			return &Behavior{
+				name: "chooseTarget",
+				Check: func(control *BehaviorControl) bool {
+					if !chooseTarget(control) {
+						return false
+					}
+					control.targetOffset = control.Difficulty.AimOffset()
+					return true
+				},
+				Enter: NoOp,
+				Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
+					return true
+				},
+				Leave: NoOp,
+				Next:  NoOp,
+			}
+		},
+		"hunt": func(config *BehaviorTreeConfig) *Behavior {
+			return withTarget(NewHuntBehavior())
+		},
+		"siege": func(config *BehaviorTreeConfig) *Behavior {
+			return withTarget(NewSiegeBehavior())
+		},
+		"pursuit": func(config *BehaviorTreeConfig) *Behavior {
+			return withTarget(NewPursuitBehavior())
+		},
+		"withdraw": func(config *BehaviorTreeConfig) *Behavior {
+			return withTarget(NewWithdrawalBehavior())
+		},
+		"attack": func(config *BehaviorTreeConfig) *Behavior {
+			return withTarget(NewAttackBehavior(NoOp))
+		},
+		"opportunityFire": func(config *BehaviorTreeConfig) *Behavior {
+			return withTarget(OpportunityFireBehavior)
+		},
+		"clearPath": func(config *BehaviorTreeConfig) *Behavior {
+			threshold := 3
+			if config.Value > 0 {
+				threshold = int(config.Value)
+			}
+			return withTarget(NewClearPathBehavior(NoOp, int8(threshold)))
+		},
+		"stop": func(config *BehaviorTreeConfig) *Behavior {
+			return &Behavior{
+				name:  "stop",
+				Check: OkOp,
+				Enter: NoOp,
+				Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
+					return control.Stop()
+				},
+				Leave: NoOp,
+				Next:  NoOp,
+			}
+		},
+	}
+)
+
+// withTarget fail behavior check if there is nothing to fight
+func withTarget(behavior *Behavior) *Behavior {
+	check := behavior.Check
+	instance := *behavior
+	instance.Check = func(control *BehaviorControl) bool {
+		return hasTarget(control) && check(control)
+	}
+	return &instance
+}
+
+type treeNode interface {
+	tick(control *BehaviorControl, duration time.Duration) int
+	reset(control *BehaviorControl)
+}
+
+type treeComposite struct {
+	children []treeNode
+	current  int
+	success  int //status that pass control to next child
+}
+
+func (receiver *treeComposite) tick(control *BehaviorControl, duration time.Duration) int {
+	for receiver.current < len(receiver.children) {
+		status := receiver.children[receiver.current].tick(control, duration)
+		if status != receiver.success {
+			if status != BT_RUNNING {
+				receiver.current = 0
+			}
+			return status
+		}
+		receiver.current++
+	}
+	receiver.current = 0
+	return receiver.success
+}
+
+func (receiver *treeComposite) reset(control *BehaviorControl) {
+	for _, child := range receiver.children {
+		child.reset(control)
+	}
+	receiver.current = 0
+}
+
+type treeDecorator struct {
+	child   treeNode
+	kind    string
+	count   int
+	counter int
+}
+
+func (receiver *treeDecorator) tick(control *BehaviorControl, duration time.Duration) int {
+	status := receiver.child.tick(control, duration)
+	if status == BT_RUNNING {
+		return status
+	}
+	switch receiver.kind {
+	case "invert":
+		if status == BT_SUCCESS {
+			return BT_FAILURE
+		}
+		return BT_SUCCESS
+	case "succeed":
+		return BT_SUCCESS
+	case "repeat":
+		if status == BT_FAILURE {
+			receiver.counter = 0
+			return status
+		}
+		if receiver.counter++; receiver.count > 0 && receiver.counter >= receiver.count {
+			receiver.counter = 0
+			return BT_SUCCESS
+		}
+		return BT_RUNNING //next iteration on next tick
+	}
+	return status
+}
+
+func (receiver *treeDecorator) reset(control *BehaviorControl) {
+	receiver.child.reset(control)
+	receiver.counter = 0
+}
+
+type treeConditionNode struct {
+	config *BehaviorTreeConfig
+	check  treeCondition
+}
+
+func (receiver *treeConditionNode) tick(control *BehaviorControl, duration time.Duration) int {
+	if receiver.check(control, receiver.config) {
+		return BT_SUCCESS
+	}
+	return BT_FAILURE
+}
+
+func (receiver *treeConditionNode) reset(control *BehaviorControl) {}
+
+// treeActionNode run one of the regular behaviors until its Update report done
+type treeActionNode struct {
+	config   *BehaviorTreeConfig
+	build    treeAction
+	behavior *Behavior
+}
+
+func (receiver *treeActionNode) tick(control *BehaviorControl, duration time.Duration) int {
+	if receiver.behavior == nil {
+		behavior := receiver.build(receiver.config)
+		if !behavior.Check(control) {
+			return BT_FAILURE
+		}
+		if DEBUG_AI_BEHAVIOR {
+			logger.Printf("cycleId: %d, objectId: %d tree action %s", CycleID, control.avatar.ID, behavior.Name())
+		}
+		receiver.behavior = behavior
+		behavior.Enter(control)
+		if control.nextBehavior != nil {
+			return BT_RUNNING //behavior switch control to other one, tree restart when it come back
+		}
+	}
+	done := receiver.behavior.Update(control, duration)
+	if control.nextBehavior != nil {
+		return BT_RUNNING
+	}
+	if done {
+		receiver.reset(control)
+		return BT_SUCCESS
+	}
+	return BT_RUNNING
+}
+
+func (receiver *treeActionNode) reset(control *BehaviorControl) {
+	if receiver.behavior != nil {
+		receiver.behavior.Leave(control)
+		receiver.behavior = nil
+	}
+}
+
+// BehaviorTree is parsed tree file, shared by all controls of blueprint. Every control get own Behavior instance
+type BehaviorTree struct {
+	Name string
+	root *BehaviorTreeConfig
+}
+
+// Behavior new tree runtime wrapped as regular behavior, control switched to detour behaviors (attack, idleUntil...)
+// return to it and tree starts from root again
+func (receiver *BehaviorTree) Behavior() *Behavior {
+	root, _ := buildTreeNode(receiver.root)
+	return &Behavior{
+		name:  "tree:" + receiver.Name,
+		Check: OkOp,
+		Enter: func(control *BehaviorControl) {
+			root.reset(control)
+		},
+		Update: func(control *BehaviorControl, duration time.Duration) (done bool) {
+			root.tick(control, duration) //finished tree simple start over
+			return false
+		},
+		Leave: func(control *BehaviorControl) {
+			root.reset(control)
+		},
+		Next: NoOp,
+	}
+}
+
+func buildTreeNode(config *BehaviorTreeConfig) (treeNode, error) {
+	if config == nil {
+		return nil, TreeNodeChildError
+	}
+	switch config.Type {
+	case "sequence", "selector":
+		if len(config.Children) == 0 {
+			return nil, fmt.Errorf("%s: %w", config.Type, TreeNodeChildError)
+		}
+		node := &treeComposite{success: BT_SUCCESS}
+		if config.Type == "selector" {
+			node.success = BT_FAILURE
+		}
+		for _, childConfig := range config.Children {
+			child, err := buildTreeNode(childConfig)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		}
+		return node, nil
+	case "invert", "succeed", "repeat":
+		child, err := buildTreeNode(config.Child)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", config.Type, err)
+		}
+		return &treeDecorator{child: child, kind: config.Type, count: config.Count}, nil
+	case "condition":
+		if check, ok := treeConditions[config.Name]; ok {
+			return &treeConditionNode{config: config, check: check}, nil
+		}
+	case "action":
+		if build, ok := treeActions[config.Name]; ok {
+			return &treeActionNode{config: config, build: build}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s: %w", config.Type, config.Name, UnknownTreeNodeError)
+}
+
+func NewBehaviorTree(name string, payload []byte) (*BehaviorTree, error) {
+	root := new(BehaviorTreeConfig)
+	if err := json.Unmarshal(payload, root); err != nil {
+		return nil, fmt.Errorf("tree %s: %w", name, err)
+	}
+	if _, err := buildTreeNode(root); err != nil { //validate once, instances built later can't fail
+		return nil, fmt.Errorf("tree %s: %w", name, err)
+	}
+	return &BehaviorTree{Name: name, root: root}, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBehaviorTreeFiles(t *testing.T) {
+	for _, name := range []string{"rush", "siege"} {
+		payload, err := loadTree(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewBehaviorTree(name, payload); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestBehaviorTreeUnknownNode(t *testing.T) {
+	_, err := NewBehaviorTree("broken", []byte(`{"type": "sequence", "children": [{"type": "action", "name": "dance"}]}`))
+	if !errors.Is(err, UnknownTreeNodeError) {
+		t.Errorf("expected unknown node error, got %v", err)
+	}
+	_, err = NewBehaviorTree("broken", []byte(`{"type": "invert"}`))
+	if !errors.Is(err, TreeNodeChildError) {
+		t.Errorf("expected child error, got %v", err)
+	}
+}
+
+func TestBehaviorTreeComposite(t *testing.T) {
+	ok := &treeConditionNode{check: func(control *BehaviorControl, config *BehaviorTreeConfig) bool { return true }}
+	fail := &treeConditionNode{check: func(control *BehaviorControl, config *BehaviorTreeConfig) bool { return false }}
+	sequence := &treeComposite{children: []treeNode{ok, fail}, success: BT_SUCCESS}
+	selector := &treeComposite{children: []treeNode{fail, ok}, success: BT_FAILURE}
+	if status := sequence.tick(nil, 0); status != BT_FAILURE {
+		t.Errorf("sequence status %d", status)
+	}
+	if status := selector.tick(nil, 0); status != BT_SUCCESS {
+		t.Errorf("selector status %d", status)
+	}
+	invert := &treeDecorator{child: sequence, kind: "invert"}
+	if status := invert.tick(nil, 0); status != BT_SUCCESS {
+		t.Errorf("invert status %d", status)
+	}
+}
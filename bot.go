@@ -0,0 +1,402 @@
+package main
+
+import (
+	"GoConsoleBT/collider"
+	"GoConsoleBT/controller"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// protocol: game write one BotObservation per line to bot stdin, bot answer every observation with one line,
+// controller.Command object or array of them ([] nothing to do). Next observation is not sent until answer,
+// slow bot just skip cycles. Bot stderr goes to log
+const (
+	BOT_RANGE        = 30          //walls and projectiles reported that far from unit, units are reported by vision
+	BOT_STOP_TIMEOUT = time.Second //bot is killed if it does not exit after stdin is closed
+)
+
+var (
+	BotCommandLineError = errors.New("bad bot command line")
+	BotAnswerError      = errors.New("bot answer is not command")
+)
+
+// BotObject is unit, wall or projectile as bot see it
+type BotObject struct {
+	ID        int64   `json:"id"`
+	Blueprint string  `json:"blueprint"`
+	Team      int8    `json:"team"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	W         float64 `json:"w"`
+	H         float64 `json:"h"`
+	Direction Point   `json:"direction"`
+	Speed     Point   `json:"speed"`
+	Moving    bool    `json:"moving,omitempty"`
+	HP        int     `json:"hp,omitempty"`
+}
+
+type BotGun struct {
+	Projectile string `json:"projectile"`
+	Name       string `json:"name"`
+	Ammo       int64  `json:"ammo"` //-1 unlimited
+	Reloading  bool   `json:"reloading"`
+}
+
+type BotObservation struct {
+	Cycle       int64       `json:"cycle"`
+	Unit        BotObject   `json:"unit"`
+	Gun         BotGun      `json:"gun"`
+	Units       []BotObject `json:"units"`       //in vision now
+	Walls       []BotObject `json:"walls"`       //seen once and not destroyed yet
+	Projectiles []BotObject `json:"projectiles"` //around unit
+}
+
+// TeamBots is team -> bot command line, flag value in form team=command
+type TeamBots map[int8]string
+
+func (receiver TeamBots) String() string {
+	teams := make([]string, 0, len(receiver))
+	for team, command := range receiver {
+		teams = append(teams, strconv.Itoa(int(team))+"="+command)
+	}
+	return strings.Join(teams, ", ")
+}
+
+func (receiver TeamBots) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return fmt.Errorf("%q, want team=command: %w", value, BotCommandLineError)
+	}
+	team, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 8)
+	if err != nil {
+		return fmt.Errorf("%q, want team=command: %w", value, BotCommandLineError)
+	}
+	receiver[int8(team)] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+// botProcess is one run of bot command, started on control enable and stopped on disable
+type botProcess struct {
+	cmd          *exec.Cmd
+	observations chan *BotObservation
+	quit, done   chan struct{}
+	waiting      int32 //observation sent, answer not read yet
+}
+
+// BotControl drive unit by external process, see protocol
+type BotControl struct {
+	Command       string
+	Collider      *collider.Collider
+	avatar        *Unit
+	seen          map[*Unit]bool
+	walls         map[*Wall]bool
+	process       *botProcess
+	commandChanel chan controller.Command
+	mutex         sync.Mutex
+}
+
+func (receiver *BotControl) GetCommandChanel() controller.CommandChanel {
+	return receiver.commandChanel
+}
+
+func (receiver *BotControl) AttachTo(object *Unit) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.avatar != object {
+		receiver.seen, receiver.walls = make(map[*Unit]bool), make(map[*Wall]bool)
+	}
+	receiver.avatar = object
+}
+
+func (receiver *BotControl) Enable() error {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.process != nil {
+		return nil
+	}
+	process, err := startBot(receiver.Command, receiver.commandChanel)
+	if err != nil {
+		logger.Printf("bot %q: %s \n", receiver.Command, err)
+		return err
+	}
+	receiver.process = process
+	return nil
+}
+
+func (receiver *BotControl) Disable() error {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.process != nil {
+		receiver.process.stop()
+		receiver.process = nil
+	}
+	receiver.seen = make(map[*Unit]bool)
+	return nil
+}
+
+func (receiver *BotControl) See(object *Unit) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.seen[object] = true
+}
+
+func (receiver *BotControl) UnSee(object *Unit) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	delete(receiver.seen, object)
+}
+
+// Update send observation if bot answered previous one, never block game loop
+func (receiver *BotControl) Update(timeLeft time.Duration) error {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.process == nil || receiver.avatar == nil || receiver.avatar.destroyed {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt32(&receiver.process.waiting, 0, 1) {
+		return nil
+	}
+	select {
+	case receiver.process.observations <- receiver.observe():
+	default:
+		//bot answered with more lines than observations, previous one still queued, its answer reset waiting
+	}
+	return nil
+}
+
+func (receiver *BotControl) observe() *BotObservation {
+	avatar := receiver.avatar
+	observation := &BotObservation{
+		Cycle:       atomic.LoadInt64(&CycleID),
+		Unit:        botObject(avatar),
+		Gun:         botGun(avatar.Gun),
+		Units:       make([]BotObject, 0, len(receiver.seen)),
+		Walls:       make([]BotObject, 0, len(receiver.walls)),
+		Projectiles: make([]BotObject, 0),
+	}
+	for unit := range receiver.seen {
+		if unit.destroyed {
+			delete(receiver.seen, unit)
+			continue
+		}
+		observation.Units = append(observation.Units, botObject(unit))
+	}
+	if receiver.Collider != nil {
+		center := avatar.GetCenter()
+		for _, object := range receiver.Collider.QueryRect(center.X-BOT_RANGE, center.Y-BOT_RANGE, BOT_RANGE*2, BOT_RANGE*2) {
+			switch object.(type) {
+			case *Wall:
+				receiver.walls[object.(*Wall)] = true
+			case *Projectile:
+				if projectile := object.(*Projectile); !projectile.destroyed {
+					observation.Projectiles = append(observation.Projectiles, botObject(projectile))
+				}
+			}
+		}
+	}
+	for wall := range receiver.walls {
+		if wall.destroyed {
+			delete(receiver.walls, wall)
+			continue
+		}
+		observation.Walls = append(observation.Walls, botObject(wall))
+	}
+	return observation
+}
+
+func (receiver *BotControl) Copy() controller.Controller {
+	instance, _ := NewBotControl(receiver.Command, receiver.Collider)
+	return instance
+}
+
+func botObject(object ObjectInterface) BotObject {
+	xy, wh := object.GetXY(), object.GetWH()
+	result := BotObject{
+		ID:        object.GetAttr().ID,
+		Blueprint: object.GetAttr().Blueprint,
+		Team:      object.GetAttr().Team,
+		X:         xy.X,
+		Y:         xy.Y,
+		W:         wh.W,
+		H:         wh.H,
+	}
+	switch object.(type) {
+	case *Unit:
+		unit := object.(*Unit)
+		result.Direction, result.Speed, result.Moving, result.HP = unit.Direction, unit.Speed, unit.moving, unit.HP
+	case *Projectile:
+		projectile := object.(*Projectile)
+		result.Direction = projectile.Direction
+		result.Speed = Point{X: math.Max(projectile.Speed.X, projectile.MaxSpeed.X), Y: math.Max(projectile.Speed.Y, projectile.MaxSpeed.Y)}
+		if projectile.Owner != nil {
+			result.Team = projectile.Owner.GetAttr().Team
+		}
+	case *Wall:
+		result.HP = object.(*Wall).HP
+	}
+	return result
+}
+
+func botGun(gun *Gun) BotGun {
+	gun.mutex.Lock()
+	defer gun.mutex.Unlock()
+	if gun.Current == nil {
+		return BotGun{}
+	}
+	return BotGun{
+		Projectile: gun.Current.Projectile,
+		Name:       gun.Current.Name,
+		Ammo:       gun.Current.Ammo,
+		Reloading:  gun.Current.isReloading(),
+	}
+}
+
+// parseBotAnswer one answer line, command object or array of commands
+func parseBotAnswer(line []byte) ([]controller.Command, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil, nil
+	}
+	var commands []controller.Command
+	var err error
+	if line[0] == '[' {
+		err = json.Unmarshal(line, &commands)
+	} else {
+		commands = make([]controller.Command, 1)
+		err = json.Unmarshal(line, &commands[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, BotAnswerError)
+	}
+	return commands, nil
+}
+
+func startBot(command string, output chan controller.Command) (*botProcess, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, BotCommandLineError
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	process := &botProcess{
+		cmd:          cmd,
+		observations: make(chan *BotObservation, 1),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	name := args[0] + " " + strconv.Itoa(cmd.Process.Pid)
+	go process.writer(stdin)
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Printf("bot %s: %s \n", name, scanner.Text())
+		}
+	}()
+	go func() {
+		process.reader(stdout, output)
+		err := cmd.Wait()
+		close(process.done)
+		if DEBUG_EVENT {
+			logger.Printf("bot %s exit: %v \n", name, err)
+		}
+	}()
+	return process, nil
+}
+
+func (receiver *botProcess) writer(stdin io.WriteCloser) {
+	encoder := json.NewEncoder(stdin)
+	for observation := range receiver.observations {
+		if err := encoder.Encode(observation); err != nil {
+			logger.Println(err)
+			break
+		}
+	}
+	stdin.Close()
+}
+
+func (receiver *botProcess) reader(stdout io.Reader, output chan controller.Command) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		commands, err := parseBotAnswer(scanner.Bytes())
+		if err != nil {
+			logger.Println(err)
+		}
+		for _, command := range commands {
+			select {
+			case <-receiver.quit:
+				continue //stopped, drain output until bot exit
+			default:
+			}
+			select {
+			case output <- command:
+			case <-receiver.quit:
+			}
+		}
+		atomic.StoreInt32(&receiver.waiting, 0)
+	}
+}
+
+// stop close bot stdin, bot is expected to exit on it, otherwise it is killed
+func (receiver *botProcess) stop() {
+	close(receiver.quit)
+	close(receiver.observations)
+	time.AfterFunc(BOT_STOP_TIMEOUT, func() {
+		select {
+		case <-receiver.done:
+		default:
+			receiver.cmd.Process.Kill()
+		}
+	})
+}
+
+// handToBot give spawned unit to bot of its team, if any. Built control is restored on despawn
+func (receiver *Game) handToBot(object ObjectInterface) {
+	unit, ok := object.(*Unit)
+	if !ok {
+		return
+	}
+	command, ok := receiver.Bots[unit.GetAttr().Team]
+	if !ok {
+		return
+	}
+	if _, ok := unit.Control.(*BotControl); ok {
+		return
+	}
+	bot, _ := NewBotControl(command, receiver.SpawnManager.collider)
+	receiver.HandOff(unit, bot, nil)
+}
+
+func NewBotControl(command string, collider *collider.Collider) (*BotControl, error) {
+	return &BotControl{
+		Command:       command,
+		Collider:      collider,
+		seen:          make(map[*Unit]bool),
+		walls:         make(map[*Wall]bool),
+		commandChanel: make(chan controller.Command),
+	}, nil
+}
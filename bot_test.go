@@ -0,0 +1,36 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"errors"
+	"testing"
+)
+
+func TestParseBotAnswer(t *testing.T) {
+	commands, err := parseBotAnswer([]byte(`{"CType":1,"Pos":{"X":1,"Y":0},"Action":true}`))
+	if err != nil || len(commands) != 1 || commands[0].CType != controller.CTYPE_MOVE || commands[0].Pos.X != 1 || !commands[0].Action {
+		t.Error("single command not parsed", commands, err)
+	}
+	commands, err = parseBotAnswer([]byte(` [{"ctype":3,"action":true},{"ctype":1}] `))
+	if err != nil || len(commands) != 2 || commands[0].CType != controller.CTYPE_FIRE || commands[1].Action {
+		t.Error("command list not parsed", commands, err)
+	}
+	if commands, err = parseBotAnswer([]byte("[]")); err != nil || len(commands) != 0 {
+		t.Error("empty answer expected", commands, err)
+	}
+	if _, err = parseBotAnswer([]byte("fire")); !errors.Is(err, BotAnswerError) {
+		t.Error("garbage accepted", err)
+	}
+}
+
+func TestTeamBots(t *testing.T) {
+	bots := make(TeamBots)
+	if err := bots.Set("-1 = python3 bots/hunter.py"); err != nil || bots[-1] != "python3 bots/hunter.py" {
+		t.Error("team bot not set", bots, err)
+	}
+	for _, value := range []string{"python3 bot.py", "x=bot", "1=", "300=bot"} {
+		if err := bots.Set(value); !errors.Is(err, BotCommandLineError) {
+			t.Error("bad value accepted", value, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+#!/usr/bin/env python3
+# sample bot: drive to nearest enemy, fire when it is on gun line
+# run: GoConsoleBT --bot '1=python3 bots/hunter.py'
+import json
+import sys
+
+DIRECTION, MOVE, SPEED_FACTOR, FIRE, ALT_FIRE = range(5)
+IRRELEVANT = {"X": -100, "Y": -100}  # controller.PosIrrelevant
+
+
+def command(ctype, pos=IRRELEVANT, action=True):
+    return {"CType": ctype, "Pos": pos, "Action": action}
+
+
+def center(obj):
+    return obj["x"] + obj["w"] / 2, obj["y"] + obj["h"] / 2
+
+
+def decide(observation):
+    me = observation["unit"]
+    enemies = [u for u in observation["units"] if u["team"] != me["team"]]
+    if not enemies:
+        return [command(MOVE, action=False)]
+    mx, my = center(me)
+    target = min(enemies, key=lambda u: abs(center(u)[0] - mx) + abs(center(u)[1] - my))
+    dx, dy = center(target)[0] - mx, center(target)[1] - my
+    if abs(dx) < me["w"] / 2 or abs(dy) < me["h"] / 2:
+        direction = {"X": 0, "Y": 1 if dy > 0 else -1} if abs(dx) < me["w"] / 2 else {"X": 1 if dx > 0 else -1, "Y": 0}
+        commands = [command(MOVE, action=False), command(DIRECTION, direction)]
+        if not observation["gun"]["reloading"]:
+            commands.append(command(FIRE))
+        return commands
+    if abs(dx) < abs(dy):
+        return [command(MOVE, {"X": 1 if dx > 0 else -1, "Y": 0})]
+    return [command(MOVE, {"X": 0, "Y": 1 if dy > 0 else -1})]
+
+
+for line in sys.stdin:
+    print(json.dumps(decide(json.loads(line))), flush=True)
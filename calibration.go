@@ -3,6 +3,7 @@ package main
 import (
 	"GoConsoleBT/collider"
 	"GoConsoleBT/controller"
+	"github.com/eiannone/keyboard"
 	"math"
 )
 
@@ -13,6 +14,7 @@ type Calibration struct {
 	location *Location
 	*ObservableObject
 	*GameConfig
+	Mouse          <-chan keyboard.KeyEvent //click place active object and take probe, optional
 	internalChanel EventChanel
 	closeChanel    bool
 }
@@ -28,7 +30,22 @@ func (receiver *Calibration) Run(control *controller.Control) error {
 	receiver.spawn(active)
 	receiver.spawn(passive)
 
-	<-receiver.internalChanel
+	mouse := receiver.Mouse
+wait:
+	for {
+		select {
+		case <-receiver.internalChanel:
+			break wait
+		case event, ok := <-mouse:
+			if !ok {
+				mouse = nil
+				continue
+			}
+			if click, ok := controller.AsMouse(event); ok && click.IsClick() {
+				active.Click(click.X, click.Y)
+			}
+		}
+	}
 
 	active.Destroy(nil)
 	passive.Destroy(nil)
@@ -33,9 +33,18 @@ type CalibrationObject struct {
 	*ObservableObject
 	opposite *CalibrationObject
 	Probe    []*Point
+	clicks   chan Point
 }
 
 func (receiver *CalibrationObject) Update(timeLeft time.Duration) error {
+	select {
+	case point := <-receiver.clicks:
+		receiver.Move(point.X, point.Y)
+		receiver.moving = false
+		receiver.Calibrate()
+		return nil
+	default:
+	}
 	if receiver.moving {
 		receiver.GetClBody().RelativeMove(
 			receiver.Moving.Direction.X*receiver.Moving.Speed.X*(float64(timeLeft)/float64(time.Second)),
@@ -69,6 +78,14 @@ func (receiver *CalibrationObject) Execute(command controller.Command) error {
 	return nil
 }
 
+// Click place object at terminal cell and take probe, applied on next update
+func (receiver *CalibrationObject) Click(x, y int) {
+	select {
+	case receiver.clicks <- Point{X: float64(x), Y: float64(y)}:
+	default: //previous click not applied yet
+	}
+}
+
 func (receiver *CalibrationObject) Calibrate() {
 	index := len(receiver.Probe)
 
@@ -126,6 +143,7 @@ func NewCalibrationObject(control *controller.Control, chanel EventChanel, x, y
 		ObservableObject: oo,
 		opposite:         nil,
 		Probe:            make([]*Point, 0, 8),
+		clicks:           make(chan Point, 1),
 	}
 	calibrationObject.ControlledObject.Owner = calibrationObject
 	calibrationObject.ObservableObject.Owner = calibrationObject
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is game time, pipeline advance it by cycle time. It follow wall clock in real time game, stand still on pause
+// and run ahead in gym. Use it instead of time.Now for game logic (reload, reaction) and its Timer instead of
+// time.AfterFunc, so gym runs are repeatable
+var Clock = NewGameClock(time.Now())
+
+type clockTimer struct {
+	at  int64
+	seq int64
+	fn  func()
+}
+
+type GameClock struct {
+	Lockstep bool //gym, Timer and Every run on game time in game loop instead of wall clock
+	now      int64
+	seq      int64
+	timers   []*clockTimer
+	mutex    sync.Mutex
+}
+
+func (receiver *GameClock) Now() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&receiver.now))
+}
+
+func (receiver *GameClock) Advance(duration time.Duration) {
+	atomic.AddInt64(&receiver.now, int64(duration))
+}
+
+// AfterFunc call fn in game loop on first cycle game time pass duration, timers of one cycle run in due order
+func (receiver *GameClock) AfterFunc(duration time.Duration, fn func()) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.seq++
+	receiver.timers = append(receiver.timers, &clockTimer{
+		at:  atomic.LoadInt64(&receiver.now) + int64(duration),
+		seq: receiver.seq,
+		fn:  fn,
+	})
+}
+
+// Timer of game logic, time.AfterFunc in real time game, AfterFunc in lockstep
+func (receiver *GameClock) Timer(duration time.Duration, fn func()) {
+	if !receiver.Lockstep {
+		time.AfterFunc(duration, fn)
+		return
+	}
+	receiver.AfterFunc(duration, fn)
+}
+
+// Every call fn every duration until ctx is done, in lockstep in game loop every duration of game time
+func (receiver *GameClock) Every(duration time.Duration, fn func(), ctx context.Context) {
+	if !receiver.Lockstep {
+		everyFunc(duration, fn, ctx)
+		return
+	}
+	var tick func()
+	tick = func() {
+		if ctx.Err() != nil {
+			return
+		}
+		fn()
+		receiver.AfterFunc(duration, tick)
+	}
+	receiver.AfterFunc(duration, tick)
+}
+
+// Fire run due timers, pipeline call it after Advance. Timers added by them wait for next cycle
+func (receiver *GameClock) Fire() {
+	receiver.mutex.Lock()
+	now := atomic.LoadInt64(&receiver.now)
+	var due []*clockTimer
+	timers := receiver.timers[:0]
+	for _, timer := range receiver.timers {
+		if timer.at <= now {
+			due = append(due, timer)
+		} else {
+			timers = append(timers, timer)
+		}
+	}
+	for i := len(timers); i < len(receiver.timers); i++ {
+		receiver.timers[i] = nil
+	}
+	receiver.timers = timers
+	receiver.mutex.Unlock()
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].at != due[j].at {
+			return due[i].at < due[j].at
+		}
+		return due[i].seq < due[j].seq
+	})
+	for _, timer := range due {
+		timer.fn()
+	}
+}
+
+// Drop pending timers, gym drop ones of previous game on reset
+func (receiver *GameClock) Drop() {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.timers = nil
+}
+
+func NewGameClock(start time.Time) *GameClock {
+	return &GameClock{now: start.UnixNano()}
+}
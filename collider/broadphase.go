@@ -0,0 +1,172 @@
+package collider
+
+import (
+	"github.com/alh1m1k/ump"
+	"math"
+	"sort"
+)
+
+const BROADPHASE_CELL_SIZE = 16 //about two map zones, vision rect covers a few cells
+
+type cellKey struct {
+	x, y int32
+}
+
+type cellRect struct {
+	x1, y1, x2, y2 int32
+}
+
+type hashEntry struct {
+	body   *ump.Body
+	object Collideable
+	cells  cellRect
+}
+
+// spatialHash uniform grid of bodies for rect queries, body is kept in every cell it overlaps. Only cells that
+// have bodies exist, so world size is not limited
+type spatialHash struct {
+	cellSize float32
+	cells    map[cellKey][]*hashEntry
+	entries  map[*ump.Body]*hashEntry
+}
+
+func (receiver *spatialHash) insert(body *ump.Body, object Collideable) {
+	if entry, ok := receiver.entries[body]; ok {
+		entry.object = object
+		receiver.update(body)
+		return
+	}
+	entry := &hashEntry{body: body, object: object, cells: receiver.cellsOf(body)}
+	receiver.entries[body] = entry
+	receiver.enter(entry)
+}
+
+func (receiver *spatialHash) remove(body *ump.Body) {
+	if entry, ok := receiver.entries[body]; ok {
+		receiver.leave(entry)
+		delete(receiver.entries, body)
+	}
+}
+
+// update cells of moved body, nothing to do while it stays in the same ones
+func (receiver *spatialHash) update(body *ump.Body) {
+	entry, ok := receiver.entries[body]
+	if !ok {
+		return
+	}
+	if cells := receiver.cellsOf(body); cells != entry.cells {
+		receiver.leave(entry)
+		entry.cells = cells
+		receiver.enter(entry)
+	}
+}
+
+// query append objects of bodies overlapping rect (touching included) with one of tags to result. Ordered
+// query check bodies in order they were created, not map one, when it is cheaper to check every body
+func (receiver *spatialHash) query(result []Collideable, x, y, w, h float32, ordered bool, tags ...string) []Collideable {
+	rect := receiver.rectOf(x, y, x+w, y+h)
+	if (int64(rect.x2)-int64(rect.x1)+1)*(int64(rect.y2)-int64(rect.y1)+1) > int64(len(receiver.entries)) {
+		//rect wider than world, cheaper to check every body
+		if ordered {
+			return receiver.queryOrdered(result, x, y, w, h, tags)
+		}
+		for _, entry := range receiver.entries {
+			result = receiver.match(result, entry, x, y, w, h, tags)
+		}
+		return result
+	}
+	for cy := rect.y1; cy <= rect.y2; cy++ {
+		for cx := rect.x1; cx <= rect.x2; cx++ {
+			for _, entry := range receiver.cells[cellKey{x: cx, y: cy}] {
+				//body spanning several cells is reported by the first one shared with rect only
+				if maxInt32(entry.cells.x1, rect.x1) != cx || maxInt32(entry.cells.y1, rect.y1) != cy {
+					continue
+				}
+				result = receiver.match(result, entry, x, y, w, h, tags)
+			}
+		}
+	}
+	return result
+}
+
+func (receiver *spatialHash) queryOrdered(result []Collideable, x, y, w, h float32, tags []string) []Collideable {
+	entries := make([]*hashEntry, 0, len(receiver.entries))
+	for _, entry := range receiver.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].body.ID < entries[j].body.ID
+	})
+	for _, entry := range entries {
+		result = receiver.match(result, entry, x, y, w, h, tags)
+	}
+	return result
+}
+
+func (receiver *spatialHash) match(result []Collideable, entry *hashEntry, x, y, w, h float32, tags []string) []Collideable {
+	bx, by, _, _, br, bb := entry.body.Extents()
+	if br >= x && bb >= y && bx <= x+w && by <= y+h && entry.body.HasTag(tags...) {
+		result = append(result, entry.object)
+	}
+	return result
+}
+
+func (receiver *spatialHash) enter(entry *hashEntry) {
+	for cy := entry.cells.y1; cy <= entry.cells.y2; cy++ {
+		for cx := entry.cells.x1; cx <= entry.cells.x2; cx++ {
+			key := cellKey{x: cx, y: cy}
+			receiver.cells[key] = append(receiver.cells[key], entry)
+		}
+	}
+}
+
+func (receiver *spatialHash) leave(entry *hashEntry) {
+	for cy := entry.cells.y1; cy <= entry.cells.y2; cy++ {
+		for cx := entry.cells.x1; cx <= entry.cells.x2; cx++ {
+			key := cellKey{x: cx, y: cy}
+			cell := receiver.cells[key]
+			for index, candidate := range cell {
+				if candidate == entry {
+					last := len(cell) - 1
+					cell[index], cell[last] = cell[last], nil
+					cell = cell[:last]
+					break
+				}
+			}
+			if len(cell) == 0 {
+				delete(receiver.cells, key)
+			} else {
+				receiver.cells[key] = cell
+			}
+		}
+	}
+}
+
+func (receiver *spatialHash) cellsOf(body *ump.Body) cellRect {
+	x, y, _, _, r, b := body.Extents()
+	return receiver.rectOf(x, y, r, b)
+}
+
+func (receiver *spatialHash) rectOf(x, y, r, b float32) cellRect {
+	return cellRect{
+		x1: int32(math.Floor(float64(x / receiver.cellSize))),
+		y1: int32(math.Floor(float64(y / receiver.cellSize))),
+		x2: int32(math.Floor(float64(r / receiver.cellSize))),
+		y2: int32(math.Floor(float64(b / receiver.cellSize))),
+	}
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func newSpatialHash(cellSize int, size int) *spatialHash {
+	return &spatialHash{
+		cellSize: float32(cellSize),
+		cells:    make(map[cellKey][]*hashEntry, size),
+		entries:  make(map[*ump.Body]*hashEntry, size),
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"log"
 	"math"
 	"os"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -22,9 +24,14 @@ type Collideable interface {
 }
 
 type Collider struct {
-	bodyMap map[*ump.Body]Collideable
-	world   *ump.World
-	ver     bool //odd even
+	Lockstep bool //move bodies in order they were created, repeatable runs
+	bodyMap  map[*ump.Body]Collideable
+	objects  map[Collideable][]*ump.Body //reverse of bodyMap
+	ordered  []*ump.Body
+	hash     *spatialHash
+	world    *ump.World
+	ver      bool //odd even
+	mutex    sync.RWMutex
 }
 
 // todo remove
@@ -35,34 +42,9 @@ func (c *Collider) AddExtra(clBody *ClBody, object Collideable) error {
 	if clBody.fake {
 		return nil
 	}
-	clBody = clBody.First
-	clBody.ver = c.ver
-	for clBody != nil {
-		if clBody.realBody == nil {
-			x, y, w, h := clBody.GetRect()
-			body := c.world.Add(clBody.filter, float32(x), float32(y), float32(w), float32(h))
-			body.SetStatic(clBody.static)
-
-			//penetrate will not affect on other object (its coordinates)
-			if clBody.First.penetrate {
-				body.SetResponse("penetrate", "cross")
-				body.SetResponse("static", "cross")
-				body.SetResponse("base", "cross")
-				body.SetResponse("perimeter", "cross")
-			} else {
-				body.SetResponse("penetrate", "cross")
-				body.SetResponse("static", "grid")
-				body.SetResponse("base", "grid")
-				body.SetResponse("perimeter", "perimeter")
-			}
-
-			clBody.realBody = body
-		} else {
-			//reenter
-		}
-		c.bodyMap[clBody.realBody] = object
-		clBody = clBody.Next
-	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.addBodies(clBody.First, object)
 	return nil
 }
 
@@ -78,6 +60,13 @@ func (c *Collider) Add(object Collideable) error {
 	if clBody.fake {
 		return nil
 	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.addBodies(clBody, object)
+	return nil
+}
+
+func (c *Collider) addBodies(clBody *ClBody, object Collideable) {
 	clBody.ver = c.ver
 	for clBody != nil {
 		if clBody.realBody == nil {
@@ -102,10 +91,13 @@ func (c *Collider) Add(object Collideable) error {
 		} else {
 			//reenter
 		}
+		if _, ok := c.bodyMap[clBody.realBody]; !ok {
+			c.objects[object] = append(c.objects[object], clBody.realBody)
+		}
 		c.bodyMap[clBody.realBody] = object
+		c.hash.insert(clBody.realBody, object)
 		clBody = clBody.Next
 	}
-	return nil
 }
 
 func (c *Collider) Remove(object Collideable) {
@@ -113,14 +105,9 @@ func (c *Collider) Remove(object Collideable) {
 	if clBody.fake {
 		return
 	}
-	for indx, candidate := range c.bodyMap {
-		if object == candidate {
-			delete(c.bodyMap, indx)
-			indx.Remove() //todo reenther
-			clBody.realBody = nil
-			clBody.First.collisionInfo.Clear()
-		}
-	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.removeBodies(clBody, object)
 }
 
 // todo remove
@@ -128,18 +115,34 @@ func (c *Collider) RemoveExtra(clBody *ClBody, object Collideable) {
 	if clBody.fake {
 		return
 	}
-	for indx, candidate := range c.bodyMap {
-		if object == candidate {
-			delete(c.bodyMap, indx)
-			indx.Remove() //todo reenther
-			clBody.realBody = nil
-			clBody.First.collisionInfo.Clear()
-		}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.removeBodies(clBody, object)
+}
+
+// removeBodies drop every body of object, O(bodies of object) by reverse index
+func (c *Collider) removeBodies(clBody *ClBody, object Collideable) {
+	bodies, ok := c.objects[object]
+	if !ok {
+		return
+	}
+	for _, body := range bodies {
+		delete(c.bodyMap, body)
+		c.hash.remove(body)
+		body.Remove() //todo reenther
+	}
+	delete(c.objects, object)
+	clBody.First.collisionInfo.Clear()
+	for clBody = clBody.First; clBody != nil; clBody = clBody.Next {
+		clBody.realBody = nil
 	}
 }
 
 func (c *Collider) Execute(timeLeft time.Duration) {
-	for realBody, object := range c.bodyMap {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, realBody := range c.bodies() {
+		object := c.bodyMap[realBody]
 		if realBody == nil {
 			continue
 		}
@@ -152,9 +155,11 @@ func (c *Collider) Execute(timeLeft time.Duration) {
 		for clBody != nil {
 			if clBody.static {
 				realBody.Update(float32(x), float32(y))
+				c.hash.update(realBody)
 				//info must be clear even if no collision at this time
 			} else {
 				newX, newY, collisions := realBody.Move(float32(x), float32(y))
+				c.hash.update(realBody)
 				for _, collision := range collisions {
 					if collideWith, ok := c.bodyMap[collision.Body]; !ok {
 						panic("undefined object in world!")
@@ -177,13 +182,34 @@ func (c *Collider) Execute(timeLeft time.Duration) {
 	c.ver = !c.ver
 }
 
+// bodies to move, map order unless Lockstep
+func (c *Collider) bodies() []*ump.Body {
+	c.ordered = c.ordered[:0]
+	for realBody := range c.bodyMap {
+		c.ordered = append(c.ordered, realBody)
+	}
+	if c.Lockstep {
+		sort.Slice(c.ordered, func(i, j int) bool {
+			return c.ordered[i].ID < c.ordered[j].ID
+		})
+	}
+	return c.ordered
+}
+
 // QueryRect will take the rectangle arguments and return any bodies that are in
 // that rectangle
 //
 // If tags are passed into the query then it will only return the bodies with those
 // tags.
 func (c *Collider) QueryRect(x, y, w, h float64, tags ...string) []Collideable {
-	return c.filterBody(c.world.QueryRect(float32(x), float32(y), float32(w), float32(h), tags...), float32(x), float32(y), float32(w), float32(h))
+	return c.AppendRect(nil, x, y, w, h, tags...)
+}
+
+// AppendRect same as QueryRect but append bodies to result, so caller may reuse it between queries
+func (c *Collider) AppendRect(result []Collideable, x, y, w, h float64, tags ...string) []Collideable {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.hash.query(result, float32(x), float32(y), float32(w), float32(h), c.Lockstep, tags...)
 }
 
 // QueryPoint will return any bodies that are underneathe the point.
@@ -191,6 +217,8 @@ func (c *Collider) QueryRect(x, y, w, h float64, tags ...string) []Collideable {
 // If tags are passed into the query then it will only return the bodies with those
 // tags.
 func (c *Collider) QueryPoint(x, y float64, tags ...string) []Collideable {
+	c.mutex.Lock() //lib creates cells on query
+	defer c.mutex.Unlock()
 	bodyList := c.world.QueryPoint(float32(x), float32(y), tags...)
 	return c.bodyList2Collideable(bodyList)
 }
@@ -200,24 +228,12 @@ func (c *Collider) QueryPoint(x, y float64, tags ...string) []Collideable {
 // If tags are passed into the query then it will only return the bodies with those
 // tags.
 func (c *Collider) QuerySegment(x1, y1, x2, y2 float64, tags ...string) []Collideable {
+	c.mutex.Lock() //lib creates cells on query
+	defer c.mutex.Unlock()
 	bodyList := c.world.QuerySegment(float32(x1), float32(y1), float32(x2), float32(y2), tags...)
 	return c.bodyList2Collideable(bodyList)
 }
 
-// additional filtering due lib do not apply it to result :(
-func (c *Collider) filterBody(bodyList []*ump.Body, x, y, w, h float32) []Collideable {
-	result := make([]Collideable, 0, len(bodyList))
-	for _, body := range bodyList {
-		bx, by, _, _, br, bb := body.Extents()
-		if br >= x && bb >= y && bx <= x+w && by <= y+h {
-			if object, ok := c.bodyMap[body]; ok {
-				result = append(result, object)
-			}
-		}
-	}
-	return result
-}
-
 func (c *Collider) bodyList2Collideable(bodyList []*ump.Body) []Collideable {
 	result := make([]Collideable, 0, len(bodyList))
 	for _, body := range bodyList {
@@ -231,6 +247,8 @@ func (c *Collider) bodyList2Collideable(bodyList []*ump.Body) []Collideable {
 func NewCollider(queueSize int) (*Collider, error) {
 	cl := &Collider{
 		bodyMap: make(map[*ump.Body]Collideable, queueSize),
+		objects: make(map[Collideable][]*ump.Body, queueSize),
+		hash:    newSpatialHash(BROADPHASE_CELL_SIZE, queueSize),
 		world:   ump.NewWorld(64),
 		ver:     true,
 	}
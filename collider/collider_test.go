@@ -0,0 +1,99 @@
+package collider
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// scatter count static bodies of tank size over w x h world
+func scatter(tb testing.TB, count int, w, h float64) (*Collider, []*ClBody) {
+	collider, _ := NewCollider(count)
+	random := rand.New(rand.NewSource(int64(count)))
+	bodies := make([]*ClBody, count)
+	for i := range bodies {
+		bodies[i] = NewStaticCollision(random.Float64()*w, random.Float64()*h, 5, 3)
+		if err := collider.Add(bodies[i]); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return collider, bodies
+}
+
+func inRect(body *ClBody, x, y, w, h float64) bool {
+	bx, by, bw, bh := body.GetRect()
+	return bx+bw >= x && by+bh >= y && bx <= x+w && by <= y+h
+}
+
+func TestQueryRect(t *testing.T) {
+	collider, bodies := scatter(t, 500, 400, 200)
+	for _, body := range bodies[:250] {
+		collider.Remove(body)
+	}
+	mover := NewPenetrateCollision(10, 10, 5, 3)
+	collider.Add(mover)
+	mover.Move(300, 150)
+	collider.Execute(0)
+	bodies = append(bodies[250:], mover)
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		x, y, w, h := random.Float64()*440-20, random.Float64()*220-10, random.Float64()*60, random.Float64()*30
+		found := make(map[Collideable]int)
+		for _, object := range collider.QueryRect(x, y, w, h) {
+			found[object]++
+		}
+		for _, body := range bodies {
+			if expected := inRect(body, x, y, w, h); (found[body] == 1) != expected || found[body] > 1 {
+				t.Fatal("rect", x, y, w, h, "body", body.x, body.y, "found", found[body], "expected", expected)
+			}
+		}
+		if len(found) != len(collider.QueryRect(x, y, w, h)) {
+			t.Error("removed body found in", x, y, w, h)
+		}
+	}
+	for _, object := range collider.QueryRect(10, 10, 5, 3) {
+		if object == mover {
+			t.Error("mover left in old cells")
+		}
+	}
+	if found := collider.QueryRect(-1e6, -1e6, 2e6, 2e6); len(found) != len(bodies) {
+		t.Error("whole world query", len(found), "expected", len(bodies))
+	}
+}
+
+func BenchmarkQueryRect(b *testing.B) {
+	for _, count := range []int{500, 2000} {
+		collider, _ := scatter(b, count, 400, 200)
+		b.Run(fmt.Sprint("hash-", count), func(b *testing.B) {
+			var result []Collideable
+			for i := 0; i < b.N; i++ {
+				result = collider.AppendRect(result[:0], float64(i%360), float64(i%180), 40, 20)
+			}
+		})
+		//lib grid query with exact filtering, as it was before the hash
+		b.Run(fmt.Sprint("world-", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				x, y := float32(i%360), float32(i%180)
+				for _, body := range collider.world.QueryRect(x, y, 40, 20) {
+					bx, by, _, _, br, bb := body.Extents()
+					if br >= x && bb >= y && bx <= x+40 && by <= y+20 {
+						_ = collider.bodyMap[body]
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRemove(b *testing.B) {
+	for _, count := range []int{500, 2000} {
+		collider, _ := scatter(b, count, 400, 200)
+		b.Run(fmt.Sprint(count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				body := NewStaticCollision(float64(i%400), float64(i%200), 5, 3)
+				collider.Add(body)
+				collider.Remove(body)
+			}
+		})
+	}
+}
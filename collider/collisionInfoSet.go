@@ -5,7 +5,8 @@ import (
 )
 
 type CollisionInfoSet struct {
-	m map[Collideable]*ump.Collision
+	m    map[Collideable]*ump.Collision
+	keys []Collideable //in order of Add
 }
 
 func (receiver *CollisionInfoSet) Size() int {
@@ -13,9 +14,17 @@ func (receiver *CollisionInfoSet) Size() int {
 }
 
 func (receiver *CollisionInfoSet) Add(object Collideable, collision *ump.Collision) {
+	if _, ok := receiver.m[object]; !ok {
+		receiver.keys = append(receiver.keys, object)
+	}
 	receiver.m[object] = collision
 }
 
+// Keys objects in order they were added, collisions of same moves are reported in same order
+func (receiver *CollisionInfoSet) Keys() []Collideable {
+	return receiver.keys
+}
+
 func (receiver *CollisionInfoSet) I() map[Collideable]*ump.Collision {
 	return receiver.m
 }
@@ -24,6 +33,10 @@ func (receiver *CollisionInfoSet) Clear() {
 	for index, _ := range receiver.m { //prey to https://go-review.googlesource.com/c/go/+/110055/
 		delete(receiver.m, index)
 	}
+	for i := range receiver.keys {
+		receiver.keys[i] = nil
+	}
+	receiver.keys = receiver.keys[:0]
 }
 
 func NewCollisionInfo(size int) *CollisionInfoSet {
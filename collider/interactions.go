@@ -13,6 +13,7 @@ type CollisionReceiver interface {
 
 type Interactions struct {
 	iteractions map[Collideable]time.Duration
+	order       []Collideable //keys of iteractions by start
 	subscribers []CollisionReceiver
 }
 
@@ -30,15 +31,24 @@ func (receiver *Interactions) Unsubscribe(collideable CollisionReceiver) {
 }
 
 func (receiver *Interactions) Interact(source Collideable, timeLeft time.Duration) {
-	collisions := source.GetClBody().CollisionInfo().I()
-	for collideable, collisionTime := range receiver.iteractions {
+	info := source.GetClBody().CollisionInfo()
+	collisions := info.I()
+	order := receiver.order[:0]
+	for _, collideable := range receiver.order {
 		if _, ok := collisions[collideable]; !ok {
-			receiver.OnStopCollide(collideable, collisionTime, receiver)
+			receiver.OnStopCollide(collideable, receiver.iteractions[collideable], receiver)
 			delete(receiver.iteractions, collideable)
+		} else {
+			order = append(order, collideable)
 		}
 	}
+	for i := len(order); i < len(receiver.order); i++ {
+		receiver.order[i] = nil
+	}
+	receiver.order = order
 
-	for collideable, collision := range collisions {
+	for _, collideable := range info.Keys() {
+		collision := collisions[collideable]
 		if _, ok := receiver.iteractions[collideable]; ok {
 			receiver.OnTickCollide(collideable, collision, receiver)
 			receiver.iteractions[collideable] += timeLeft
@@ -46,6 +56,7 @@ func (receiver *Interactions) Interact(source Collideable, timeLeft time.Duratio
 			receiver.OnStartCollide(collideable, collision, receiver)
 			receiver.OnTickCollide(collideable, collision, receiver)
 			receiver.iteractions[collideable] = timeLeft
+			receiver.order = append(receiver.order, collideable)
 		}
 	}
 }
@@ -54,6 +65,10 @@ func (receiver *Interactions) Clear() {
 	for key, _ := range receiver.iteractions {
 		delete(receiver.iteractions, key)
 	}
+	for i := range receiver.order {
+		receiver.order[i] = nil
+	}
+	receiver.order = receiver.order[:0]
 	i, j := 0, 0
 	for i < len(receiver.subscribers) {
 		if receiver.subscribers[i] == nil {
@@ -106,6 +121,7 @@ func (receiver *Interactions) Copy() *Interactions {
 	for key, value := range receiver.iteractions {
 		instanse.iteractions[key] = value
 	}
+	instanse.order = append([]Collideable(nil), receiver.order...)
 	return instanse
 }
 
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	direct "github.com/buger/goterm"
+	"github.com/eiannone/keyboard"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CONSOLE_LINES   = 8 //output lines kept on screen
+	CONSOLE_HISTORY = 50
+	CONSOLE_ZINDEX  = OVERLAY_SCREEN_ZINDEX + 1
+	CONSOLE_WAIT    = time.Second //for game loop to run command
+)
+
+var (
+	UnknownCommandError  = errors.New("unknown command")
+	CommandArgumentError = errors.New("wrong arguments")
+	ObjectNotFoundError  = errors.New("object not found")
+	GameLoopBusyError    = errors.New("game loop does not respond, command queued")
+)
+
+type consoleCommand struct {
+	usage string
+	run   func(console *Console, args []string) (string, error)
+}
+
+var consoleCommands = map[string]*consoleCommand{
+	"spawn": {
+		usage: "spawn <blueprint> [x y] [team]",
+		run: func(console *Console, args []string) (string, error) {
+			if len(args) == 0 || len(args) > 4 {
+				return "", CommandArgumentError
+			}
+			request := &SpawnRequest{Position: PosAuto, Location: ZoneAuto, Team: 1, Blueprint: args[0], Count: 1}
+			rest := args[1:]
+			if len(rest) >= 2 {
+				x, errX := strconv.ParseFloat(rest[0], 64)
+				y, errY := strconv.ParseFloat(rest[1], 64)
+				if errX != nil || errY != nil {
+					return "", CommandArgumentError
+				}
+				request.Position, rest = Point{X: x, Y: y}, rest[2:]
+			}
+			if len(rest) == 1 {
+				team, err := strconv.ParseInt(rest[0], 10, 8)
+				if err != nil {
+					return "", CommandArgumentError
+				}
+				request.Team = int8(team)
+			}
+			runner := console.runner
+			if runner.Scenario == nil {
+				return "", GameNotInProgressError
+			}
+			if _, err := runner.BlueprintManager.Get(request.Blueprint); err != nil {
+				return "", err
+			}
+			//spawner maps are read by game cycle
+			return console.inGame(func() (string, error) {
+				recursiveRequire(request.Blueprint, runner.BlueprintManager, runner.SpawnManager, runner.BehaviorControlBuilder)
+				runner.Scenario.Trigger(SpawnReqEvent, runner.Scenario, request)
+				return "spawn " + request.Blueprint, nil
+			})
+		},
+	},
+	"kill": {
+		usage: "kill <id>",
+		run: func(console *Console, args []string) (string, error) {
+			return console.inGame(func() (string, error) {
+				object, err := console.object(args)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d destroyed", object.GetAttr().ID), object.Destroy(nil)
+			})
+		},
+	},
+	"god": {
+		usage: "god",
+		run: func(console *Console, args []string) (string, error) {
+			return console.inGame(func() (string, error) {
+				DEBUG_IMMORTAL_PLAYER = !DEBUG_IMMORTAL_PLAYER
+				return "immortal_player " + onOff(DEBUG_IMMORTAL_PLAYER), nil
+			})
+		},
+	},
+	"freeze-ai": {
+		usage: "freeze-ai",
+		run: func(console *Console, args []string) (string, error) {
+			return console.inGame(func() (string, error) {
+				DEBUG_FREEZ_AI = !DEBUG_FREEZ_AI
+				return "freeze_ai " + onOff(DEBUG_FREEZ_AI), nil
+			})
+		},
+	},
+	"give": {
+		usage: "give <projectile> <ammo>",
+		run: func(console *Console, args []string) (string, error) {
+			if len(args) != 2 {
+				return "", CommandArgumentError
+			}
+			ammo, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return "", CommandArgumentError
+			}
+			runner := console.runner
+			info, err := runner.BlueprintManager.Info(args[0])
+			if err != nil {
+				return "", err
+			}
+			if info.Type != "projectile" {
+				return "", fmt.Errorf("%s is not projectile: %w", args[0], CommandArgumentError)
+			}
+			return console.inGame(func() (string, error) {
+				recursiveRequire(args[0], runner.BlueprintManager, runner.SpawnManager, runner.BehaviorControlBuilder)
+				given := 0
+				for _, player := range runner.players {
+					if player.Unit == nil || player.Unit.destroyed {
+						continue
+					}
+					player.Unit.Gun.Upgrade(&GunState{
+						Projectile:       args[0],
+						Name:             info.Name,
+						Ammo:             ammo,
+						ShotQueue:        1,
+						PerShotQueueTime: time.Second / 5,
+						ReloadTime:       time.Second,
+					})
+					given++
+				}
+				return fmt.Sprintf("%s x%d given to %d player(s)", info.Name, ammo, given), nil
+			})
+		},
+	},
+	"state": {
+		usage: "state <id> <path>",
+		run: func(console *Console, args []string) (string, error) {
+			if len(args) != 2 {
+				return "", CommandArgumentError
+			}
+			return console.inGame(func() (string, error) {
+				object, err := console.object(args[:1])
+				if err != nil {
+					return "", err
+				}
+				stater, ok := object.(Stater)
+				if !ok {
+					return "", fmt.Errorf("%d has no state: %w", object.GetAttr().ID, CommandArgumentError)
+				}
+				if err := stater.Enter(args[1]); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d enter %s", object.GetAttr().ID, args[1]), nil
+			})
+		},
+	},
+	"nav": {
+		usage: "nav",
+		run: func(console *Console, args []string) (string, error) {
+			if console.runner.Game == nil || console.runner.Game.Navigation == nil {
+				return "", GameNotInProgressError
+			}
+			metrics := console.runner.Game.Navigation.Metrics()
+			return fmt.Sprintf("queued %d, working %d, done %d, canceled %d, deduplicated %d, repaired %d, replanned %d, latency %s (max %s)",
+				metrics.Queued, metrics.Working, metrics.Done, metrics.Canceled, metrics.Deduplicated, metrics.Repaired,
+				metrics.Replanned, metrics.Latency.Round(time.Microsecond), metrics.MaxLatency.Round(time.Microsecond)), nil
+		},
+	},
+	"keys": {
+		usage: "keys",
+		run: func(console *Console, args []string) (string, error) {
+			if console.runner == nil || console.runner.KeyboardRepeater == nil {
+				return "", GameNotInProgressError
+			}
+			repeater := console.runner.KeyboardRepeater
+			return fmt.Sprintf("captured %s, dropped %d, by console %d", onOff(repeater.IsCaptured()),
+				repeater.Dropped(nil), repeater.Dropped(console.runner.consoleKeyboard)), nil
+		},
+	},
+	"set": {
+		usage: "set <flag> on|off",
+		run: func(console *Console, args []string) (string, error) {
+			if len(args) == 0 {
+				return console.inGame(func() (string, error) {
+					enabled := make([]string, 0)
+					for _, name := range DebugFlagNames() {
+						if *DebugFlags[name] {
+							enabled = append(enabled, name)
+						}
+					}
+					return "on: " + strings.Join(enabled, " ") + "; flags: " + strings.Join(DebugFlagNames(), " "), nil
+				})
+			}
+			if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+				return "", CommandArgumentError
+			}
+			return console.inGame(func() (string, error) {
+				if err := SetDebugFlag(args[0], args[1] == "on"); err != nil {
+					return "", err
+				}
+				return args[0] + " " + args[1], nil
+			})
+		},
+	},
+}
+
+func consoleUsage() string {
+	usage := make([]string, 0, len(consoleCommands))
+	for _, command := range consoleCommands {
+		usage = append(usage, command.usage)
+	}
+	sort.Strings(usage)
+	return strings.Join(usage, ", ")
+}
+
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
+
+// Console is drop-down developer console, it is driven by runner keyboard while open. Game is not paused
+type Console struct {
+	*Screen
+	runner  *GameRunner
+	input   []rune
+	lines   []string
+	history []string
+	recall  int
+}
+
+func (receiver *Console) GetXY() Point {
+	return Point{}
+}
+
+func (receiver *Console) GetZIndex() int {
+	return CONSOLE_ZINDEX
+}
+
+// HandleKey process single key event, return true if console must be closed
+func (receiver *Console) HandleKey(event keyboard.KeyEvent) (done bool) {
+	switch key := event.Key; {
+	case key == keyboard.KeyEsc || (key == 0 && event.Rune == '`'):
+		return true
+	case key == keyboard.KeyEnter:
+		line := strings.TrimSpace(string(receiver.input))
+		receiver.input = receiver.input[0:0]
+		if line != "" {
+			receiver.history = append(receiver.history, line)
+			if len(receiver.history) > CONSOLE_HISTORY {
+				receiver.history = receiver.history[1:]
+			}
+			receiver.print("> " + line)
+			receiver.print(receiver.Execute(line))
+		}
+		receiver.recall = len(receiver.history)
+	case key == keyboard.KeyBackspace || key == keyboard.KeyBackspace2:
+		if len(receiver.input) > 0 {
+			receiver.input = receiver.input[:len(receiver.input)-1]
+		}
+	case key == keyboard.KeyArrowUp || key == keyboard.KeyArrowDown:
+		if key == keyboard.KeyArrowUp && receiver.recall > 0 {
+			receiver.recall--
+		}
+		if key == keyboard.KeyArrowDown && receiver.recall < len(receiver.history) {
+			receiver.recall++
+		}
+		receiver.input = receiver.input[0:0]
+		if receiver.recall < len(receiver.history) {
+			receiver.input = append(receiver.input, []rune(receiver.history[receiver.recall])...)
+		}
+	case key == keyboard.KeySpace:
+		receiver.input = append(receiver.input, ' ')
+	case key == 0 && event.Rune != 0:
+		receiver.input = append(receiver.input, event.Rune)
+	}
+	receiver.redraw()
+	return false
+}
+
+// Execute run one command line, result or error is returned as text
+func (receiver *Console) Execute(line string) string {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return ""
+	}
+	if args[0] == "help" {
+		return consoleUsage()
+	}
+	command, ok := consoleCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("%s: %w, try help", args[0], UnknownCommandError).Error()
+	}
+	result, err := command.run(receiver, args[1:])
+	if errors.Is(err, CommandArgumentError) {
+		return err.Error() + ", usage: " + command.usage
+	}
+	if err != nil {
+		return err.Error()
+	}
+	logger.Printf("console: %s: %s \n", line, result)
+	return result
+}
+
+// inGame run command in game loop and wait for result: game objects and debug flags are used by pipeline stages
+// and must not be changed from runner goroutine. Without game loop command run right away
+func (receiver *Console) inGame(command func() (string, error)) (string, error) {
+	if receiver.runner == nil || receiver.runner.Pipeline == nil {
+		return command()
+	}
+	type result struct {
+		text string
+		err  error
+	}
+	done := make(chan result, 1)
+	receiver.runner.Pipeline.Defer(func() {
+		text, err := command()
+		done <- result{text: text, err: err}
+	})
+	select {
+	case result := <-done:
+		return result.text, result.err
+	case <-time.After(CONSOLE_WAIT):
+		return "", GameLoopBusyError
+	}
+}
+
+func (receiver *Console) object(args []string) (ObjectInterface, error) {
+	if len(args) != 1 {
+		return nil, CommandArgumentError
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return nil, CommandArgumentError
+	}
+	object := receiver.runner.SpawnManager.QuerySpawnedById(id)
+	if object == nil {
+		return nil, fmt.Errorf("%d: %w", id, ObjectNotFoundError)
+	}
+	return object, nil
+}
+
+func (receiver *Console) print(line string) {
+	receiver.lines = append(receiver.lines, line)
+	if len(receiver.lines) > CONSOLE_LINES {
+		receiver.lines = receiver.lines[len(receiver.lines)-CONSOLE_LINES:]
+	}
+}
+
+func (receiver *Console) redraw() {
+	width := maxInt(direct.Width(), 40)
+	var buf strings.Builder
+	for i := 0; i < CONSOLE_LINES-len(receiver.lines); i++ {
+		buf.WriteString(strings.Repeat(" ", width) + "\n")
+	}
+	for _, line := range append(receiver.lines, "`> "+string(receiver.input)+"_") {
+		runes := []rune(line)
+		if len(runes) > width {
+			runes = runes[len(runes)-width:] //keep tail, input is typed there
+		}
+		buf.WriteString(string(runes) + strings.Repeat(" ", width-len(runes)) + "\n")
+	}
+	buf.WriteString(strings.Repeat("=", width))
+
+	sprite := NewContentSprite([]byte(buf.String()))
+	receiver.size = Point{X: float64(sprite.Size.W), Y: float64(sprite.Size.H)}
+	receiver.sprite = sprite
+}
+
+func NewConsole(runner *GameRunner) (*Console, error) {
+	screen, _ := NewScreen(nil)
+	instance := &Console{
+		Screen: screen,
+		runner: runner,
+		lines:  make([]string, 0, CONSOLE_LINES),
+	}
+	instance.redraw()
+	return instance, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestConsoleSet(t *testing.T) {
+	console, _ := NewConsole(nil)
+	defer func() { DEBUG_SHOW_ID = false }()
+	if result := console.Execute("set show_id on"); result != "show_id on" || !DEBUG_SHOW_ID {
+		t.Error("flag not set", result)
+	}
+	if result := console.Execute("set show_id off"); DEBUG_SHOW_ID {
+		t.Error("flag not cleared", result)
+	}
+	if result := console.Execute("set no_such_flag on"); !strings.Contains(result, UnknownDebugFlagError.Error()) {
+		t.Error("unknown flag accepted", result)
+	}
+	if result := console.Execute("set show_id maybe"); !strings.Contains(result, "usage: set") {
+		t.Error("usage expected", result)
+	}
+	if result := console.Execute("fly"); !strings.Contains(result, UnknownCommandError.Error()) {
+		t.Error("unknown command accepted", result)
+	}
+}
+
+// execInLoop run console command while test drive game loop, like runner goroutine do while game is running
+func execInLoop(gym *Gym, console *Console, line string) string {
+	result := make(chan string)
+	go func() {
+		result <- console.Execute(line)
+	}()
+	for {
+		select {
+		case text := <-result:
+			return text
+		default:
+			gym.cycle()
+		}
+	}
+}
+
+func TestConsoleGameCommands(t *testing.T) {
+	gym := testGym(t)
+	if _, err := gym.Reset(5); err != nil {
+		t.Fatal(err)
+	}
+	runner := &GameRunner{
+		Pipeline:               gym.pipe,
+		SpawnManager:           gym.spawner,
+		BlueprintManager:       buildManager,
+		BehaviorControlBuilder: gym.builder,
+		Game:                   gym.game,
+	}
+	console, _ := NewConsole(runner)
+	player := gym.players[0].Unit
+	var enemy *Unit
+	for _, object := range gym.spawner.QuerySpawnedByTag("tank") {
+		if unit, ok := object.(*Unit); ok && unit != player && !unit.HasTag("base") {
+			enemy = unit
+			break
+		}
+	}
+	if player == nil || enemy == nil {
+		t.Fatal("no player or enemy tank spawned")
+	}
+
+	id := strconv.FormatInt(enemy.ID, 10)
+	if result := execInLoop(gym, console, "kill "+id); result != id+" destroyed" || !enemy.destroyed {
+		t.Error("enemy not killed", result)
+	}
+	if result := execInLoop(gym, console, "kill 999999"); !strings.Contains(result, ObjectNotFoundError.Error()) {
+		t.Error("unknown object killed", result)
+	}
+	if result := execInLoop(gym, console, "kill"); !strings.Contains(result, "usage: kill") {
+		t.Error("usage expected", result)
+	}
+
+	id = strconv.FormatInt(player.ID, 10)
+	if result := execInLoop(gym, console, "state "+id+" /receiveDamage/top"); result != id+" enter /receiveDamage/top" ||
+		player.State.path != "/receiveDamage/top" {
+		t.Error("state not entered", result, player.State.path)
+	}
+	if result := execInLoop(gym, console, "state "+id+" /no/such/state"); !strings.Contains(result, "state") ||
+		player.State.path != "/receiveDamage/top" {
+		t.Error("unknown state entered", result, player.State.path)
+	}
+
+	if result := execInLoop(gym, console, "give projectile-sharp 7"); !strings.HasSuffix(result, "x7 given to 1 player(s)") {
+		t.Error("projectile not given", result)
+	}
+	if current := player.Gun.Current; current == nil || current.Projectile != "projectile-sharp" || current.Ammo != 7 {
+		t.Errorf("gun state %+v", current)
+	}
+	if result := execInLoop(gym, console, "give tank 7"); !strings.Contains(result, "is not projectile") {
+		t.Error("tank given as projectile", result)
+	}
+}
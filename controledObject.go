@@ -11,6 +11,17 @@ type ControlledObjectInterface interface {
 	Execute(command controller.Command) error
 }
 
+// unitAttachable control need unit it drive (behavior ai, bot)
+type unitAttachable interface {
+	AttachTo(object *Unit)
+}
+
+// unitWatcher control told what its unit see
+type unitWatcher interface {
+	See(object *Unit)
+	UnSee(object *Unit)
+}
+
 type ControlledObject struct {
 	Owner            ControlledObjectInterface
 	dispatcherEnable bool
@@ -30,6 +41,9 @@ func (receiver *ControlledObject) Execute(command controller.Command) error {
 
 func (receiver *ControlledObject) Deactivate() error {
 	receiver.Control.Disable()
+	if control, ok := receiver.Control.(*controller.Control); ok {
+		control.Rewind() //next life start script over
+	}
 	if receiver.dispatcherEnable {
 		close(receiver.terminator)
 	}
@@ -47,15 +61,35 @@ func (receiver *ControlledObject) Activate() error {
 		go coCmdDispatcher(receiver, receiver.Control.GetCommandChanel(), receiver.terminator)
 	}
 	receiver.dispatcherEnable = true
-	if bc, ok := receiver.Control.(*BehaviorControl); ok {
+	if control, ok := receiver.Control.(unitAttachable); ok {
 		if unit, ok := receiver.Owner.(*Unit); ok {
-			bc.AttachTo(unit) //todo simplify
+			control.AttachTo(unit) //todo simplify
 		}
 	}
 	receiver.Control.Enable()
 	return nil
 }
 
+// SwapControl replace control on the fly, previous one is released and may be attached to other object.
+// Owner is stopped, so it does not keep motion of previous control
+func (receiver *ControlledObject) SwapControl(control controller.Controller) (previous controller.Controller) {
+	previous = receiver.Control
+	active := receiver.dispatcherEnable
+	if active {
+		receiver.Deactivate()
+	}
+	if bc, ok := previous.(*BehaviorControl); ok && bc.avatar == receiver.Owner {
+		bc.Deattach()
+	}
+	receiver.Execute(controller.Command{CType: controller.CTYPE_MOVE, Pos: controller.PosIrrelevant, Action: false})
+	receiver.Execute(controller.Command{CType: controller.CTYPE_SPEED_FACTOR, Pos: controller.Point{X: 1, Y: 1}, Action: true})
+	receiver.Control = control
+	if active && control != nil {
+		receiver.Activate()
+	}
+	return previous
+}
+
 func (receiver *ControlledObject) Free() error {
 	close(receiver.terminator)
 	return nil
@@ -66,14 +100,7 @@ func (receiver *ControlledObject) Copy() *ControlledObject {
 	instance.terminator = nil
 	instance.dispatcherEnable = false
 	if receiver.Control != nil {
-		switch receiver.Control.(type) {
-		case *controller.Control:
-			instance.Control = receiver.Control.(*controller.Control).Copy()
-		case *BehaviorControl:
-			instance.Control = receiver.Control.(*BehaviorControl).Copy()
-		default:
-			logger.Println("unknown type of Control")
-		}
+		instance.Control = copyControl(receiver.Control)
 	}
 	if receiver.dispatcherEnable {
 		logger.Println("dispatcher already enable")
@@ -82,6 +109,20 @@ func (receiver *ControlledObject) Copy() *ControlledObject {
 	return &instance
 }
 
+func copyControl(control controller.Controller) controller.Controller {
+	switch control.(type) {
+	case *controller.Control:
+		return control.(*controller.Control).Copy()
+	case *BehaviorControl:
+		return control.(*BehaviorControl).Copy()
+	case *BotControl:
+		return control.(*BotControl).Copy()
+	default:
+		logger.Println("unknown type of Control")
+	}
+	return control
+}
+
 func NewControlledObject(cmd controller.Controller, owner ControlledObjectInterface) (*ControlledObject, error) {
 	instance := new(ControlledObject)
 
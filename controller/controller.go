@@ -6,6 +6,7 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,22 +26,42 @@ var (
 )
 
 var Player1DefaultKeyBinding KeyBind = KeyBind{
-	Up: 	keyboard.KeyArrowUp,
-	Down: 	keyboard.KeyArrowDown,
-	Left: 	keyboard.KeyArrowLeft,
-	Right: 	keyboard.KeyArrowRight,
-	Fire: 	keyboard.KeySpace,
+	ACTION_UP: 			{KeyCode(keyboard.KeyArrowUp)},
+	ACTION_DOWN: 		{KeyCode(keyboard.KeyArrowDown)},
+	ACTION_LEFT: 		{KeyCode(keyboard.KeyArrowLeft)},
+	ACTION_RIGHT: 		{KeyCode(keyboard.KeyArrowRight)},
+	ACTION_FIRE: 		{KeyCode(keyboard.KeySpace)},
+	ACTION_ALT_FIRE: 	{KeyCode('/')},
+	ACTION_BOOST: 		{KeyCode('.')},
+	ACTION_STOP: 		{KeyCode(',')},
+	ACTION_PAUSE: 		{KeyCode('p')},
+	ACTION_MENU: 		{KeyCode(keyboard.KeyEsc)},
+	ACTION_AUTOPILOT: 	{KeyCode('o')},
+	ACTION_TAKEOVER: 	{KeyCode('i')},
+	ACTION_DEBUG_AI: 	{KeyCode(keyboard.KeyF9)},
+	ACTION_CONSOLE: 	{KeyCode('`')},
+	ACTION_FOLLOW: 		{KeyCode('j')},
+	ACTION_HOLD: 		{KeyCode('k')},
+	ACTION_ATTACK: 		{KeyCode('l')},
 }
 
 var Player2DefaultKeyBinding KeyBind = KeyBind{
-	Up: 	keyboard.Key('w'),
-	Down: 	keyboard.Key('s'),
-	Left: 	keyboard.Key('a'),
-	Right: 	keyboard.Key('d'),
-	Fire: 	keyboard.KeyBackspace,
+	ACTION_UP: 			{KeyCode('w')},
+	ACTION_DOWN: 		{KeyCode('s')},
+	ACTION_LEFT: 		{KeyCode('a')},
+	ACTION_RIGHT: 		{KeyCode('d')},
+	ACTION_FIRE: 		{KeyCode(keyboard.KeyBackspace)},
+	ACTION_ALT_FIRE: 	{KeyCode('q')},
+	ACTION_BOOST: 		{KeyCode('e')},
+	ACTION_STOP: 		{KeyCode('x')},
+	ACTION_AUTOPILOT: 	{KeyCode('r')},
+	ACTION_TAKEOVER: 	{KeyCode('f')},
+	ACTION_FOLLOW: 		{KeyCode('z')},
+	ACTION_HOLD: 		{KeyCode('c')},
+	ACTION_ATTACK: 		{KeyCode('v')},
 }
 
-var KeyboardBindingPool = [2]KeyBind{
+var KeyboardBindingPool = []KeyBind{
 	Player1DefaultKeyBinding, Player2DefaultKeyBinding,
 }
 
@@ -80,24 +101,42 @@ type Control struct {
 	eventChanel 	EventChanel
 	dispatcher      func(instance *Control, output chan Command, done chan bool)
 	terminator      chan bool
+	keyBind         atomic.Value
+	onDemand        bool //dispatcher run only while enabled, terminator closed by Disable
+	script          *scriptProgress //where script control stopped, own one per copy
 }
 
 func (receiver *Control) Enable() error  {
-/*	if receiver.enabled != true {
+	if receiver.onDemand && !receiver.enabled {
+		receiver.terminator = make(chan bool)
 		go receiver.dispatcher(receiver, receiver.commandChanel, receiver.terminator)
-	}*/
+	}
 	receiver.enabled = true
 	return nil
 }
 
 func (receiver *Control) Disable() error {
-/*	if receiver.enabled != false {
-		receiver.terminator <- true
-	}*/
+	if receiver.onDemand && receiver.enabled {
+		if receiver.script != nil {
+			receiver.script.stop()
+		}
+		close(receiver.terminator)
+	}
 	receiver.enabled = false
 	return nil
 }
 
+// Rewind script control, next Enable run the script from start. No-op for other controls
+func (receiver *Control) Rewind() {
+	if receiver.script != nil {
+		receiver.script.rewind()
+	}
+}
+
+func (receiver *Control) IsEnabled() bool {
+	return receiver.enabled
+}
+
 func (receiver *Control) Copy() *Control {
 	var control *Control
 	if receiver.IsPlayer {
@@ -107,11 +146,23 @@ func (receiver *Control) Copy() *Control {
 		control = &copy
 		control.terminator 		= make(chan bool)
 		control.commandChanel 	= make(chan Command)
-		go control.dispatcher(control, control.commandChanel, control.terminator)
+		if control.script != nil {
+			control.script = &scriptProgress{}
+		}
+		if control.onDemand {
+			control.enabled = false
+		} else {
+			go control.dispatcher(control, control.commandChanel, control.terminator)
+		}
 	}
 	return control
 }
 
+//replace key bindings of player control on the fly
+func (receiver *Control) SetKeyBind(keyMapping KeyBind) {
+	receiver.keyBind.Store(keyMapping)
+}
+
 func (receiver *Control) GetCommandChanel() CommandChanel  {
 	return receiver.commandChanel
 }
@@ -120,7 +171,7 @@ func (receiver *Control) SetEventChanel(chanel EventChanel) {
 	receiver.eventChanel = chanel
 }
 
-func NewPlayerControl(event <-chan keyboard.KeyEvent, keyMapping KeyBind) (*Control, error) {
+func NewPlayerControl(event <-chan keyboard.KeyEvent, keyMapping KeyBind, holdConfig HoldConfig) (*Control, error) {
 	commandChanel := make(chan Command)
 	instance := &Control{
 		enabled:       	false,
@@ -130,58 +181,66 @@ func NewPlayerControl(event <-chan keyboard.KeyEvent, keyMapping KeyBind) (*Cont
 		IsPlayer: 	   	true,
 	}
 
-	command := Command{
-		Pos:  Point{},
-		Action: false,
-	}
+	instance.SetKeyBind(keyMapping)
+
+	boost := false
+	hold  := &holdTracker{HoldConfig: holdConfig}
 
 	instance.dispatcher = func(instance *Control, output chan Command, done chan bool) {
+		send := func(command Command) {
+			logger.Printf("send: %T, %+v \n", command, command)
+			if instance.enabled {
+				output <- command
+			}
+		}
 		for {
 			select {
+			case <-hold.timer:
+				if hold.expire() {
+					send(stopCommand)
+				}
 			case keyEvent, ok := <-event:
 				if !ok {
 					close(commandChanel)
 					return
 				}
-				if keyEvent.Key == 0 {
-					keyEvent.Key = keyboard.Key(keyEvent.Rune)
-				}
-				if keyEvent.Key == keyboard.KeyBackspace2 {
-					keyEvent.Key = keyboard.KeyBackspace //normalize backspace
+				if IsMouse(keyEvent) {
+					continue
 				}
-				switch keyEvent.Key {
-				case keyMapping.Up:
-					command.CType = CTYPE_MOVE
-					command.Pos.Y = -1
-					command.Pos.X =  0
-					command.Action = true
-				case keyMapping.Down:
-					command.CType = CTYPE_MOVE
-					command.Pos.Y =  1
-					command.Pos.X =  0
-					command.Action = true
-				case keyMapping.Left:
-					command.CType = CTYPE_MOVE
-					command.Pos.X = -1
-					command.Pos.Y =  0
-					command.Action = true
-				case keyMapping.Right:
-					command.CType = CTYPE_MOVE
-					command.Pos.X =  1
-					command.Pos.Y =  0
-					command.Action = true
-
-				case keyMapping.Fire:
-					command.CType = CTYPE_FIRE
-					command.Pos 	= PosIrrelevant
-					command.Action 	= true
-				default:
+				action, _ := instance.keyBind.Load().(KeyBind).Lookup(EventKey(keyEvent))
+				if IsRelease(keyEvent) {
+					if isDirection(action) {
+						if next, changed := hold.release(action); changed && next != "" {
+							send(moveCommand(next))
+						} else if changed {
+							send(stopCommand)
+						}
+					}
 					continue
 				}
-			}
-			logger.Printf("send: %T, %+v \n", command, command)
-			if instance.enabled {
-				output <- command
+				switch action {
+				case ACTION_UP, ACTION_DOWN, ACTION_LEFT, ACTION_RIGHT:
+					if hold.press(action) {
+						send(moveCommand(action))
+					}
+				case ACTION_STOP:
+					hold.reset()
+					send(stopCommand)
+				case ACTION_FIRE:
+					send(Command{CType: CTYPE_FIRE, Pos: PosIrrelevant, Action: true})
+				case ACTION_ALT_FIRE:
+					send(Command{CType: CTYPE_ALT_FIRE, Pos: PosIrrelevant, Action: true})
+				case ACTION_BOOST:
+					if !instance.enabled {
+						continue
+					}
+					boost = !boost
+					factor := 1.0
+					if boost {
+						factor = BoostSpeedFactor
+					}
+					send(Command{CType: CTYPE_SPEED_FACTOR, Pos: Point{factor, factor}, Action: true})
+				}
 			}
 		}
 	}
@@ -213,13 +272,14 @@ func NewAIControl()(*Control, error)  {
 		IsPlayer: 	   false,
 	}
 
-	command := Command{
-		Pos:  Point{},
-		Action: false,
-	}
-
 	instance.dispatcher = func(instance *Control, output chan Command, done chan bool) {
-		timeEvents := time.After(time.Duration(rand.Intn(3000)) * time.Millisecond + 500)
+		//own state per dispatcher, copies share it. Source is own too, wandering must not shift global sequence of the game
+		command := Command{
+			Pos:  Point{},
+			Action: false,
+		}
+		random := rand.New(rand.NewSource(time.Now().UnixNano()))
+		timeEvents := time.After(IdleDelay(random.Intn))
 		for {
 			select {
 			case _, ok := <-timeEvents:
@@ -227,41 +287,12 @@ func NewAIControl()(*Control, error)  {
 					close(commandChanel)
 					return
 				}
-				switch rand.Intn(8) {
-				case 0:
-					command.CType = CTYPE_MOVE
-					command.Pos.Y = -1
-					command.Pos.X =  0
-					command.Action = true
-				case 1:
-					command.CType = CTYPE_MOVE
-					command.Pos.Y =  1
-					command.Pos.X =  0
-					command.Action = true
-				case 2:
-					command.CType = CTYPE_MOVE
-					command.Pos.X = -1
-					command.Pos.Y =  0
-					command.Action = true
-				case 3:
-					command.CType = CTYPE_MOVE
-					command.Pos.X =  1
-					command.Pos.Y =  0
-					command.Action = true
-				}
-
-				if rand.Intn(3) == 1 {
-					if !DEBUG_DISARM_AI {
-						command.CType = CTYPE_FIRE
-						command.Pos = PosIrrelevant
-						command.Action = true
-					}
-				}
+				command = IdleCommand(command, random.Intn)
 			}
 			if instance.enabled {
 				output <- command
 			}
-			timeEvents = time.After(time.Duration(rand.Intn(3000)) * time.Millisecond + 500)
+			timeEvents = time.After(IdleDelay(random.Intn))
 		}
 	}
 	go instance.dispatcher(instance, instance.commandChanel, instance.terminator)
@@ -269,10 +300,46 @@ func NewAIControl()(*Control, error)  {
 	return instance, nil
 }
 
-func (c Command) String()string  {
-	return fmt.Sprintf("direction %v, moving: %v, firing: %v", c.CType, c.Pos, c.Action)
+// IdleCommand next random wander command, previous one is repeated sometimes
+func IdleCommand(command Command, intn func(n int) int) Command {
+	switch intn(8) {
+	case 0:
+		command.CType = CTYPE_MOVE
+		command.Pos.Y = -1
+		command.Pos.X =  0
+		command.Action = true
+	case 1:
+		command.CType = CTYPE_MOVE
+		command.Pos.Y =  1
+		command.Pos.X =  0
+		command.Action = true
+	case 2:
+		command.CType = CTYPE_MOVE
+		command.Pos.X = -1
+		command.Pos.Y =  0
+		command.Action = true
+	case 3:
+		command.CType = CTYPE_MOVE
+		command.Pos.X =  1
+		command.Pos.Y =  0
+		command.Action = true
+	}
+
+	if intn(3) == 1 {
+		if !DEBUG_DISARM_AI {
+			command.CType = CTYPE_FIRE
+			command.Pos = PosIrrelevant
+			command.Action = true
+		}
+	}
+	return command
+}
+
+// IdleDelay pause before next wander command
+func IdleDelay(intn func(n int) int) time.Duration {
+	return time.Duration(intn(3000)) * time.Millisecond + 500
 }
 
-type KeyBind struct {
-	Up, Down, Left, Right, Fire keyboard.Key
+func (c Command) String()string  {
+	return fmt.Sprintf("direction %v, moving: %v, firing: %v", c.CType, c.Pos, c.Action)
 }
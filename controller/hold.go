@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/eiannone/keyboard"
+	"time"
+)
+
+// KeyReleaseError mark keyboard event as key release, only input with release support (kitty protocol) produce it
+var KeyReleaseError = errors.New("key release")
+
+// Duration of config, in json it is duration string ("150ms") or number of milliseconds
+type Duration time.Duration
+
+func (receiver Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(receiver).String())
+}
+
+func (receiver *Duration) UnmarshalJSON(payload []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*receiver = Duration(value * float64(time.Millisecond))
+	case string:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*receiver = Duration(duration)
+	default:
+		return fmt.Errorf("duration must be string or milliseconds, got %s", payload)
+	}
+	return nil
+}
+
+// HoldConfig tune release emulation: terminal send only presses with auto repeat,
+// so key counted as released when no repeat come in time
+type HoldConfig struct {
+	Enabled      bool     `json:"holdToMove"`
+	ReleaseDelay Duration `json:"releaseDelay"` //wait for first repeat, terminal auto repeat delay
+	RepeatDelay  Duration `json:"repeatDelay"`  //wait for next repeat, terminal auto repeat rate
+}
+
+var DefaultHoldConfig = HoldConfig{
+	Enabled:      true,
+	ReleaseDelay: Duration(600 * time.Millisecond),
+	RepeatDelay:  Duration(150 * time.Millisecond),
+}
+
+func IsRelease(event keyboard.KeyEvent) bool {
+	return errors.Is(event.Err, KeyReleaseError)
+}
+
+func isDirection(action string) bool {
+	return action == ACTION_UP || action == ACTION_DOWN || action == ACTION_LEFT || action == ACTION_RIGHT
+}
+
+type holdTracker struct {
+	HoldConfig
+	held         []string //holding directions, last one is active
+	repeated     bool
+	releaseKnown bool //input report real releases, no emulation needed
+	timer        <-chan time.Time
+}
+
+// press return true if move in action direction must be (re)started
+func (receiver *holdTracker) press(action string) bool {
+	if !receiver.Enabled {
+		return true
+	}
+	top := receiver.top()
+	if receiver.releaseKnown {
+		if top == action {
+			return false //repeat
+		}
+		receiver.remove(action)
+		receiver.held = append(receiver.held, action)
+		return true
+	}
+	restart := top != action
+	if restart {
+		//terminal repeat only last pressed key, previous one lost
+		receiver.held = append(receiver.held[0:0], action)
+		receiver.repeated = false
+	} else {
+		receiver.repeated = true
+	}
+	delay := receiver.ReleaseDelay
+	if receiver.repeated {
+		delay = receiver.RepeatDelay
+	}
+	receiver.timer = time.After(time.Duration(delay))
+	return restart
+}
+
+// release return direction to continue with, empty means stop. changed is false if active direction is same
+func (receiver *holdTracker) release(action string) (next string, changed bool) {
+	if !receiver.Enabled {
+		return "", false
+	}
+	receiver.releaseKnown = true
+	receiver.timer = nil
+	top := receiver.top()
+	receiver.remove(action)
+	if top != action {
+		return "", false
+	}
+	return receiver.top(), true
+}
+
+// expire is release emulation, return true if something was held
+func (receiver *holdTracker) expire() bool {
+	receiver.timer = nil
+	wasHeld := len(receiver.held) > 0
+	receiver.held = receiver.held[0:0]
+	return wasHeld
+}
+
+func (receiver *holdTracker) reset() {
+	receiver.timer = nil
+	receiver.held = receiver.held[0:0]
+}
+
+func (receiver *holdTracker) top() string {
+	if len(receiver.held) == 0 {
+		return ""
+	}
+	return receiver.held[len(receiver.held)-1]
+}
+
+func (receiver *holdTracker) remove(action string) {
+	for i := 0; i < len(receiver.held); i++ {
+		if receiver.held[i] == action {
+			receiver.held = append(receiver.held[:i], receiver.held[i+1:]...)
+			i--
+		}
+	}
+}
+
+func moveCommand(action string) Command {
+	command := Command{
+		CType:  CTYPE_MOVE,
+		Action: true,
+	}
+	switch action {
+	case ACTION_UP:
+		command.Pos = Point{0, -1}
+	case ACTION_DOWN:
+		command.Pos = Point{0, 1}
+	case ACTION_LEFT:
+		command.Pos = Point{-1, 0}
+	case ACTION_RIGHT:
+		command.Pos = Point{1, 0}
+	}
+	return command
+}
+
+var stopCommand = Command{
+	CType:  CTYPE_MOVE,
+	Pos:    PosIrrelevant,
+	Action: false,
+}
@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/eiannone/keyboard"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	ACTION_UP        = "up"
+	ACTION_DOWN      = "down"
+	ACTION_LEFT      = "left"
+	ACTION_RIGHT     = "right"
+	ACTION_FIRE      = "fire"
+	ACTION_ALT_FIRE  = "altFire"
+	ACTION_BOOST     = "boost"
+	ACTION_STOP      = "stop"
+	ACTION_PAUSE     = "pause"
+	ACTION_MENU      = "menu"
+	ACTION_AUTOPILOT = "autopilot"
+	ACTION_TAKEOVER  = "takeOver"
+	ACTION_DEBUG_AI  = "debugAi"
+	ACTION_CONSOLE   = "console"
+	ACTION_FOLLOW    = "orderFollow"
+	ACTION_HOLD      = "orderHold"
+	ACTION_ATTACK    = "orderAttack"
+)
+
+const BoostSpeedFactor = 1.5
+
+var (
+	UnknownKeyError = errors.New("unknown key")
+
+	//known actions in menu order, KeyBind may contain others
+	Actions = []string{
+		ACTION_UP, ACTION_DOWN, ACTION_LEFT, ACTION_RIGHT,
+		ACTION_FIRE, ACTION_ALT_FIRE, ACTION_BOOST, ACTION_STOP,
+		ACTION_PAUSE, ACTION_MENU, ACTION_AUTOPILOT, ACTION_TAKEOVER,
+		ACTION_DEBUG_AI, ACTION_CONSOLE, ACTION_FOLLOW, ACTION_HOLD, ACTION_ATTACK,
+	}
+
+	keyNames = map[keyboard.Key]string{
+		keyboard.KeyF1: "f1", keyboard.KeyF2: "f2", keyboard.KeyF3: "f3", keyboard.KeyF4: "f4",
+		keyboard.KeyF5: "f5", keyboard.KeyF6: "f6", keyboard.KeyF7: "f7", keyboard.KeyF8: "f8",
+		keyboard.KeyF9: "f9", keyboard.KeyF10: "f10", keyboard.KeyF11: "f11", keyboard.KeyF12: "f12",
+		keyboard.KeyInsert:     "insert",
+		keyboard.KeyDelete:     "delete",
+		keyboard.KeyHome:       "home",
+		keyboard.KeyEnd:        "end",
+		keyboard.KeyPgup:       "pgup",
+		keyboard.KeyPgdn:       "pgdn",
+		keyboard.KeyArrowUp:    "arrowUp",
+		keyboard.KeyArrowDown:  "arrowDown",
+		keyboard.KeyArrowLeft:  "arrowLeft",
+		keyboard.KeyArrowRight: "arrowRight",
+		keyboard.KeyBackspace:  "backspace",
+		keyboard.KeyTab:        "tab",
+		keyboard.KeyEnter:      "enter",
+		keyboard.KeyEsc:        "esc",
+		keyboard.KeySpace:      "space",
+	}
+	namedKeys = make(map[string]keyboard.Key, len(keyNames))
+)
+
+func init() {
+	for key, name := range keyNames {
+		namedKeys[strings.ToLower(name)] = key
+	}
+}
+
+// KeyCode is keyboard.Key with human readable json form: "space", "w", "ctrl+f", "shift+w"
+type KeyCode keyboard.Key
+
+func (k KeyCode) String() string {
+	key := keyboard.Key(k)
+	if name, ok := keyNames[key]; ok {
+		return name
+	}
+	if key >= keyboard.KeyCtrlA && key <= keyboard.KeyCtrlZ {
+		return "ctrl+" + string(rune('a'+key-keyboard.KeyCtrlA))
+	}
+	r := rune(key)
+	if unicode.IsUpper(r) {
+		return "shift+" + string(unicode.ToLower(r))
+	}
+	if unicode.IsPrint(r) {
+		return string(r)
+	}
+	return strconv.Itoa(int(key))
+}
+
+func (k KeyCode) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *KeyCode) UnmarshalText(text []byte) error {
+	key, err := ParseKey(string(text))
+	if err != nil {
+		return err
+	}
+	*k = key
+	return nil
+}
+
+// ParseKey parse key name, modifiers ctrl and shift are supported for letters
+func ParseKey(name string) (KeyCode, error) {
+	lower := strings.ToLower(name)
+	if key, ok := namedKeys[lower]; ok {
+		return KeyCode(key), nil
+	}
+	if mod := strings.Index(lower, "+"); mod > 0 && mod < len(lower)-1 {
+		r, size := utf8.DecodeRuneInString(lower[mod+1:])
+		if size == len(lower)-mod-1 && r >= 'a' && r <= 'z' {
+			switch lower[:mod] {
+			case "ctrl":
+				return KeyCode(keyboard.KeyCtrlA + keyboard.Key(r-'a')), nil
+			case "shift":
+				return KeyCode(unicode.ToUpper(r)), nil
+			}
+		}
+		return 0, fmt.Errorf("%w: %s", UnknownKeyError, name)
+	}
+	if r, size := utf8.DecodeRuneInString(name); size == len(name) && r != utf8.RuneError {
+		return KeyCode(r), nil
+	}
+	if code, err := strconv.Atoi(name); err == nil {
+		return KeyCode(code), nil
+	}
+	return 0, fmt.Errorf("%w: %s", UnknownKeyError, name)
+}
+
+// EventKey normalize keyboard event to single key code
+func EventKey(event keyboard.KeyEvent) keyboard.Key {
+	key := event.Key
+	if key == 0 {
+		key = keyboard.Key(event.Rune)
+	}
+	if key == keyboard.KeyBackspace2 {
+		key = keyboard.KeyBackspace //normalize backspace
+	}
+	return key
+}
+
+// KeyBind map action to keys, multiple keys per action allowed
+type KeyBind map[string][]KeyCode
+
+// Lookup return action bound to key
+func (kb KeyBind) Lookup(key keyboard.Key) (action string, ok bool) {
+	for action, keys := range kb {
+		for _, candidate := range keys {
+			if keyboard.Key(candidate) == key {
+				return action, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Bind add key to action, key removed from other actions
+func (kb KeyBind) Bind(action string, key KeyCode) {
+	kb.Unbind(key)
+	kb[action] = append(kb[action], key)
+}
+
+func (kb KeyBind) Unbind(key KeyCode) {
+	for action, keys := range kb {
+		for i := 0; i < len(keys); i++ {
+			if keys[i] == key {
+				keys = append(keys[:i], keys[i+1:]...)
+				i--
+			}
+		}
+		kb[action] = keys
+	}
+}
+
+func (kb KeyBind) Copy() KeyBind {
+	instance := make(KeyBind, len(kb))
+	for action, keys := range kb {
+		instance[action] = append([]KeyCode(nil), keys...)
+	}
+	return instance
+}
+
+// Merge add actions missing in kb from defaults, if their keys are free
+func (kb KeyBind) Merge(defaults KeyBind) {
+	for action, keys := range defaults {
+		if _, ok := kb[action]; ok {
+			continue
+		}
+		for _, key := range keys {
+			if _, used := kb.Lookup(keyboard.Key(key)); !used {
+				kb[action] = append(kb[action], key)
+			}
+		}
+	}
+}
+
+func (kb KeyBind) Describe(action string) string {
+	names := make([]string, 0, len(kb[action]))
+	for _, key := range kb[action] {
+		names = append(names, key.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// UnmarshalJSON accept both {"fire": ["space", "f"]} and old {"Fire": 32} form
+func (kb *KeyBind) UnmarshalJSON(payload []byte) error {
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return err
+	}
+	if *kb == nil {
+		*kb = make(KeyBind, len(raw))
+	}
+	for name, value := range raw {
+		action := normalizeAction(name)
+		var keys []KeyCode
+		if err := json.Unmarshal(value, &keys); err != nil {
+			var key KeyCode
+			var code int
+			if err := json.Unmarshal(value, &code); err == nil {
+				key = KeyCode(code)
+			} else if err := json.Unmarshal(value, &key); err != nil {
+				return fmt.Errorf("action %s: %w", name, err)
+			}
+			keys = []KeyCode{key}
+		}
+		(*kb)[action] = keys
+	}
+	return nil
+}
+
+func normalizeAction(name string) string {
+	for _, action := range Actions {
+		if strings.EqualFold(action, name) {
+			return action
+		}
+	}
+	return name
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"errors"
+	"github.com/eiannone/keyboard"
+)
+
+const (
+	MOUSE_LEFT = iota
+	MOUSE_MIDDLE
+	MOUSE_RIGHT
+	MOUSE_WHEEL_UP
+	MOUSE_WHEEL_DOWN
+)
+
+const (
+	MOUSE_PRESS = iota
+	MOUSE_RELEASE
+)
+
+// MouseEvent travel inside keyboard event Err (same as KeyReleaseError), so mouse share keyboard subscribers and focus.
+// X, Y are zero based terminal cells
+type MouseEvent struct {
+	X, Y   int
+	Button int
+	Action int
+}
+
+func (m *MouseEvent) Error() string {
+	return "mouse event"
+}
+
+func NewMouseKeyEvent(mouse MouseEvent) keyboard.KeyEvent {
+	return keyboard.KeyEvent{Err: &mouse}
+}
+
+func AsMouse(event keyboard.KeyEvent) (*MouseEvent, bool) {
+	var mouse *MouseEvent
+	ok := errors.As(event.Err, &mouse)
+	return mouse, ok
+}
+
+func IsMouse(event keyboard.KeyEvent) bool {
+	_, ok := AsMouse(event)
+	return ok
+}
+
+// IsClick is left button press
+func (m *MouseEvent) IsClick() bool {
+	return m.Button == MOUSE_LEFT && m.Action == MOUSE_PRESS
+}
@@ -0,0 +1,26 @@
+package controller
+
+// NewRemoteControl forward commands of player on other machine, input closed when connection lost
+func NewRemoteControl(input <-chan Command) (*Control, error) {
+	instance := &Control{
+		enabled:       false,
+		commandChanel: make(chan Command),
+		terminator:    make(chan bool),
+		eventChanel:   nil,
+		IsPlayer:      true,
+	}
+
+	instance.dispatcher = func(instance *Control, output chan Command, done chan bool) {
+		for command := range input {
+			if instance.enabled {
+				output <- command
+			}
+		}
+		if instance.enabled {
+			output <- stopCommand //disconnected, don't let tank run away
+		}
+	}
+	go instance.dispatcher(instance, instance.commandChanel, instance.terminator)
+
+	return instance, nil
+}
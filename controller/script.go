@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	SCRIPT_WAIT_NONE  = iota
+	SCRIPT_WAIT_AT    //since script start
+	SCRIPT_WAIT_FOR   //since previous step
+	SCRIPT_WAIT_CYCLE //until game cycle
+)
+
+const scriptPollInterval = 10 * time.Millisecond //cycle wait only
+
+var (
+	ScriptSyntaxError = errors.New("script syntax error")
+
+	//game cycle counter, set by game. Without it cycle waits never end
+	CycleSource func() int64
+	//game clock, set by game. Script time is game time then and stands still on pause, wall clock without it
+	Clock ScriptClock
+
+	scriptDirections = map[string]Point{
+		"up":    {0, -1},
+		"down":  {0, 1},
+		"left":  {-1, 0},
+		"right": {1, 0},
+	}
+)
+
+type ScriptClock interface {
+	Now() time.Time
+	AfterFunc(duration time.Duration, fn func())
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+func (wallClock) AfterFunc(duration time.Duration, fn func()) {
+	time.AfterFunc(duration, fn)
+}
+
+// scriptProgress survive Disable, script go on from there on Enable. Time script was disabled is not counted
+type scriptProgress struct {
+	next        int
+	waited      bool //wait of next step is over, its command is not sent yet
+	start, prev time.Time
+	stoppedAt   atomic.Value //time.Time of Disable
+	mutex       sync.Mutex   //held by dispatcher, next one wait for stopped one to save progress
+}
+
+func (receiver *scriptProgress) stop() {
+	receiver.stoppedAt.Store(scriptClock().Now())
+}
+
+// rewind to first step, waits for stopped dispatcher to save progress first
+func (receiver *scriptProgress) rewind() {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.next, receiver.waited, receiver.start = 0, false, time.Time{}
+}
+
+func scriptClock() ScriptClock {
+	if Clock == nil {
+		return wallClock{}
+	}
+	return Clock
+}
+
+type ScriptStep struct {
+	Wait     int
+	Duration time.Duration
+	Cycle    int64
+	Command  *Command
+	Loop     bool
+	Line     int
+}
+
+// ParseScript parse macro script. Statements are separated by new line or ";", "#" start comment.
+// Each statement is optional condition followed by optional action:
+//
+//	at 0.5s move right; at 2s fire; wait 300ms; wait until cycle 80; turn up
+//
+// conditions: "at <duration>", "wait <duration>", "wait until cycle <n>"
+// actions: "move <dir>", "turn <dir>", "stop", "fire", "altfire", "speed <factor>", "loop"
+func ParseScript(script string) ([]ScriptStep, error) {
+	steps := make([]ScriptStep, 0, 10)
+	for lineNum, line := range strings.Split(script, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		for _, statement := range strings.Split(line, ";") {
+			tokens := strings.Fields(strings.ToLower(statement))
+			if len(tokens) == 0 {
+				continue
+			}
+			step, err := parseStatement(tokens)
+			if err != nil {
+				return nil, fmt.Errorf("line %d, %q: %w", lineNum+1, strings.TrimSpace(statement), err)
+			}
+			step.Line = lineNum + 1
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+func parseStatement(tokens []string) (step ScriptStep, err error) {
+	switch {
+	case tokens[0] == "at" && len(tokens) >= 2:
+		step.Wait = SCRIPT_WAIT_AT
+		step.Duration, err = time.ParseDuration(tokens[1])
+		tokens = tokens[2:]
+	case tokens[0] == "wait" && len(tokens) >= 4 && tokens[1] == "until" && tokens[2] == "cycle":
+		step.Wait = SCRIPT_WAIT_CYCLE
+		step.Cycle, err = strconv.ParseInt(tokens[3], 10, 64)
+		tokens = tokens[4:]
+	case tokens[0] == "wait" && len(tokens) >= 2:
+		step.Wait = SCRIPT_WAIT_FOR
+		step.Duration, err = time.ParseDuration(tokens[1])
+		tokens = tokens[2:]
+	}
+	if err != nil {
+		return step, fmt.Errorf("%w: %s", ScriptSyntaxError, err)
+	}
+	if len(tokens) == 0 {
+		if step.Wait == SCRIPT_WAIT_NONE {
+			return step, fmt.Errorf("%w: condition without value", ScriptSyntaxError)
+		}
+		return step, nil
+	}
+
+	command := &Command{Pos: PosIrrelevant, Action: true}
+	switch {
+	case tokens[0] == "move" && len(tokens) == 2:
+		command.CType = CTYPE_MOVE
+		command.Pos, err = scriptDirection(tokens[1])
+	case tokens[0] == "turn" && len(tokens) == 2:
+		command.CType = CTYPE_DIRECTION
+		command.Pos, err = scriptDirection(tokens[1])
+	case tokens[0] == "stop" && len(tokens) == 1:
+		*command = stopCommand
+	case tokens[0] == "fire" && len(tokens) == 1:
+		command.CType = CTYPE_FIRE
+	case tokens[0] == "altfire" && len(tokens) == 1:
+		command.CType = CTYPE_ALT_FIRE
+	case tokens[0] == "speed" && len(tokens) == 2:
+		var factor float64
+		factor, err = strconv.ParseFloat(tokens[1], 64)
+		command.CType = CTYPE_SPEED_FACTOR
+		command.Pos = Point{factor, factor}
+	case tokens[0] == "loop" && len(tokens) == 1:
+		step.Loop = true
+		command = nil
+	default:
+		return step, fmt.Errorf("%w: unknown action %s", ScriptSyntaxError, tokens[0])
+	}
+	if err != nil {
+		return step, fmt.Errorf("%w: %s", ScriptSyntaxError, err)
+	}
+	step.Command = command
+	return step, nil
+}
+
+func scriptDirection(name string) (Point, error) {
+	if dir, ok := scriptDirections[name]; ok {
+		return dir, nil
+	}
+	return PosIrrelevant, fmt.Errorf("unknown direction %s", name)
+}
+
+// NewScriptControl create control that replay script steps, time count from first Enable. Script run only while
+// control is enabled, Disable pause it and next Enable resume it. Rewind start it over
+func NewScriptControl(steps []ScriptStep, isPlayer bool) (*Control, error) {
+	instance := &Control{
+		enabled:       false,
+		commandChanel: make(chan Command),
+		terminator:    make(chan bool),
+		eventChanel:   nil,
+		IsPlayer:      isPlayer,
+		onDemand:      true,
+		script:        &scriptProgress{},
+	}
+
+	instance.dispatcher = func(instance *Control, output chan Command, done chan bool) {
+		clock := scriptClock()
+		sleep := func(duration time.Duration) bool {
+			timer := time.NewTimer(duration)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				return true
+			case <-done:
+				return false
+			}
+		}
+		waitUntil := func(at time.Time) bool {
+			fired := make(chan bool)
+			clock.AfterFunc(at.Sub(clock.Now()), func() {
+				close(fired)
+			})
+			select {
+			case <-fired:
+				return true
+			case <-done:
+				return false
+			}
+		}
+		progress := instance.script
+		progress.mutex.Lock()
+		defer progress.mutex.Unlock()
+		if now := clock.Now(); progress.start.IsZero() {
+			progress.start, progress.prev = now, now
+		} else {
+			stopped := now.Sub(progress.stoppedAt.Load().(time.Time))
+			progress.start, progress.prev = progress.start.Add(stopped), progress.prev.Add(stopped)
+		}
+		for ; progress.next < len(steps); progress.next, progress.waited = progress.next+1, false {
+			step := steps[progress.next]
+			if !progress.waited {
+				running := true
+				switch step.Wait {
+				case SCRIPT_WAIT_AT:
+					running = waitUntil(progress.start.Add(step.Duration))
+				case SCRIPT_WAIT_FOR:
+					running = waitUntil(progress.prev.Add(step.Duration))
+				case SCRIPT_WAIT_CYCLE:
+					for running && CycleSource != nil && CycleSource() < step.Cycle {
+						running = sleep(scriptPollInterval)
+					}
+				}
+				if !running {
+					logger.Printf("script stopped at line %d \n", step.Line)
+					return
+				}
+				progress.prev, progress.waited = clock.Now(), true
+			}
+			if step.Loop {
+				progress.next, progress.start = -1, progress.prev
+				continue
+			}
+			if step.Command != nil {
+				logger.Printf("script line %d: %+v \n", step.Line, *step.Command)
+				select {
+				case output <- *step.Command:
+				case <-done:
+					return
+				}
+			}
+		}
+		logger.Printf("script done in %s \n", progress.prev.Sub(progress.start))
+	}
+
+	return instance, nil
+}
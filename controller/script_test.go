@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseScript(t *testing.T) {
+	steps, err := ParseScript("at 0.5s move right; at 2s fire # comment\n\nwait 300ms\nwait until cycle 80; turn UP\nspeed 1.5;stop\naltfire;loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ScriptStep{
+		{Wait: SCRIPT_WAIT_AT, Duration: 500 * time.Millisecond, Command: &Command{CType: CTYPE_MOVE, Pos: Point{1, 0}, Action: true}, Line: 1},
+		{Wait: SCRIPT_WAIT_AT, Duration: 2 * time.Second, Command: &Command{CType: CTYPE_FIRE, Pos: PosIrrelevant, Action: true}, Line: 1},
+		{Wait: SCRIPT_WAIT_FOR, Duration: 300 * time.Millisecond, Line: 3},
+		{Wait: SCRIPT_WAIT_CYCLE, Cycle: 80, Line: 4},
+		{Command: &Command{CType: CTYPE_DIRECTION, Pos: Point{0, -1}, Action: true}, Line: 4},
+		{Command: &Command{CType: CTYPE_SPEED_FACTOR, Pos: Point{1.5, 1.5}, Action: true}, Line: 5},
+		{Command: &stopCommand, Line: 5},
+		{Command: &Command{CType: CTYPE_ALT_FIRE, Pos: PosIrrelevant, Action: true}, Line: 6},
+		{Loop: true, Line: 6},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("%d steps, expected %d", len(steps), len(expected))
+	}
+	for i, step := range steps {
+		want := expected[i]
+		if step.Wait != want.Wait || step.Duration != want.Duration || step.Cycle != want.Cycle ||
+			step.Loop != want.Loop || step.Line != want.Line || (step.Command == nil) != (want.Command == nil) ||
+			(step.Command != nil && *step.Command != *want.Command) {
+			t.Errorf("step %d: got %+v %+v, expected %+v %+v", i, step, step.Command, want, want.Command)
+		}
+	}
+}
+
+func TestParseScriptMalformed(t *testing.T) {
+	for _, script := range []string{
+		"fly",
+		"move",
+		"move north",
+		"turn up down",
+		"fire now",
+		"at",
+		"at soon fire",
+		"wait 1x",
+		"wait until cycle",
+		"wait until cycle ten fire",
+		"speed fast",
+		"loop again",
+		"at 1s fire; fire\nmove sideways",
+	} {
+		if steps, err := ParseScript(script); !errors.Is(err, ScriptSyntaxError) || steps != nil {
+			t.Errorf("%q: got %v, %v", script, steps, err)
+		}
+	}
+	if steps, err := ParseScript("# nothing\n ; \n"); err != nil || len(steps) != 0 {
+		t.Errorf("empty script: got %v, %v", steps, err)
+	}
+}
+
+// settle wait until goroutine count drop to expected, dispatcher exit asynchronously
+func settle(expected int) int {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > expected && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return runtime.NumGoroutine()
+}
+
+func TestScriptControlLifetime(t *testing.T) {
+	steps, _ := ParseScript("fire; wait 50ms; altfire; wait 1h; fire")
+	base := runtime.NumGoroutine()
+	template, _ := NewScriptControl(steps, false)
+	copies := []*Control{template.Copy(), template.Copy()}
+	if count := settle(base); count != base {
+		t.Fatalf("%d dispatchers started without Enable", count-base)
+	}
+
+	control := copies[0]
+	control.Enable()
+	select {
+	case command := <-control.GetCommandChanel():
+		if command.CType != CTYPE_FIRE {
+			t.Errorf("unexpected command %+v", command)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("script not started by Enable")
+	}
+	control.Disable()
+	if count := settle(base); count != base {
+		t.Errorf("%d dispatchers left after Disable", count-base)
+	}
+
+	control.Enable()
+	select {
+	case command := <-control.GetCommandChanel():
+		if command.CType != CTYPE_ALT_FIRE {
+			t.Errorf("script not resumed, got %+v", command)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("script not resumed by second Enable")
+	}
+	control.Disable()
+	if count := settle(base); count != base {
+		t.Errorf("%d dispatchers left after second Disable", count-base)
+	}
+
+	copies[1].Enable()
+	select {
+	case command := <-copies[1].GetCommandChanel():
+		if command.CType != CTYPE_FIRE {
+			t.Errorf("copy share progress, got %+v", command)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("copy not started")
+	}
+	copies[1].Disable()
+}
+
+type testClock struct {
+	now    time.Time
+	timers []testTimer
+	mutex  sync.Mutex
+}
+
+type testTimer struct {
+	at time.Time
+	fn func()
+}
+
+func (receiver *testClock) Now() time.Time {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	return receiver.now
+}
+
+func (receiver *testClock) AfterFunc(duration time.Duration, fn func()) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.timers = append(receiver.timers, testTimer{at: receiver.now.Add(duration), fn: fn})
+}
+
+// advance game time once dispatcher waits for it
+func (receiver *testClock) advance(duration time.Duration) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		receiver.mutex.Lock()
+		waiting := len(receiver.timers) > 0
+		receiver.mutex.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	receiver.mutex.Lock()
+	receiver.now = receiver.now.Add(duration)
+	var due []func()
+	timers := receiver.timers[:0]
+	for _, timer := range receiver.timers {
+		if timer.at.After(receiver.now) {
+			timers = append(timers, timer)
+		} else {
+			due = append(due, timer.fn)
+		}
+	}
+	receiver.timers = timers
+	receiver.mutex.Unlock()
+	for _, fn := range due {
+		fn()
+	}
+}
+
+func TestScriptControlClock(t *testing.T) {
+	clock := &testClock{now: time.Unix(100, 0)}
+	Clock = clock
+	defer func() { Clock = nil }()
+	steps, _ := ParseScript("at 1s fire; wait 1s; altfire")
+	control, _ := NewScriptControl(steps, false)
+	expect := func(ctype int, what string) {
+		select {
+		case command := <-control.GetCommandChanel():
+			if command.CType != ctype {
+				t.Errorf("%s: unexpected command %+v", what, command)
+			}
+		case <-time.After(time.Second):
+			t.Fatal(what + ": no command")
+		}
+	}
+	expectNone := func(what string) {
+		select {
+		case command := <-control.GetCommandChanel():
+			t.Errorf("%s: unexpected command %+v", what, command)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	control.Enable()
+	clock.advance(999 * time.Millisecond)
+	expectNone("before game time")
+	clock.advance(time.Millisecond)
+	expect(CTYPE_FIRE, "at game time")
+
+	control.Disable() //paused while waiting
+	clock.advance(time.Hour)
+	control.Enable()
+	clock.advance(999 * time.Millisecond)
+	expectNone("time paused counted")
+	clock.advance(time.Millisecond)
+	expect(CTYPE_ALT_FIRE, "after pause")
+	control.Disable()
+}
@@ -13,3 +13,10 @@ type Vulnerable interface {
 type Endurance struct {
 	HP, FullHP int
 }
+
+// recordHit report applied damage to game stats if they are collected, damage is capped by hp target had
+func recordHit(target, nemesis ObjectInterface, damage int, destroyed bool) {
+	if game != nil && game.Stats != nil {
+		game.Stats.Hit(game, target, nemesis, damage, destroyed)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"GoConsoleBT/output"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var UnknownDebugFlagError = errors.New("unknown debug flag")
+
+// DebugFlags runtime switches by name, used by --debug.flags and console `set`.
+// disable_ui, disable_vision and minimap are read on start only
+var DebugFlags = map[string]*bool{
+	"debug":              &DEBUG,
+	"spawn":              &DEBUG_SPAWN,
+	"event":              &DEBUG_EVENT,
+	"exec":               &DEBUG_EXEC,
+	"state":              &DEBUG_STATE,
+	"no_ai":              &DEBUG_NO_AI,
+	"shake":              &DEBUG_SHAKE,
+	"immortal_player":    &DEBUG_IMMORTAL_PLAYER,
+	"freeze_ai":          &DEBUG_FREEZ_AI,
+	"ai_path":            &DEBUG_AI_PATH,
+	"ai_behavior":        &DEBUG_AI_BEHAVIOR,
+	"fire_solution":      &DEBUG_FIRE_SOLUTION,
+	"minimap":            &DEBUG_MINIMAP,
+	"disable_vision":     &DEBUG_DISABLE_VISION,
+	"shutdown":           &DEBUG_SHUTDOWN,
+	"opportunity_fire":   &DEBUG_OPPORTUNITY_FIRE,
+	"disable_ui":         &DEBUG_DISABLE_UI,
+	"disarm_ai":          &controller.DEBUG_DISARM_AI,
+	"show_id":            &DEBUG_SHOW_ID,
+	"show_ai_behavior":   &DEBUG_SHOW_AI_BEHAVIOR,
+	"free_spaces":        &DEBUG_FREE_SPACES,
+	"spawn_point_status": &DEBUG_SPAWN_POINT_STATUS,
+	"output":             &output.DEBUG,
+}
+
+func SetDebugFlag(name string, value bool) error {
+	flag, ok := DebugFlags[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("%s: %w", name, UnknownDebugFlagError)
+	}
+	*flag = value
+	return nil
+}
+
+// SetDebugFlags enable comma separated flags, eg. "immortal_player,show_id"
+func SetDebugFlags(names string) error {
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if err := SetDebugFlag(name, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func DebugFlagNames() []string {
+	names := make([]string, 0, len(DebugFlags))
+	for name := range DebugFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
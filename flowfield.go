@@ -0,0 +1,184 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	FLOW_FIELD_REFRESH = 200 * time.Millisecond //fields follow map changes at most that often
+	FLOW_FIELD_TTL     = 5 * time.Second        //field nobody asked for that long is dropped
+	FLOW_UNREACHABLE   = math.MaxInt32
+)
+
+// FlowField cost to goal and next step from every zone, shared by all units heading to the same goal. Built
+// once by Navigation, after that only updated on map change: cheaper zones (destroyed wall) are spread from,
+// costlier ones rebuild it
+type FlowField struct {
+	Goal   Zone
+	w      int
+	dist   []int32
+	next   []int32 //zone index one step closer to goal, -1 on goal or unreachable
+	ready  bool
+	usedAt time.Time
+	open   pathHeap
+	mutex  sync.RWMutex
+}
+
+// Next zone to go from zone, O(1). False if field is not ready yet or goal is unreachable from zone
+func (receiver *FlowField) Next(zone Zone) (Zone, bool) {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	index, ok := receiver.index(zone)
+	if !ok || receiver.next[index] == -1 {
+		return NoZone, false
+	}
+	next := receiver.next[index]
+	return Zone{X: int(next) % receiver.w, Y: int(next) / receiver.w}, true
+}
+
+// Cost to goal from zone, FLOW_UNREACHABLE if there is no way
+func (receiver *FlowField) Cost(zone Zone) int32 {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	index, ok := receiver.index(zone)
+	if !ok {
+		return FLOW_UNREACHABLE
+	}
+	return receiver.dist[index]
+}
+
+// Path from zone to goal, both included as FindPath does, empty if goal is unreachable. False while field is
+// not ready
+func (receiver *FlowField) Path(from Zone) ([]Zone, bool) {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	if !receiver.ready {
+		return nil, false
+	}
+	index, ok := receiver.index(from)
+	if !ok || receiver.dist[index] == FLOW_UNREACHABLE {
+		return []Zone{}, true
+	}
+	path := make([]Zone, 0, 16)
+	for steps := len(receiver.next); index != -1 && steps >= 0; steps-- {
+		path = append(path, Zone{X: int(index) % receiver.w, Y: int(index) / receiver.w})
+		index = receiver.next[index]
+	}
+	return path, true
+}
+
+func (receiver *FlowField) Ready() bool {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	return receiver.ready
+}
+
+func (receiver *FlowField) index(zone Zone) (int32, bool) {
+	if !receiver.ready || zone.X < 0 || zone.Y < 0 || zone.X >= receiver.w || zone.Y*receiver.w+zone.X >= len(receiver.dist) {
+		return 0, false
+	}
+	return int32(zone.Y*receiver.w + zone.X), true
+}
+
+// compute whole field from scratch by grid
+func (receiver *FlowField) compute(grid *PathGrid, diagonal bool) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	size := len(grid.Cost)
+	if cap(receiver.dist) < size {
+		receiver.dist, receiver.next = make([]int32, size), make([]int32, size)
+	}
+	receiver.dist, receiver.next = receiver.dist[:size], receiver.next[:size]
+	for i := range receiver.dist {
+		receiver.dist[i], receiver.next[i] = FLOW_UNREACHABLE, -1
+	}
+	receiver.w, receiver.ready = grid.W, true
+	if !grid.inside(receiver.Goal) {
+		return
+	}
+	goal := int32(receiver.Goal.Y*grid.W + receiver.Goal.X)
+	receiver.dist[goal] = 0
+	receiver.open = receiver.open[:0]
+	receiver.open.push(goal, 0)
+	receiver.spread(grid, diagonal)
+	if DEBUG_AI_PATH {
+		logger.Printf("cycleID %d flow field to %d, %d computed \n", CycleID, receiver.Goal.X, receiver.Goal.Y)
+	}
+}
+
+// lower update field after zones got cheaper, spread from them only
+func (receiver *FlowField) lower(grid *PathGrid, zones []int32, diagonal bool) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.open = receiver.open[:0]
+	for _, index := range zones {
+		receiver.reopen(index)
+		if diagonal { //unblocked corner opens diagonal steps between its neighbours
+			zone := Zone{X: int(index) % grid.W, Y: int(index) / grid.W}
+			for _, step := range pathSteps4 {
+				if neighbour := (Zone{X: zone.X + step.X, Y: zone.Y + step.Y}); grid.inside(neighbour) {
+					receiver.reopen(int32(neighbour.Y*grid.W + neighbour.X))
+				}
+			}
+		}
+	}
+	receiver.spread(grid, diagonal)
+}
+
+func (receiver *FlowField) reopen(index int32) {
+	if receiver.dist[index] != FLOW_UNREACHABLE {
+		receiver.open.push(index, receiver.dist[index])
+	}
+}
+
+// spread Dijkstra from open zones toward start ones: step from neighbour into zone costs what entering zone does
+func (receiver *FlowField) spread(grid *PathGrid, diagonal bool) {
+	steps := pathSteps4
+	if diagonal {
+		steps = pathSteps8
+	}
+	goal := int32(receiver.Goal.Y*grid.W + receiver.Goal.X)
+	for len(receiver.open) > 0 {
+		current, dist := receiver.open.pop()
+		if dist > receiver.dist[current] {
+			continue //outdated entry
+		}
+		enter := grid.Cost[current]
+		if enter == PATH_COST_BLOCKED {
+			if current != goal {
+				continue //unit standing there may leave it, nobody passes through
+			}
+			enter = PATH_COST_FREE
+		}
+		zone := Zone{X: int(current) % grid.W, Y: int(current) / grid.W}
+		for _, step := range steps {
+			from := Zone{X: zone.X + step.X, Y: zone.Y + step.Y}
+			if !grid.inside(from) {
+				continue
+			}
+			cost := enter
+			if step.X != 0 && step.Y != 0 {
+				//no corner cutting
+				if grid.At(Zone{X: from.X, Y: zone.Y}) == PATH_COST_BLOCKED || grid.At(Zone{X: zone.X, Y: from.Y}) == PATH_COST_BLOCKED {
+					continue
+				}
+				cost = cost * PATH_COST_DIAG / PATH_COST_FREE
+			}
+			index := int32(from.Y*grid.W + from.X)
+			if dist+cost < receiver.dist[index] {
+				receiver.dist[index], receiver.next[index] = dist+cost, current
+				receiver.open.push(index, dist+cost)
+			}
+		}
+	}
+}
+
+// NewFlowField empty field to goal, Navigation computes it on next cycle
+func NewFlowField(goal Zone) (*FlowField, error) {
+	return &FlowField{
+		Goal:   goal,
+		usedAt: Clock.Now(),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestFlowFieldMatchesFindPath(t *testing.T) {
+	grid, _, goal := costGrid(t, "....#....\n.ww.#.~~.\n.#..w..#.\n.#.###.#e\n.........")
+	field, _ := NewFlowField(goal)
+	if _, ok := field.Path(Zone{}); ok {
+		t.Error("path from field not computed yet")
+	}
+	field.compute(grid, false)
+	for y := 0; y < grid.H; y++ {
+		for x := 0; x < grid.W; x++ {
+			from := Zone{X: x, Y: y}
+			if grid.At(from) == PATH_COST_BLOCKED {
+				continue
+			}
+			path, _ := field.Path(from)
+			if path[0] != from || path[len(path)-1] != goal {
+				t.Fatal("path from", from, path)
+			}
+			if expected := pathCost(grid, grid.FindPath(from, goal, false)); field.Cost(from) != expected || pathCost(grid, path) != expected {
+				t.Error("cost from", from, field.Cost(from), pathCost(grid, path), "expected", expected)
+			}
+		}
+	}
+}
+
+func TestFlowFieldLower(t *testing.T) {
+	for _, diagonal := range []bool{false, true} {
+		grid, from, goal := costGrid(t, "s.........\n#########.\n..........\n.#########\n....e.....")
+		field, _ := NewFlowField(goal)
+		field.compute(grid, diagonal)
+		old := field.Cost(from)
+		//breach in both walls
+		lowered := []int32{}
+		for _, zone := range []Zone{{X: 4, Y: 1}, {X: 4, Y: 3}} {
+			index := int32(zone.Y*grid.W + zone.X)
+			grid.Cost[index] = PATH_COST_FREE + PATH_COST_SHOT
+			lowered = append(lowered, index)
+		}
+		field.lower(grid, lowered, diagonal)
+		expected, _ := NewFlowField(goal)
+		expected.compute(grid, diagonal)
+		for i := range field.dist {
+			if field.dist[i] != expected.dist[i] {
+				t.Fatal("diagonal", diagonal, "zone", i, "cost", field.dist[i], "expected", expected.dist[i])
+			}
+		}
+		if field.Cost(from) >= old {
+			t.Error("breach not used", old, field.Cost(from))
+		}
+	}
+}
+
+func BenchmarkFlowFieldCompute(b *testing.B) {
+	grid := mazeGrid(b)
+	field, _ := NewFlowField(Zone{X: 99, Y: 59})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		field.compute(grid, false)
+	}
+}
+
+func BenchmarkFlowFieldNext(b *testing.B) {
+	grid := mazeGrid(b)
+	field, _ := NewFlowField(Zone{X: 99, Y: 59})
+	field.compute(grid, false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		field.Next(Zone{X: i % 100, Y: i % 60})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"GoConsoleBT/collider"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -18,19 +19,82 @@ type GPipeline struct {
 	*Location
 	*Navigation
 	*UI
+	Lockstep bool //stages one by one in caller goroutine, repeatable gym runs
 	stage    int64
+	paused   int32
 	pipe     chan int64
 	ret      chan bool
 	timeLeft time.Duration
+	deferred []func()
+	mutex    sync.Mutex
 }
 
 func (receiver *GPipeline) Execute(timeLeft time.Duration) {
+	receiver.runDeferred()
+	if receiver.IsPaused() {
+		receiver.Render.Execute(timeLeft) //keep screens alive, world frozen
+		return
+	}
 	receiver.timeLeft = timeLeft
+	Clock.Advance(timeLeft)
+	Clock.Fire()
+	if receiver.Lockstep {
+		receiver.executeInOrder()
+		return
+	}
 	receiver.pipe <- 1
 	<-receiver.ret
 	receiver.stage = 0
 }
 
+// executeInOrder stages of plDispatcher, ones of stage in order they are started
+func (receiver *GPipeline) executeInOrder() {
+	timeLeft := receiver.timeLeft
+	receiver.SpawnManager.Execute(timeLeft)
+	receiver.Updater.Execute(timeLeft)
+	receiver.Navigation.Execute(timeLeft)
+	receiver.AnimationManager.Execute(timeLeft)
+	receiver.Collider.Execute(timeLeft)
+	receiver.EffectManager.Execute(timeLeft)
+	receiver.collect()
+	if receiver.UI != nil {
+		receiver.UI.Execute(timeLeft)
+	}
+	receiver.Render.Execute(timeLeft)
+	receiver.Visioner.Execute(timeLeft)
+	receiver.Location.Execute(timeLeft)
+}
+
+// Defer run fn in game loop before next cycle (paused one too), no stage is working at that time,
+// so fn may change game objects, eg. console commands
+func (receiver *GPipeline) Defer(fn func()) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.deferred = append(receiver.deferred, fn)
+}
+
+func (receiver *GPipeline) runDeferred() {
+	receiver.mutex.Lock()
+	deferred := receiver.deferred
+	receiver.deferred = nil
+	receiver.mutex.Unlock()
+	for _, fn := range deferred {
+		fn()
+	}
+}
+
+func (receiver *GPipeline) Pause(pause bool) {
+	if pause {
+		atomic.StoreInt32(&receiver.paused, 1)
+	} else {
+		atomic.StoreInt32(&receiver.paused, 0)
+	}
+}
+
+func (receiver *GPipeline) IsPaused() bool {
+	return atomic.LoadInt32(&receiver.paused) == 1
+}
+
 func (receiver *GPipeline) doUpdate() {
 	receiver.Updater.Execute(receiver.timeLeft)
 	receiver.pipe <- 1
@@ -42,6 +106,11 @@ func (receiver *GPipeline) doAnimate() {
 }
 
 func (receiver *GPipeline) doCollect() {
+	receiver.collect()
+	receiver.pipe <- 1
+}
+
+func (receiver *GPipeline) collect() {
 	receiver.SpawnManager.Collect()
 	if receiver.Updater.NeedCompact() {
 		receiver.Updater.Compact()
@@ -52,7 +121,6 @@ func (receiver *GPipeline) doCollect() {
 	if receiver.AnimationManager.NeedCompact() {
 		receiver.AnimationManager.Compact()
 	}
-	receiver.pipe <- 1
 }
 
 func (receiver *GPipeline) doCollide() {
@@ -15,9 +15,14 @@ import (
 const GAME_START = 200
 const GAME_END_WIN = 201
 const GAME_END_LOSE = 202
+const GAME_SYNC = 203 //dispatcher barrier, payload is chanel closed on receive
+
+const PLAYER_TEAM = -1 //allied ai may join it
 
 var (
 	GameInProgressError         = errors.New("game in progress")
+	GameNotInProgressError      = errors.New("game not in progress")
+	PlayerNotFoundError         = errors.New("player not found")
 	NoSpawnPointError           = errors.New("no spawn point")
 	NotAvailableSpawnPointError = errors.New("not available spawn point")
 	NoAvailableLocationManager  = errors.New("location spawn required but no location manager")
@@ -30,6 +35,11 @@ type Game struct {
 	*Location
 	*EffectManager
 	*SoundManager
+	AiBuilder                  *BehaviorControlBuilder //autopilot, nil means simple ai
+	Navigation                 *Navigation             //path for player waypoints
+	Bots                       map[int8]string         //team -> bot command line, see BotControl
+	Stats                      *GameStats              //hits per player, nil if not collected
+	Loop                       func(fn func())         //run event handlers in game loop in order, gym pipeline Defer
 	spawnPoints                []*SpawnPoint
 	scenario                   *Scenario
 	spawnedPlayer, spawnedAi   int64
@@ -85,33 +95,11 @@ func (receiver *Game) Run(scenario *Scenario) error {
 	}
 
 	for pIndex, player := range receiver.players {
-
-		if (pIndex+1)%2 == 0 && scenario.player2Blueprint != "" {
-			player.Blueprint = scenario.player2Blueprint
-		} else if scenario.player1Blueprint != "" {
-			player.Blueprint = scenario.player1Blueprint
-		} else {
-			player.Blueprint = "player-tank"
-		}
-
-		//todo move to custom configurator to remove get info call
-		spawnPosition, err := receiver.NewSpawnPosition(player.Blueprint)
-		if err != nil {
-			logger.Println(fmt.Errorf("unable alloc new position for player %d: %w", pIndex+1, err))
-			spawnPosition = PosAuto
-		}
-		object, err := receiver.SpawnManager.SpawnPlayerTank(spawnPosition, player.Blueprint, player)
-		if err != nil {
-			logger.Println("at spawning player error: ", err)
-		}
-		receiver.spawnedPlayer++
-		if unit, ok := object.(*Unit); ok && unit.Gun != nil {
-			unit.Gun.Current.Name = getProjectilePlDescription(unit.Gun.Current.Projectile).Name
-		}
+		receiver.spawnPlayer(pIndex, player)
 	}
 
 	//timers block
-	everyFunc(time.Second/2, receiver.doDelayedSpawn, receiver.ctxGame)
+	Clock.Every(time.Second/2, receiver.doDelayedSpawn, receiver.ctxGame)
 
 	receiver.playBackground("main")
 
@@ -126,6 +114,84 @@ func (receiver *Game) Run(scenario *Scenario) error {
 	return nil
 }
 
+func (receiver *Game) spawnPlayer(pIndex int, player *Player) {
+	scenario := receiver.scenario
+	if (pIndex+1)%2 == 0 && scenario.player2Blueprint != "" {
+		player.Blueprint = scenario.player2Blueprint
+	} else if scenario.player1Blueprint != "" {
+		player.Blueprint = scenario.player1Blueprint
+	} else {
+		player.Blueprint = "player-tank"
+	}
+
+	//todo move to custom configurator to remove get info call
+	spawnPosition, err := receiver.NewSpawnPosition(player.Blueprint)
+	if err != nil {
+		logger.Println(fmt.Errorf("unable alloc new position for player %d: %w", pIndex+1, err))
+		spawnPosition = PosAuto
+	}
+	object, err := receiver.SpawnManager.SpawnPlayerTank(spawnPosition, player.Blueprint, player)
+	if err != nil {
+		logger.Println("at spawning player error: ", err)
+	}
+	receiver.handToBot(object)
+	atomic.AddInt64(&receiver.spawnedPlayer, 1)
+	if unit, ok := object.(*Unit); ok && unit.Gun != nil {
+		unit.Gun.Current.Name = getProjectilePlDescription(unit.Gun.Current.Projectile).Name
+	}
+}
+
+// JoinPlayer add player to running game (drop-in), use AddPlayer before start
+func (receiver *Game) JoinPlayer(player *Player) error {
+	receiver.mutex.Lock()
+	if !receiver.inProgress {
+		receiver.mutex.Unlock()
+		return GameNotInProgressError
+	}
+	players := make([]*Player, len(receiver.players), len(receiver.players)+1)
+	copy(players, receiver.players) //copy on write, players are iterated without lock
+	receiver.players = append(players, player)
+	pIndex := len(players)
+	receiver.mutex.Unlock()
+
+	receiver.spawnPlayer(pIndex, player)
+	return nil
+}
+
+// LeavePlayer remove player and his tank, game is lost when nobody left
+func (receiver *Game) LeavePlayer(player *Player) error {
+	receiver.mutex.Lock()
+	players := make([]*Player, 0, len(receiver.players))
+	for _, candidate := range receiver.players {
+		if candidate != player {
+			players = append(players, candidate)
+		}
+	}
+	if len(players) == len(receiver.players) {
+		receiver.mutex.Unlock()
+		return PlayerNotFoundError
+	}
+	receiver.players = players
+	inProgress := receiver.inProgress
+	receiver.mutex.Unlock()
+
+	if !inProgress {
+		return nil
+	}
+	player.Control.Disable()
+	if atomic.SwapInt32(&player.Retry, 0) > 0 { //still counted as alive
+		if player.Unit != nil {
+			receiver.SpawnManager.DeSpawn(player.Unit)
+		}
+		if atomic.AddInt64(&receiver.spawnedPlayer, -1) == 0 {
+			Clock.Timer(time.Second, func() {
+				receiver.End(GAME_END_LOSE)
+			})
+		}
+	}
+	return nil
+}
+
 func (receiver *Game) onSpawnRequest(scenario *Scenario, payload *SpawnRequest) {
 	if payload.Count <= 0 {
 		payload.Count = 1
@@ -141,7 +207,7 @@ func (receiver *Game) onSpawnRequest(scenario *Scenario, payload *SpawnRequest)
 			if err != nil {
 				logger.Println(err)
 			}
-		} else if scenario.limits.AiUnits == 0 || scenario.limits.AiUnits > receiver.spawnedAi {
+		} else if payload.Team == PLAYER_TEAM || scenario.limits.AiUnits == 0 || scenario.limits.AiUnits > receiver.spawnedAi {
 			err := receiver.doSpawn(scenario, payload)
 			if err != nil {
 				logger.Println(err)
@@ -205,21 +271,26 @@ func (receiver *Game) doSpawn(scenario *Scenario, payload *SpawnRequest) error {
 	if err != nil {
 		return err
 	} else {
-		if object.HasTag("ai") {
+		if isEnemyAi(object) {
 			atomic.AddInt64(&receiver.spawnedAi, 1)
 		}
+		if base, ok := object.(*Unit); ok && base.HasTag("base") && receiver.AiBuilder != nil {
+			receiver.AiBuilder.Squad(base.GetAttr().Team).Guard(base)
+		}
 		if object.HasTag("spawnPoint") {
 			receiver.spawnPoints = append(receiver.spawnPoints, object.(*SpawnPoint))
 		}
+		receiver.handToBot(object)
 	}
 	return nil
 }
 
 func (receiver *Game) onUnitFire(object *Unit, payload interface{}) {
-	if object.Gun != nil && object.Gun.GetProjectile() != "" {
-		_, err := receiver.SpawnManager.SpawnProjectile(PosAuto, object.Gun.GetProjectile(), payload.(FireParams))
+	params := payload.(FireParams)
+	if params.Projectile != "" {
+		_, err := receiver.SpawnManager.SpawnProjectile(PosAuto, params.Projectile, params)
 		if err != nil {
-			logger.Printf("unable to fire %s due: %s \n", object.Gun.GetProjectile(), err)
+			logger.Printf("unable to fire %s due: %s \n", params.Projectile, err)
 		} else {
 			err = receiver.playSound("fire")
 			if err != nil {
@@ -227,7 +298,7 @@ func (receiver *Game) onUnitFire(object *Unit, payload interface{}) {
 			}
 		}
 	} else {
-		logger.Printf("unable to fire due projectile or gun not found %s \n", object.Gun.GetProjectile())
+		logger.Printf("unable to fire due projectile not found, unit %d \n", object.ID)
 	}
 }
 
@@ -272,10 +343,8 @@ func (receiver *Game) onUnitOnSight(object ObjectInterface, payload interface{})
 		}
 	}
 	if unit, ok := payload.(*Unit); ok {
-		if unit.GetAttr().AI {
-			if bc, ok := unit.Control.(*BehaviorControl); ok {
-				bc.See(object.(*Unit))
-			}
+		if watcher, ok := unit.Control.(unitWatcher); ok {
+			watcher.See(object.(*Unit))
 		}
 	}
 }
@@ -287,10 +356,8 @@ func (receiver *Game) onUnitOffSight(object ObjectInterface, payload interface{}
 		}
 	}
 	if unit, ok := payload.(*Unit); ok {
-		if unit.GetAttr().AI {
-			if bc, ok := unit.Control.(*BehaviorControl); ok {
-				bc.UnSee(object.(*Unit))
-			}
+		if watcher, ok := unit.Control.(unitWatcher); ok {
+			watcher.UnSee(object.(*Unit))
 		}
 	}
 }
@@ -374,8 +441,8 @@ func (receiver *Game) onObjectDestroy(object ObjectInterface, payload interface{
 		if err != nil {
 			logger.Printf("unable to spawn water: %s \n", err)
 		}
-		time.AfterFunc(time.Second*time.Duration(30), func() { //time.AfterFunc(time.Second*time.Duration(rand.Intn(11)+25), func() {
-			if game.inProgress && !newWaterObject.GetAttr().Destroyed {
+		Clock.Timer(time.Second*time.Duration(30), func() { //time.AfterFunc(time.Second*time.Duration(rand.Intn(11)+25), func() {
+			if receiver.inProgress && !newWaterObject.GetAttr().Destroyed {
 				_, err := receiver.SpawnManager.Spawn(point, originalBl, func(object ObjectInterface, config interface{}) ObjectInterface {
 					DefaultConfigurator(object, config)
 					//probably not best way to do this, mb wait until spawned
@@ -408,7 +475,7 @@ func (receiver *Game) onObjectDestroy(object ObjectInterface, payload interface{
 			logger.Printf("highlights-appear: invalid duration value %s or toState value %s", durStr, toState)
 		} else {
 			object.(Stater).Enter(toState)
-			time.AfterFunc(time.Duration(duration), func() {
+			Clock.Timer(time.Duration(duration), func() {
 				if receiver.inProgress { //todo fix in game method
 					receiver.SpawnManager.DeSpawn(object)
 				}
@@ -488,7 +555,7 @@ func (receiver *Game) onObjectDeSpawn(object ObjectInterface, payload interface{
 				logger.Printf("cycleId: %d, player %d have %d retry\n", CycleID, idx+1, left)
 				if left <= 0 {
 					if atomic.AddInt64(&receiver.spawnedPlayer, -1) == 0 {
-						time.AfterFunc(time.Second, func() { //small delay to improve experience
+						Clock.Timer(time.Second, func() { //small delay to improve experience
 							receiver.End(GAME_END_LOSE)
 						})
 					}
@@ -500,15 +567,17 @@ func (receiver *Game) onObjectDeSpawn(object ObjectInterface, payload interface{
 							logger.Println(fmt.Errorf("player %d: %w", idx+1, err))
 							spawnPosition = PosAuto //no position?
 						}
-						if _, err = receiver.SpawnManager.SpawnPlayerTank(spawnPosition, player.Blueprint, player); err != nil {
+						if object, err := receiver.SpawnManager.SpawnPlayerTank(spawnPosition, player.Blueprint, player); err != nil {
 							logger.Println("at respawning player error: ", err)
+						} else {
+							receiver.handToBot(object)
 						}
 					}
 				}
 			}
 		}
 	}
-	if object.HasTag("ai") {
+	if isEnemyAi(object) {
 		//todo fix performance degradation if intn = 2 ie probability of spawn ~50%
 		if rand.Intn(5) <= 1 {
 			var bl string
@@ -693,6 +762,10 @@ func gameCmdDispatcher(instance *Game, unitEvent EventChanel, ctx context.Contex
 				panic("chanel error")
 				return
 			}
+			if event.EType == GAME_SYNC {
+				close(event.Payload.(chan bool))
+				continue
+			}
 			if !instance.inProgress {
 				continue
 			}
@@ -701,29 +774,56 @@ func gameCmdDispatcher(instance *Game, unitEvent EventChanel, ctx context.Contex
 			}
 			switch event.EType {
 			case UNIT_EVENT_FIRE:
-				go instance.onUnitFire(event.Object.(*Unit), event.Payload)
+				instance.handle(func() { instance.onUnitFire(event.Object.(*Unit), event.Payload) })
 			case UNIT_EVENT_DAMAGE:
-				go instance.onUnitDamage(event.Object.(ObjectInterface), event.Payload)
+				instance.handle(func() { instance.onUnitDamage(event.Object.(ObjectInterface), event.Payload) })
 			case UNIT_EVENT_ONSIGTH:
-				go instance.onUnitOnSight(event.Object.(ObjectInterface), event.Payload)
+				instance.handle(func() { instance.onUnitOnSight(event.Object.(ObjectInterface), event.Payload) })
 			case UNIT_EVENT_OFFSIGTH:
-				go instance.onUnitOffSight(event.Object.(ObjectInterface), event.Payload)
+				instance.handle(func() { instance.onUnitOffSight(event.Object.(ObjectInterface), event.Payload) })
 			case OBJECT_EVENT_DESTROY:
-				go instance.onObjectDestroy(event.Object.(ObjectInterface), event.Payload)
+				instance.handle(func() { instance.onObjectDestroy(event.Object.(ObjectInterface), event.Payload) })
 			case OBJECT_EVENT_DESPAWN:
-				go instance.onObjectDeSpawn(event.Object.(ObjectInterface), event.Payload)
+				instance.handle(func() { instance.onObjectDeSpawn(event.Object.(ObjectInterface), event.Payload) })
 			case OBJECT_EVENT_RESET:
-				go instance.onObjectReset(event.Object.(ObjectInterface), event.Payload)
+				instance.handle(func() { instance.onObjectReset(event.Object.(ObjectInterface), event.Payload) })
 			case OBJECT_EVENT_SPAWN:
-				go instance.onObjectSpawn(event.Object.(ObjectInterface), event.Payload)
+				instance.handle(func() { instance.onObjectSpawn(event.Object.(ObjectInterface), event.Payload) })
 			case COLLECT_EVENT_COLLECTED:
-				go instance.onUnitCollect(event.Object.(*Collectable), event.Payload)
+				instance.handle(func() { instance.onUnitCollect(event.Object.(*Collectable), event.Payload) })
 			case SPAWN_POINT_STATUS:
-				go instance.onSpawnPointStatus(event.Object.(*SpawnPoint), event.Payload)
+				instance.handle(func() { instance.onSpawnPointStatus(event.Object.(*SpawnPoint), event.Payload) })
 			}
 		}
 	}
 }
+
+// handle event in own goroutine, or queue it to game loop if Loop is set
+func (receiver *Game) handle(handler func()) {
+	if receiver.Loop != nil {
+		receiver.Loop(handler)
+		return
+	}
+	go handler()
+}
+
+// Sync wait until dispatchers took events triggered so far, so with Loop set handlers of them are queued
+func (receiver *Game) Sync() {
+	scenario := receiver.scenario
+	if scenario == nil || receiver.ctxGame.Err() != nil {
+		return //dispatchers are gone
+	}
+	for _, events := range []EventChanel{scenario.GetEventChanel(), receiver.SpawnManager.UnitEventChanel} {
+		done := make(chan bool)
+		select {
+		case events <- Event{EType: GAME_SYNC, Payload: done}:
+			<-done
+		case <-receiver.ctxGame.Done():
+			return
+		}
+	}
+}
+
 func scenarioDispatcher(instance *Game, scenarioEvent EventChanel, ctx context.Context) {
 	if instance == nil {
 		return
@@ -736,6 +836,10 @@ func scenarioDispatcher(instance *Game, scenarioEvent EventChanel, ctx context.C
 			if !ok {
 				return
 			}
+			if event.EType == GAME_SYNC {
+				close(event.Payload.(chan bool))
+				continue
+			}
 			if !instance.inProgress {
 				continue
 			}
@@ -745,14 +849,18 @@ func scenarioDispatcher(instance *Game, scenarioEvent EventChanel, ctx context.C
 			switch event.EType {
 			case SPAWN_REQUEST:
 				//sync due t
-				instance.onSpawnRequest(event.Object.(*Scenario), event.Payload.(*SpawnRequest))
+				if instance.Loop != nil {
+					instance.Loop(func() { instance.onSpawnRequest(event.Object.(*Scenario), event.Payload.(*SpawnRequest)) })
+				} else {
+					instance.onSpawnRequest(event.Object.(*Scenario), event.Payload.(*SpawnRequest))
+				}
 			}
 		}
 	}
 }
 
 func delayedEnterState(object Stater, state string, delay time.Duration) {
-	time.AfterFunc(delay, func() {
+	Clock.Timer(delay, func() {
 		object.Enter(state)
 	})
 }
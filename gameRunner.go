@@ -4,6 +4,7 @@ import (
 	"GoConsoleBT/controller"
 	"context"
 	direct "github.com/buger/goterm"
+	"github.com/buger/jsonparser"
 	"github.com/eiannone/keyboard"
 	"strconv"
 	"time"
@@ -21,6 +22,20 @@ type GameRunner struct {
 	*SoundManager
 	*UI
 	Renderer
+	Pipeline        *GPipeline
+	paused          bool
+	pauseScreen     *OverlayScreen
+	keyBindScreen   *KeyBindScreen
+	menuKeyboard    <-chan keyboard.KeyEvent
+	console         *Console
+	consoleKeyboard <-chan keyboard.KeyEvent
+	menuPaused      bool
+	pausedControls  []*controller.Control
+	PlayerScripts   []string //macro script per player slot, scripted run skip setup dialogs
+	NetHost         *NetHost
+	RemotePlayers   int  //remote players to wait before game start
+	MouseWaypoint   bool //click on map drive player tank
+	AiDebug         *AiDebugOverlay
 }
 
 func (receiver *GameRunner) Init() {
@@ -34,15 +49,7 @@ func (receiver *GameRunner) Run(game *Game, scenario *Scenario, done EventChanel
 		receiver.KeyboardRepeater, _ = NewKeyboardRepeater(receiver.Keyboard)
 	}
 
-	receiver.BlueprintManager.AddLoaderPackage(NewJsonPackage())
-	receiver.BlueprintManager.GameConfig = receiver.GameConfig
-	receiver.BlueprintManager.EventChanel = receiver.SpawnManager.UnitEventChanel //remove from builder
-	if receiver.BehaviorControlBuilder != nil {
-		receiver.BlueprintManager.AddLoader("ai", func(ctx context.Context, get LoaderGetter, eCollector *LoadErrors, preset interface{}, payload []byte) interface{} {
-			ai, _ := receiver.BehaviorControlBuilder.Build()
-			return ai
-		})
-	}
+	setupBlueprints(receiver.BlueprintManager, receiver.GameConfig, receiver.SpawnManager, receiver.BehaviorControlBuilder)
 
 	scenario.DeclareBlueprint(func(blueprint string) {
 		recursiveRequire(blueprint, receiver.BlueprintManager, receiver.SpawnManager, receiver.BehaviorControlBuilder)
@@ -88,9 +95,12 @@ func (receiver *GameRunner) Run(game *Game, scenario *Scenario, done EventChanel
 }
 
 func (receiver *GameRunner) setupSize() {
+	if receiver.scripted() > 0 {
+		return
+	}
 	var configurationChanel EventChanel = make(EventChanel) //todo remove
 
-	keyboard := receiver.KeyboardRepeater.Subscribe()
+	keyboard := receiver.KeyboardRepeater.Capture()
 	screen, err := NewSetupSizeDialog(receiver.GameConfig.Box, keyboard, configurationChanel)
 	if err != nil {
 		panic(err)
@@ -113,9 +123,13 @@ func (receiver *GameRunner) setupSize() {
 }
 
 func (receiver *GameRunner) setupPlayers() {
-
+	if scripted := receiver.scripted(); scripted > 0 {
+		receiver.addPlayers(scripted)
+		receiver.addRemotePlayers()
+		return
+	}
 	var configurationChanel EventChanel = make(EventChanel) //todo remove
-	keyboard := receiver.KeyboardRepeater.Subscribe()
+	keyboard := receiver.KeyboardRepeater.Capture()
 	screen, _ := NewPlayerSelectDialog(keyboard, configurationChanel)
 	receiver.Renderer.Add(screen)
 	screen.Activate()
@@ -128,25 +142,80 @@ func (receiver *GameRunner) setupPlayers() {
 				screen.Deactivate()
 				receiver.KeyboardRepeater.Unsubscribe(keyboard)
 				payload := configuration.Payload.(*DialogInfo)
-				for i := 0; i < payload.Value; i++ {
-					pKeyboard := receiver.KeyboardRepeater.Subscribe()
-					playerControl, _ := controller.NewPlayerControl(pKeyboard, receiver.GameConfig.KeyBindings[i])
-					player, _ := NewPlayer("Player"+strconv.Itoa(i+1), playerControl)
-					player.Keyboard = pKeyboard
-					player.CustomizeMap = &CustomizeMap{
-						"gun":   direct.RED,
-						"armor": direct.YELLOW,
-						"track": direct.CYAN,
-					}
-					game.AddPlayer(player)
-				}
+				receiver.addPlayers(payload.Value)
 				receiver.Renderer.Remove(screen)
+				receiver.addRemotePlayers()
 				return
 			}
 		}
 	}
 }
 
+func (receiver *GameRunner) addPlayers(count int) {
+	for i := 0; i < count; i++ {
+		var playerControl *controller.Control
+		var pKeyboard <-chan keyboard.KeyEvent
+		if i < len(receiver.PlayerScripts) && receiver.PlayerScripts[i] != "" {
+			var err error
+			if playerControl, err = loadScriptControl(receiver.PlayerScripts[i], true); err != nil {
+				logger.Println(err)
+				panic("player script " + receiver.PlayerScripts[i] + " err:" + err.Error())
+			}
+		} else {
+			pKeyboard = receiver.KeyboardRepeater.SubscribeAll()
+			playerControl, _ = controller.NewPlayerControl(pKeyboard, receiver.GameConfig.KeyBindings[i], receiver.GameConfig.Input.HoldConfig)
+		}
+		player, _ := NewPlayer("Player"+strconv.Itoa(i+1), playerControl)
+		player.Keyboard = pKeyboard
+		player.CustomizeMap = &CustomizeMap{
+			"gun":   direct.RED,
+			"armor": direct.YELLOW,
+			"track": direct.CYAN,
+		}
+		game.AddPlayer(player)
+	}
+}
+
+// addRemotePlayers block until all remote players joined
+func (receiver *GameRunner) addRemotePlayers() {
+	if receiver.NetHost == nil || receiver.RemotePlayers <= 0 {
+		return
+	}
+	screen, _ := NewMessageScreen("waiting for " + strconv.Itoa(receiver.RemotePlayers) + " player(s) on " + receiver.NetHost.Addr().String())
+	receiver.Renderer.Add(screen)
+	defer receiver.Renderer.Remove(screen)
+	players, err := receiver.NetHost.Accept(receiver.RemotePlayers, len(game.GetPlayers()))
+	if err != nil {
+		logger.Println(err)
+	}
+	for _, player := range players {
+		player.CustomizeMap = &CustomizeMap{
+			"gun":   direct.RED,
+			"armor": direct.YELLOW,
+			"track": direct.CYAN,
+		}
+		game.AddPlayer(player)
+	}
+}
+
+// RefreshUI pick up players joined or left in running game
+func (receiver *GameRunner) RefreshUI() {
+	if receiver.UI != nil && receiver.UI.UIData != nil {
+		receiver.UI.UIData = &UIData{players: receiver.Game.GetPlayers()}
+	}
+}
+
+// scripted return count of players needed to cover all scripted slots
+func (receiver *GameRunner) scripted() int {
+	count := 0
+	for i, script := range receiver.PlayerScripts {
+		if script != "" {
+			count = i + 1
+		}
+	}
+	return count
+}
+
 func (receiver *GameRunner) wait(duration time.Duration) {
 	<-time.After(duration)
 }
@@ -157,9 +226,33 @@ func (receiver *GameRunner) clear() {
 }
 
 func (receiver *GameRunner) runGame() (exitEvent Event) {
+	sysKeyboard := receiver.KeyboardRepeater.Subscribe()
+	defer receiver.KeyboardRepeater.Unsubscribe(sysKeyboard)
 	go receiver.Game.Run(receiver.Scenario)
 	for {
 		select {
+		case event, ok := <-sysKeyboard:
+			if !ok {
+				sysKeyboard = nil
+				continue
+			}
+			receiver.onSystemKey(event)
+		case event, ok := <-receiver.menuKeyboard:
+			if !ok {
+				receiver.menuKeyboard = nil //input gone, closed chanel would spin select
+				continue
+			}
+			if receiver.keyBindScreen.HandleKey(event) {
+				receiver.closeKeyBindScreen()
+			}
+		case event, ok := <-receiver.consoleKeyboard:
+			if !ok {
+				receiver.consoleKeyboard = nil
+				continue
+			}
+			if receiver.console.HandleKey(event) {
+				receiver.closeConsole()
+			}
 		case gameEvent := <-receiver.Game.GetEventChanel():
 			switch gameEvent.EType {
 			case GAME_START:
@@ -170,20 +263,191 @@ func (receiver *GameRunner) runGame() (exitEvent Event) {
 			case GAME_END_WIN:
 				fallthrough
 			case GAME_END_LOSE:
+				if receiver.keyBindScreen != nil {
+					receiver.closeKeyBindScreen()
+				}
+				if receiver.consoleKeyboard != nil {
+					receiver.closeConsole()
+				}
+				receiver.pause(false)
 				if receiver.UI != nil {
 					receiver.Renderer.Remove(receiver.UI)
 				}
 				for _, player := range receiver.players {
 					receiver.KeyboardRepeater.Unsubscribe(player.Keyboard)
 				}
+				if dropped := receiver.KeyboardRepeater.Dropped(nil); dropped > 0 {
+					logger.Printf("keyboard subscribers lost %d keys \n", dropped)
+				}
 				if DEBUG_SHUTDOWN {
 					logger.Println("receive GAME_END event")
 				}
+				if receiver.NetHost != nil {
+					receiver.NetHost.End(gameEvent.EType)
+				}
 				return gameEvent
 			}
 		}
 	}
 }
+
+// onSystemKey handle game wide actions: pause, menu and control hand-off
+func (receiver *GameRunner) onSystemKey(event keyboard.KeyEvent) {
+	if mouse, ok := controller.AsMouse(event); ok {
+		receiver.onMouse(mouse)
+		return
+	}
+	key := controller.EventKey(event)
+	for i, bind := range receiver.GameConfig.KeyBindings {
+		action, _ := bind.Lookup(key)
+		switch action {
+		case controller.ACTION_UP, controller.ACTION_DOWN, controller.ACTION_LEFT, controller.ACTION_RIGHT,
+			controller.ACTION_STOP, controller.ACTION_FIRE:
+			if i < len(receiver.players) && receiver.players[i].Waypoint != nil {
+				receiver.players[i].Waypoint.Cancel() //player take control back
+				receiver.players[i].Waypoint = nil
+			}
+		case controller.ACTION_AUTOPILOT:
+			if i < len(receiver.players) && !receiver.paused {
+				player := receiver.players[i]
+				if player.Waypoint != nil {
+					player.Waypoint.Cancel()
+					player.Waypoint = nil
+				}
+				receiver.Game.Autopilot(player, player.Autopilot == nil)
+			}
+			return
+		case controller.ACTION_TAKEOVER:
+			if i < len(receiver.players) && !receiver.paused {
+				if err := receiver.Game.TakeOverNearest(receiver.players[i]); err != nil {
+					logger.Println(err)
+				}
+			}
+			return
+		case controller.ACTION_FOLLOW, controller.ACTION_HOLD, controller.ACTION_ATTACK:
+			if i < len(receiver.players) && !receiver.paused {
+				order := AI_ORDER_FOLLOW
+				if action == controller.ACTION_HOLD {
+					order = AI_ORDER_HOLD
+				} else if action == controller.ACTION_ATTACK {
+					order = AI_ORDER_ATTACK
+				}
+				if err := receiver.Game.Order(receiver.players[i], order); err != nil {
+					logger.Println(err)
+				}
+			}
+			return
+		case controller.ACTION_PAUSE:
+			receiver.pause(!receiver.paused)
+			return
+		case controller.ACTION_MENU:
+			receiver.openKeyBindScreen()
+			return
+		case controller.ACTION_CONSOLE:
+			if receiver.keyBindScreen == nil {
+				receiver.openConsole()
+			}
+			return
+		case controller.ACTION_DEBUG_AI:
+			if receiver.AiDebug != nil {
+				receiver.AiDebug.Toggle()
+			}
+			return
+		}
+	}
+}
+
+// onMouse set waypoint, left button for first player, right for second
+func (receiver *GameRunner) onMouse(mouse *controller.MouseEvent) {
+	if !receiver.MouseWaypoint || receiver.paused || mouse.Action != controller.MOUSE_PRESS {
+		return
+	}
+	index := -1
+	switch mouse.Button {
+	case controller.MOUSE_LEFT:
+		index = 0
+	case controller.MOUSE_RIGHT:
+		index = 1
+	}
+	if index < 0 || index >= len(receiver.players) {
+		return
+	}
+	if err := receiver.Game.SetWaypoint(receiver.players[index], Point{X: float64(mouse.X), Y: float64(mouse.Y)}); err != nil {
+		logger.Println(err)
+	}
+}
+
+func (receiver *GameRunner) pause(pause bool) {
+	if receiver.paused == pause {
+		return
+	}
+	receiver.paused = pause
+	if receiver.Pipeline != nil {
+		receiver.Pipeline.Pause(pause)
+	}
+	if pause {
+		for _, player := range receiver.players {
+			if player.Control.IsEnabled() {
+				player.Control.Disable()
+				receiver.pausedControls = append(receiver.pausedControls, player.Control)
+			}
+		}
+		if receiver.pauseScreen == nil {
+			receiver.pauseScreen, _ = NewPauseScreen()
+		}
+		receiver.Renderer.Add(receiver.pauseScreen)
+	} else {
+		for _, control := range receiver.pausedControls {
+			control.Enable()
+		}
+		receiver.pausedControls = receiver.pausedControls[0:0]
+		receiver.Renderer.Remove(receiver.pauseScreen)
+	}
+}
+
+func (receiver *GameRunner) openKeyBindScreen() {
+	receiver.keyBindScreen, _ = NewKeyBindScreen(receiver.GameConfig.KeyBindings)
+	receiver.menuPaused = !receiver.paused
+	receiver.pause(true)
+	receiver.Renderer.Add(receiver.keyBindScreen)
+	receiver.menuKeyboard = receiver.KeyboardRepeater.Capture()
+}
+
+func (receiver *GameRunner) closeKeyBindScreen() {
+	receiver.KeyboardRepeater.Unsubscribe(receiver.menuKeyboard)
+	receiver.menuKeyboard = nil
+	receiver.Renderer.Remove(receiver.keyBindScreen)
+	receiver.GameConfig.KeyBindings = receiver.keyBindScreen.Bindings()
+	receiver.keyBindScreen = nil
+	for i, player := range receiver.players {
+		if i < len(receiver.GameConfig.KeyBindings) {
+			player.Control.SetKeyBind(receiver.GameConfig.KeyBindings[i])
+		}
+	}
+	if _, err := saveConfig(receiver.GameConfig); err != nil {
+		logger.Println(err)
+	}
+	if receiver.menuPaused {
+		receiver.pause(false)
+	}
+}
+
+// openConsole drop down developer console, it capture keyboard but game keep running
+func (receiver *GameRunner) openConsole() {
+	if receiver.console == nil {
+		receiver.console, _ = NewConsole(receiver)
+	}
+	receiver.console.redraw()
+	receiver.Renderer.Add(receiver.console)
+	receiver.consoleKeyboard = receiver.KeyboardRepeater.Capture()
+}
+
+func (receiver *GameRunner) closeConsole() {
+	receiver.KeyboardRepeater.Unsubscribe(receiver.consoleKeyboard)
+	receiver.consoleKeyboard = nil
+	receiver.Renderer.Remove(receiver.console)
+}
+
 func (receiver *GameRunner) resultScreen(exitEvent Event) Event {
 	var screen Screener
 	switch exitEvent.EType {
@@ -209,6 +473,30 @@ func NewGameRunner() (*GameRunner, error) {
 	return &GameRunner{}, nil
 }
 
+// setupBlueprints register json loaders and ones backed by game services (ai, bot)
+func setupBlueprints(manager *BlueprintManager, config *GameConfig, spawner *SpawnManager, aiBuilder *BehaviorControlBuilder) {
+	manager.AddLoaderPackage(NewJsonPackage())
+	manager.GameConfig = config
+	manager.EventChanel = spawner.UnitEventChanel //remove from builder
+	if aiBuilder != nil {
+		manager.AddLoader("ai", func(ctx context.Context, get LoaderGetter, eCollector *LoadErrors, preset interface{}, payload []byte) interface{} {
+			ai, _ := aiBuilder.Build()
+			if name, err := jsonparser.GetString(payload, "ai", "tree"); err == nil {
+				if tree, err := aiBuilder.Tree(name); !eCollector.Add(err) {
+					ai.SetTree(tree)
+				}
+			}
+			return ai
+		})
+	}
+
+	manager.AddLoader("bot", func(ctx context.Context, get LoaderGetter, eCollector *LoadErrors, preset interface{}, payload []byte) interface{} {
+		command, _ := jsonparser.GetString(payload, "control", "bot")
+		bot, _ := NewBotControl(command, spawner.collider)
+		return bot
+	})
+}
+
 func recursiveRequire(blueprint string, blManager *BlueprintManager, spawnManager *SpawnManager, behavior *BehaviorControlBuilder) {
 	//todo make tree
 	if spawnManager.HasBuilder(blueprint) {
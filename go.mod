@@ -21,4 +21,6 @@ require (
 	github.com/xarg/gopathfinding v0.0.0-20170223193223-aefc81ce6658
 	github.com/xiaonanln/go-lockfree-pool v0.0.0-20181017030802-53ecc7b8f637
 	github.com/xiaonanln/go-lockfree-queue v0.0.0-20181015150615-23113b463d4f // indirect
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
+	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c
 )
@@ -14,6 +14,7 @@ var OutAmmoError = errors.New("out of ammo")
 type FireParams struct {
 	Position, Direction, BaseSpeed Point
 	Owner                          ObjectInterface
+	Projectile                     string //blueprint of fired slot, alt fire shoots not current one
 }
 
 type GunState struct {
@@ -36,30 +37,49 @@ type Gun struct {
 func (receiver *Gun) Fire() error {
 	receiver.mutex.Lock()
 	var current = receiver.Current
+	receiver.mutex.Unlock()
+	return receiver.fire(current)
+}
+
+// AltFire fire from slot that is not current one, e.g. basic gun while upgrade is selected
+func (receiver *Gun) AltFire() error {
+	receiver.mutex.Lock()
+	var alt *GunState
+	for _, state := range receiver.State {
+		if state != nil && state != receiver.Current {
+			alt = state
+			break
+		}
+	}
+	receiver.mutex.Unlock()
+	return receiver.fire(alt)
+}
+
+func (receiver *Gun) fire(current *GunState) error {
 	if current == nil {
-		receiver.mutex.Unlock()
 		return GunConfigError
 	}
 	var delayAccumulator time.Duration
-	if receiver.IsReloading() {
+	receiver.mutex.Lock()
+	if current.isReloading() {
 		receiver.mutex.Unlock()
 		return ReloadError
 	}
-	current.lastShotTime = time.Now()
+	current.lastShotTime = Clock.Now()
 	receiver.mutex.Unlock()
-	params := receiver.getParams()
+	params := receiver.getParams(current)
 	for i := 0; i < current.ShotQueue; i++ {
 		if current.Ammo != -1 && current.Ammo <= 0 {
 			return OutAmmoError
 		}
 		if current.PerShotQueueTime > 0 && i > 1 {
 			delayAccumulator += current.PerShotQueueTime
-			time.AfterFunc(delayAccumulator, func() {
-				if receiver.Owner.destroyed {
+			Clock.Timer(delayAccumulator, func() {
+				if receiver.Owner.destroyed || !receiver.Owner.spawned { //game may end before burst
 					return
 				}
 				receiver.Owner.Trigger(FireEvent, receiver.Owner, params)
-				current.lastShotTime = time.Now()
+				current.lastShotTime = Clock.Now()
 				if current.Ammo > 0 {
 					current.Ammo--
 				}
@@ -67,7 +87,7 @@ func (receiver *Gun) Fire() error {
 
 		} else {
 			receiver.Owner.Trigger(FireEvent, receiver.Owner, params)
-			current.lastShotTime = time.Now()
+			current.lastShotTime = Clock.Now()
 			if current.Ammo > 0 {
 				current.Ammo--
 			}
@@ -77,10 +97,14 @@ func (receiver *Gun) Fire() error {
 }
 
 func (receiver *Gun) IsReloading() bool {
-	if receiver.Current.lastShotTime.IsZero() {
+	return receiver.Current.isReloading()
+}
+
+func (receiver *GunState) isReloading() bool {
+	if receiver.lastShotTime.IsZero() {
 		return false
 	}
-	if time.Now().Sub(receiver.Current.lastShotTime) > receiver.Current.ReloadTime {
+	if Clock.Now().Sub(receiver.lastShotTime) > receiver.ReloadTime {
 		return false
 	}
 	return true
@@ -158,13 +182,17 @@ func (receiver *Gun) getPosition() Point {
 	}
 }
 
-func (receiver *Gun) getParams() FireParams {
-	return FireParams{
+func (receiver *Gun) getParams(state *GunState) FireParams {
+	params := FireParams{
 		Position:  receiver.getPosition(),
 		BaseSpeed: receiver.Owner.Speed,
 		Direction: receiver.Owner.Direction,
 		Owner:     receiver.Owner,
 	}
+	if state != nil {
+		params.Projectile = state.Projectile
+	}
+	return params
 }
 
 func (receiver *Gun) Copy() *Gun {
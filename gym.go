@@ -0,0 +1,507 @@
+package main
+
+import (
+	"GoConsoleBT/collider"
+	"GoConsoleBT/controller"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// zone grid channels, see Location.ZoneGrid
+const (
+	GYM_GRID_OBSTACLE = iota //block move and projectiles
+	GYM_GRID_TERRAIN         //water, forest, ice and rest of passable or slowing objects
+	GYM_GRID_ALLY
+	GYM_GRID_ENEMY
+	GYM_GRID_BASE
+	GYM_GRID_CHANNELS
+)
+
+const (
+	GYM_AGENT_TEAM   = PLAYER_TEAM //agents are player slots
+	GYM_END_TIMEOUT  = 1000        //cycles to wait for previous game teardown on reset
+	GYM_RPC_PARSE    = -32700
+	GYM_RPC_METHOD   = -32601
+	GYM_RPC_PARAMS   = -32602
+	GYM_RPC_INTERNAL = -32603
+)
+
+var (
+	GymNotResetError = errors.New("gym is not reset")
+	GymAgentError    = errors.New("actions for unknown agent")
+	GymTeardownError = errors.New("previous game did not end in time")
+)
+
+// PlayerStats is what player tanks did, damage dealt and kills count enemy units only
+type PlayerStats struct {
+	DamageDealt int `json:"damageDealt"`
+	DamageTaken int `json:"damageTaken"`
+	Kills       int `json:"kills"`
+	Deaths      int `json:"deaths"`
+}
+
+// GameStats collect hits since last Take
+type GameStats struct {
+	players    map[*Player]*PlayerStats
+	baseDamage int
+	mutex      sync.Mutex
+}
+
+func (receiver *GameStats) Hit(game *Game, target, nemesis ObjectInterface, damage int, destroyed bool) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if target.HasTag("base") {
+		receiver.baseDamage += damage
+	}
+	if _, ok := target.(*Unit); !ok {
+		return
+	}
+	if player := game.playerByUnit(target); player != nil {
+		stats := receiver.player(player)
+		stats.DamageTaken += damage
+		if destroyed {
+			stats.Deaths++
+		}
+	}
+	if nemesis == nil || nemesis.GetAttr().Team == target.GetAttr().Team {
+		return
+	}
+	if player := game.playerByUnit(nemesis); player != nil {
+		stats := receiver.player(player)
+		stats.DamageDealt += damage
+		if destroyed {
+			stats.Kills++
+		}
+	}
+}
+
+func (receiver *GameStats) player(player *Player) *PlayerStats {
+	stats, ok := receiver.players[player]
+	if !ok {
+		stats = &PlayerStats{}
+		receiver.players[player] = stats
+	}
+	return stats
+}
+
+// Take return collected stats and start over
+func (receiver *GameStats) Take() (players map[*Player]PlayerStats, baseDamage int) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	players = make(map[*Player]PlayerStats, len(receiver.players))
+	for player, stats := range receiver.players {
+		players[player] = *stats
+	}
+	baseDamage = receiver.baseDamage
+	receiver.players, receiver.baseDamage = make(map[*Player]*PlayerStats), 0
+	return players, baseDamage
+}
+
+func NewGameStats() (*GameStats, error) {
+	return &GameStats{players: make(map[*Player]*PlayerStats)}, nil
+}
+
+// ZoneGrid one hot tensor [channel][y][x] of objects on unit and terrain layers from team point of view
+func (receiver *Location) ZoneGrid(team int8) [][][]int8 {
+	receiver.zoneLock.Lock()
+	defer receiver.zoneLock.Unlock()
+	grid := make([][][]int8, GYM_GRID_CHANNELS)
+	for channel := range grid {
+		grid[channel] = make([][]int8, receiver.sizeZone.Y)
+		for yi := range grid[channel] {
+			grid[channel][yi] = make([]int8, receiver.sizeZone.X)
+		}
+	}
+	for _, layer := range receiver.zones[:LOCATION_LAYER_AIR] {
+		for yi, row := range layer {
+			for xi, trackable := range row {
+				object, ok := trackable.(ObjectInterface)
+				if !ok {
+					continue //empty or spawn placeholder
+				}
+				channel := GYM_GRID_TERRAIN
+				switch {
+				case object.HasTag("base"):
+					channel = GYM_GRID_BASE
+				case object.GetAttr().Type == "unit" && object.GetAttr().Team == team:
+					channel = GYM_GRID_ALLY
+				case object.GetAttr().Type == "unit":
+					channel = GYM_GRID_ENEMY
+				case object.HasTag("obstacle") && !object.HasTag("low"):
+					channel = GYM_GRID_OBSTACLE
+				}
+				grid[channel][yi][xi] = 1
+			}
+		}
+	}
+	return grid
+}
+
+type GymConfig struct {
+	Scenario   string //scenario file or random
+	Agents     int    //player slots driven by step actions
+	Difficulty string //ai difficulty, default from scenario
+	TankCnt    int    //random scenario
+	WallCnt    int    //random scenario
+}
+
+type GymAgent struct {
+	Alive  bool        `json:"alive"`
+	Unit   *BotObject  `json:"unit,omitempty"` //nil while dead
+	Gun    *BotGun     `json:"gun,omitempty"`
+	Lives  int32       `json:"lives"`
+	Score  int64       `json:"score"`
+	Reward PlayerStats `json:"reward"` //since previous observation
+}
+
+type GymObservation struct {
+	Cycle      int64       `json:"cycle"` //since reset
+	Grid       [][][]int8  `json:"grid"`  //[channel][y][x], see GYM_GRID_*
+	Box        Box         `json:"box"`   //location coordinates, zone is box size / grid size
+	Units      []BotObject `json:"units"` //all tanks, base excluded
+	Agents     []GymAgent  `json:"agents"`
+	BaseHP     int         `json:"baseHp"`
+	BaseDamage int         `json:"baseDamage"` //since previous observation
+	Done       bool        `json:"done"`
+	Result     string      `json:"result,omitempty"` //win or lose
+}
+
+// Gym run game headless and as fast as it can, driven by Reset and Step. One gym per process: game state is global
+type Gym struct {
+	GymConfig
+	pipe     *GPipeline
+	spawner  *SpawnManager
+	location *Location
+	builder  *BehaviorControlBuilder
+	nav      *Navigation
+	game     *Game
+	players  []*Player
+	cycles   int64
+	idBase   int64 //observed ids count from reset
+	result   int32 //GAME_END_* of current game, 0 in progress
+	ended    chan bool
+}
+
+// Reset end current game and start scenario again, seed make ai and random scenario repeatable
+func (receiver *Gym) Reset(seed int64) (*GymObservation, error) {
+	if err := receiver.stop(); err != nil {
+		return nil, err
+	}
+	rand.Seed(seed)
+	Clock.Drop() //timers of previous game
+	atomic.StoreInt64(&CycleID, 0)
+	var err error
+	if scenario, err = receiver.loadScenario(); err != nil {
+		return nil, err
+	}
+	//blueprints loaded first time take ids and draw from rand, game goes on the same whatever they took
+	next := rand.Int63()
+	for _, blueprint := range scenario.Declared() {
+		recursiveRequire(blueprint, buildManager, receiver.spawner, receiver.builder)
+	}
+	rand.Seed(next)
+	scenario.DeclareBlueprint(func(blueprint string) {
+		recursiveRequire(blueprint, buildManager, receiver.spawner, receiver.builder)
+	})
+
+	receiver.players = make([]*Player, receiver.Agents)
+	for i := range receiver.players {
+		control, _ := controller.NewNoneControl()
+		receiver.players[i], _ = NewPlayer("Agent"+strconv.Itoa(i+1), control)
+	}
+	receiver.game, _ = NewGame(receiver.players, receiver.spawner)
+	receiver.game.Location = receiver.location
+	receiver.game.EffectManager = receiver.pipe.EffectManager
+	receiver.game.AiBuilder = receiver.builder
+	receiver.game.Navigation = receiver.nav
+	receiver.game.Stats, _ = NewGameStats()
+	receiver.game.Loop = receiver.pipe.Defer
+	atomic.StoreInt32(&receiver.result, 0)
+	receiver.ended = make(chan bool)
+	receiver.idBase = atomic.LoadInt64(&monotonicId)
+	go receiver.watch(receiver.game.GetEventChanel(), receiver.ended)
+
+	if err := receiver.game.Run(scenario); err != nil {
+		return nil, err
+	}
+	receiver.cycles = 0
+	receiver.cycle() //spawn
+	return receiver.observe(), nil
+}
+
+// Step apply actions[i] to agent i tank and run cycles, game time is cycles * CYCLE whatever real time it takes
+func (receiver *Gym) Step(actions [][]controller.Command, cycles int) (*GymObservation, error) {
+	if receiver.game == nil {
+		return nil, GymNotResetError
+	}
+	if len(actions) > len(receiver.players) {
+		return nil, fmt.Errorf("%d: %w", len(actions)-1, GymAgentError)
+	}
+	for i, commands := range actions {
+		unit := receiver.players[i].Unit
+		if unit == nil || unit.destroyed {
+			continue
+		}
+		for _, command := range commands {
+			unit.Execute(command)
+		}
+	}
+	for i := 0; i < maxInt(cycles, 1) && atomic.LoadInt32(&receiver.result) == 0; i++ {
+		receiver.cycle()
+	}
+	return receiver.observe(), nil
+}
+
+// cycle of game loop, event handlers of previous one are queued to it first
+func (receiver *Gym) cycle() {
+	receiver.game.Sync()
+	receiver.pipe.Execute(CYCLE)
+	atomic.AddInt64(&CycleID, 1)
+	receiver.cycles++
+	if receiver.game.ctxGame.Err() != nil {
+		<-receiver.ended //everything is despawned, result is on the way
+	}
+}
+
+// watch game events until game end, game block on them otherwise
+func (receiver *Gym) watch(events EventChanel, ended chan bool) {
+	for event := range events {
+		if event.EType == GAME_END_WIN || event.EType == GAME_END_LOSE {
+			atomic.StoreInt32(&receiver.result, int32(event.EType))
+			close(ended)
+			return
+		}
+	}
+}
+
+// stop end game in progress and run cycles until it despawn everything
+func (receiver *Gym) stop() error {
+	if receiver.game == nil {
+		return nil
+	}
+	receiver.game.End(GAME_END_LOSE)
+	for i := 0; i < GYM_END_TIMEOUT && atomic.LoadInt32(&receiver.result) == 0; i++ {
+		receiver.cycle()
+	}
+	if atomic.LoadInt32(&receiver.result) == 0 {
+		return GymTeardownError
+	}
+	receiver.game = nil
+	return nil
+}
+
+func (receiver *Gym) loadScenario() (*Scenario, error) {
+	if receiver.Scenario == "random" {
+		return NewRandomScenario(receiver.TankCnt, receiver.WallCnt, limitMaxAi)
+	}
+	return GetScenario(receiver.Scenario)
+}
+
+func (receiver *Gym) observe() *GymObservation {
+	hits, baseDamage := receiver.game.Stats.Take()
+	observation := &GymObservation{
+		Cycle:      receiver.cycles,
+		Grid:       receiver.location.ZoneGrid(GYM_AGENT_TEAM),
+		Box:        receiver.location.GetBox(),
+		Units:      make([]BotObject, 0),
+		Agents:     make([]GymAgent, len(receiver.players)),
+		BaseDamage: baseDamage,
+	}
+	for _, object := range receiver.spawner.QuerySpawnedByTag("tank") {
+		unit, ok := object.(*Unit)
+		if !ok || unit.destroyed {
+			continue
+		}
+		if unit.HasTag("base") {
+			observation.BaseHP += unit.HP
+			continue
+		}
+		observation.Units = append(observation.Units, receiver.botObject(unit))
+	}
+	sort.Slice(observation.Units, func(i, j int) bool {
+		return observation.Units[i].ID < observation.Units[j].ID
+	})
+	for i, player := range receiver.players {
+		agent := GymAgent{
+			Lives:  atomic.LoadInt32(&player.Retry),
+			Score:  atomic.LoadInt64(&player.Score),
+			Reward: hits[player],
+		}
+		if unit := player.Unit; unit != nil && !unit.destroyed {
+			object, gun := receiver.botObject(unit), botGun(unit.Gun)
+			agent.Alive, agent.Unit, agent.Gun = true, &object, &gun
+		}
+		observation.Agents[i] = agent
+	}
+	switch atomic.LoadInt32(&receiver.result) {
+	case GAME_END_WIN:
+		observation.Done, observation.Result = true, "win"
+	case GAME_END_LOSE:
+		observation.Done, observation.Result = true, "lose"
+	}
+	return observation
+}
+
+type gymRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type gymStepParams struct {
+	Actions [][]controller.Command `json:"actions"`
+	Cycles  int                    `json:"cycles"`
+}
+
+type gymResetParams struct {
+	Seed int64 `json:"seed"`
+}
+
+type gymError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type gymResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *gymError       `json:"error,omitempty"`
+}
+
+func (receiver *Gym) botObject(unit *Unit) BotObject {
+	object := botObject(unit)
+	object.ID -= receiver.idBase
+	return object
+}
+
+// Serve line delimited json-rpc 2.0: reset {seed}, step {actions, cycles}, close. Return on close or input end
+func (receiver *Gym) Serve(input io.Reader, output io.Writer) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	encoder := json.NewEncoder(output)
+	for scanner.Scan() {
+		var request gymRequest
+		response := gymResponse{Version: "2.0"}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response.Error = &gymError{Code: GYM_RPC_PARSE, Message: err.Error()}
+		} else {
+			response.ID = request.ID
+			response.Result, response.Error = receiver.call(request)
+		}
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+		if request.Method == "close" {
+			return receiver.stop()
+		}
+	}
+	return scanner.Err()
+}
+
+func (receiver *Gym) call(request gymRequest) (interface{}, *gymError) {
+	var result interface{}
+	var err error
+	switch request.Method {
+	case "reset":
+		params := gymResetParams{}
+		if len(request.Params) > 0 && json.Unmarshal(request.Params, &params) != nil {
+			return nil, &gymError{Code: GYM_RPC_PARAMS, Message: "want {seed}"}
+		}
+		result, err = receiver.Reset(params.Seed)
+	case "step":
+		params := gymStepParams{}
+		if len(request.Params) > 0 && json.Unmarshal(request.Params, &params) != nil {
+			return nil, &gymError{Code: GYM_RPC_PARAMS, Message: "want {actions, cycles}"}
+		}
+		result, err = receiver.Step(params.Actions, params.Cycles)
+	case "close":
+		return true, nil
+	default:
+		return nil, &gymError{Code: GYM_RPC_METHOD, Message: request.Method + ": unknown method"}
+	}
+	if errors.Is(err, GymAgentError) {
+		return nil, &gymError{Code: GYM_RPC_PARAMS, Message: err.Error()}
+	}
+	if err != nil {
+		return nil, &gymError{Code: GYM_RPC_INTERNAL, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// NewGym build headless world: no render, sound, ui or keyboard. Location size is taken from first scenario load
+func NewGym(config GymConfig) (*Gym, error) {
+	var err error
+	if gameConfig, err = loadConfig(); err != nil {
+		gameConfig, _ = NewDefaultGameConfig()
+	}
+	gameConfig.disableCustomization = true
+	render = NullRender{}
+
+	instance := &Gym{GymConfig: config}
+	instance.Agents = maxInt(instance.Agents, 1)
+	if scenario, err = instance.loadScenario(); err != nil {
+		return nil, err
+	}
+
+	pipe, _ := NewGPipeline()
+	pipe.Lockstep = true
+	Clock.Lockstep = true
+	pipe.AnimationManager, _ = getAnimationManager()
+	pipe.Render = render
+	pipe.Updater, _ = NewUpdater(100)
+	pipe.EffectManager, _ = NewEffectManager(render, pipe.Updater)
+	pipe.Collider, _ = collider.NewCollider(100)
+	pipe.Collider.Lockstep = true
+	pipe.Visioner, _ = NewVisioner(pipe.Collider, 100)
+
+	size := scenario.Location
+	if size == EmptyLocation {
+		size = gameConfig.Box
+		size.H -= 3
+	}
+	size.Y += 3
+	pipe.Location, _ = NewLocation(size.Point, size.Size)
+	pipe.Location.Lockstep = true
+	pipe.Collider.Add(pipe.Location)
+	pipe.SpawnManager, _ = NewSpawner(pipe.Updater, render, pipe.Collider, pipe.Location, pipe.Visioner, gameConfig)
+	pipe.SpawnManager.Lockstep = true
+	pipe.Navigation, _ = NewNavigation(pipe.Location, pipe.Collider)
+	pipe.Navigation.Lockstep = true
+	influence, _ := NewInfluenceMap(pipe.Location, pipe.SpawnManager)
+	influence.Lockstep = true
+	pipe.Updater.Add(influence)
+
+	buildManager, _ = NewBlueprintManager()
+	Require = func(blueprint string) error {
+		_, err := buildManager.Get(blueprint)
+		return err
+	}
+	Info = func(blueprint string) (BlueprintInfo, error) {
+		return buildManager.Info(blueprint)
+	}
+
+	builder, _ := NewAIControlBuilder(pipe.Collider, pipe.Location, pipe.Navigation)
+	builder.Influence = influence
+	builder.Lockstep = true
+	difficulty := config.Difficulty
+	if difficulty == "" {
+		difficulty = scenario.Difficulty
+	}
+	if builder.Difficulty, err = GetAiDifficulty(difficulty); err != nil {
+		return nil, err
+	}
+	setupBlueprints(buildManager, gameConfig, pipe.SpawnManager, builder)
+
+	instance.pipe, instance.spawner, instance.location = pipe, pipe.SpawnManager, pipe.Location
+	instance.builder, instance.nav = builder, pipe.Navigation
+	return instance, nil
+}
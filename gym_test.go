@@ -0,0 +1,92 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	sharedGym     *Gym
+	sharedGymErr  error
+	sharedGymOnce sync.Once
+)
+
+// testGym is one for all tests, game state is global
+func testGym(t *testing.T) *Gym {
+	sharedGymOnce.Do(func() {
+		sharedGym, sharedGymErr = NewGym(GymConfig{Scenario: "random", Agents: 1, TankCnt: 4, WallCnt: 10})
+	})
+	if sharedGymErr != nil {
+		t.Fatal(sharedGymErr)
+	}
+	return sharedGym
+}
+
+func TestGameStatsHit(t *testing.T) {
+	agent, enemy, base := slotTarget(Point{X: 1}), slotTarget(Point{X: 1}), slotTarget(Point{X: 1}, "base")
+	agent.Attributes, enemy.Attributes, base.Attributes = &Attributes{Team: GYM_AGENT_TEAM}, &Attributes{Team: 1}, &Attributes{Team: GYM_AGENT_TEAM}
+	player := &Player{Unit: agent}
+	game := &Game{players: []*Player{player}}
+	stats, _ := NewGameStats()
+
+	stats.Hit(game, enemy, agent, 30, true)
+	stats.Hit(game, agent, enemy, 20, false)
+	stats.Hit(game, base, enemy, 10, false)
+	stats.Hit(game, base, agent, 5, false) //friendly fire is not dealt damage
+	players, baseDamage := stats.Take()
+	if got := players[player]; got != (PlayerStats{DamageDealt: 30, DamageTaken: 20, Kills: 1}) {
+		t.Errorf("player stats %+v", got)
+	}
+	if baseDamage != 15 {
+		t.Errorf("base damage %d", baseDamage)
+	}
+	if players, baseDamage = stats.Take(); len(players) != 0 || baseDamage != 0 {
+		t.Error("stats not reset on take", players, baseDamage)
+	}
+}
+
+func TestGymSameSeed(t *testing.T) {
+	gym := testGym(t)
+	fire := [][]controller.Command{{{CType: controller.CTYPE_FIRE, Pos: controller.PosIrrelevant, Action: true}}}
+	params, _ := json.Marshal(gymStepParams{Actions: fire, Cycles: 1})
+	script := []string{`{"id":0,"method":"reset","params":{"seed":7}}`}
+	for i := 1; i <= 300; i++ {
+		script = append(script, `{"id":1,"method":"step","params":`+string(params)+`}`)
+	}
+	run := func() []string {
+		var out bytes.Buffer
+		if err := gym.Serve(strings.NewReader(strings.Join(script, "\n")), &out); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(out.String(), "\n")
+	}
+	first, second := run(), run()
+	if len(first) != len(second) {
+		t.Fatalf("responses %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		if strings.Contains(first[i], `"error"`) {
+			t.Fatalf("response %d: %s", i, first[i])
+		}
+		if first[i] != second[i] {
+			t.Fatalf("same seed diverge at response %d", i)
+		}
+	}
+
+	//direct calls see the same
+	obs, err := gym.Reset(7)
+	for i, id := range []string{"0", "1", "1"} {
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, _ := json.Marshal(gymResponse{Version: "2.0", ID: json.RawMessage(id), Result: obs})
+		if string(response) != first[i] {
+			t.Fatalf("direct call diverge at response %d", i)
+		}
+		obs, err = gym.Step(fire, 1)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"errors"
+	"math"
+)
+
+var (
+	HandOffRejectedError = errors.New("hand-off rejected, unit not spawned")
+	NotAllyError         = errors.New("unit is not allied ai")
+)
+
+// HandOffCallback run on game cycle right after control was swapped, previous control is free to be reused
+type HandOffCallback func(previous controller.Controller, err error)
+
+// unitOrigin is unit as it was built, take-over change it and despawn put it back
+type unitOrigin struct {
+	control controller.Controller
+	tags    Tags
+	player  bool
+}
+
+type handOffRequest struct {
+	unit     *Unit
+	control  controller.Controller
+	callback HandOffCallback
+}
+
+// HandOff schedule unit control replacement, done on next SpawnManager cycle.
+// Original unit control, tags and player flag are restored on despawn, so pooled object come back as it was built
+func (manager *SpawnManager) HandOff(unit *Unit, control controller.Controller, callback HandOffCallback) {
+	manager.handOffMutex.Lock()
+	manager.pendingHandOff = append(manager.pendingHandOff, handOffRequest{unit, control, callback})
+	manager.handOffMutex.Unlock()
+}
+
+// executeHandOff process requests one by one, callback may schedule next hand-off within same cycle
+func (manager *SpawnManager) executeHandOff() {
+	for {
+		manager.handOffMutex.Lock()
+		if len(manager.pendingHandOff) == 0 {
+			manager.handOffMutex.Unlock()
+			return
+		}
+		request := manager.pendingHandOff[0]
+		manager.pendingHandOff = manager.pendingHandOff[1:]
+		manager.handOffMutex.Unlock()
+
+		previous, err := manager.handOff(request.unit, request.control)
+		if DEBUG_SPAWN {
+			logger.Printf("cycleId: %d, hand-off unit %d to %T, err: %v \n", CycleID, request.unit.ID, request.control, err)
+		}
+		if request.callback != nil {
+			request.callback(previous, err)
+		}
+	}
+}
+
+func (manager *SpawnManager) handOff(unit *Unit, control controller.Controller) (controller.Controller, error) {
+	if !manager.spawned[unit] || unit.destroyed {
+		return nil, HandOffRejectedError
+	}
+	if _, ok := manager.origin[unit]; !ok {
+		manager.origin[unit] = unitOrigin{
+			control: unit.Control,
+			tags:    *unit.Tags.Copy(),
+			player:  unit.GetAttr().Player,
+		}
+	}
+	return manager.swapControl(unit, control), nil
+}
+
+// swapControl keep ai attribute and updater in sync with control type
+func (manager *SpawnManager) swapControl(unit *Unit, control controller.Controller) controller.Controller {
+	if updated, ok := unit.Control.(Updateable); ok {
+		manager.updater.Remove(updated)
+	}
+	previous := unit.SwapControl(control)
+	_, isAi := control.(*BehaviorControl)
+	unit.GetAttr().AI = isAi
+	if updated, ok := control.(Updateable); ok && !unit.destroyed && manager.spawned[unit] {
+		manager.updater.Add(updated)
+	}
+	return previous
+}
+
+// restoreControl give despawned unit fresh copy of control it was built with, take-over tags and flag are dropped
+func (manager *SpawnManager) restoreControl(unit *Unit) {
+	origin, ok := manager.origin[unit]
+	if !ok {
+		return
+	}
+	delete(manager.origin, unit)
+	*unit.Tags = origin.tags
+	unit.GetAttr().Player = origin.player
+	manager.swapControl(unit, copyControl(origin.control))
+}
+
+// Autopilot let ai drive player tank (afk, accessibility) and return it back
+func (receiver *Game) Autopilot(player *Player, enable bool) {
+	if enable == (player.Autopilot != nil) {
+		return
+	}
+	if enable {
+		if receiver.AiBuilder != nil {
+			player.Autopilot, _ = receiver.AiBuilder.Build()
+		} else {
+			player.Autopilot, _ = controller.NewAIControl()
+		}
+		if player.Unit != nil {
+			receiver.SpawnManager.HandOff(player.Unit, player.Autopilot, nil)
+		}
+	} else {
+		player.Autopilot = nil
+		if player.Unit != nil {
+			receiver.SpawnManager.HandOff(player.Unit, player.Control, nil)
+		}
+	}
+	logger.Printf("cycleId: %d, player %s autopilot %t \n", CycleID, player.Name, enable)
+}
+
+// TakeOver exchange controls: player drive allied ai unit, its ai drive player previous tank
+func (receiver *Game) TakeOver(player *Player, unit *Unit) error {
+	if unit == player.Unit {
+		return nil
+	}
+	if !unit.GetAttr().AI || unit.GetAttr().Player || unit.HasTag("player") || unit.destroyed {
+		return NotAllyError
+	}
+	if player.Unit != nil && unit.GetAttr().Team != player.Unit.GetAttr().Team {
+		return NotAllyError
+	}
+	player.Autopilot = nil
+	old := player.Unit
+	takeOver := func(_ controller.Controller, err error) {
+		if err != nil {
+			logger.Println(err)
+			return
+		}
+		receiver.SpawnManager.HandOff(unit, player.Control, func(ai controller.Controller, err error) {
+			if err != nil {
+				logger.Println(err)
+				//player lost both, keep old unit under player
+				if old != nil {
+					receiver.SpawnManager.HandOff(old, player.Control, nil)
+				}
+				return
+			}
+			unit.removeTag("ai")
+			unit.addTag("player")
+			unit.GetAttr().Player = true
+			player.Unit = unit
+			if old != nil {
+				old.removeTag("player")
+				old.addTag("ai")
+				old.GetAttr().Player = false
+				receiver.SpawnManager.HandOff(old, ai, nil)
+			}
+		})
+	}
+	if old != nil {
+		//release player control first, so one control never feed two units
+		none, _ := controller.NewNoneControl()
+		receiver.SpawnManager.HandOff(old, none, takeOver)
+	} else {
+		takeOver(nil, nil)
+	}
+	return nil
+}
+
+// TakeOverNearest pick closest allied ai unit
+func (receiver *Game) TakeOverNearest(player *Player) error {
+	if player.Unit == nil {
+		return NotAllyError
+	}
+	var (
+		nearest  *Unit
+		distance = math.MaxFloat64
+	)
+	from := player.Unit.GetCenter()
+	for _, object := range receiver.SpawnManager.QuerySpawnedByTag(player.Unit.GetAttr().TeamTag) {
+		unit, ok := object.(*Unit)
+		if !ok || unit == player.Unit || !unit.GetAttr().AI || unit.GetAttr().Player || unit.destroyed {
+			continue
+		}
+		to := unit.GetCenter()
+		if d := getDistance(from.X, from.Y, to.X, to.Y); d < distance {
+			nearest, distance = unit, d
+		}
+	}
+	if nearest == nil {
+		return NotAllyError
+	}
+	return receiver.TakeOver(player, nearest)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"GoConsoleBT/collider"
+	"GoConsoleBT/controller"
+	"testing"
+)
+
+func TestTakeOverRespawnAsAi(t *testing.T) {
+	testGym(t) //blueprints
+	updater, _ := NewUpdater(10)
+	cl, _ := collider.NewCollider(10)
+	visioner, _ := NewVisioner(cl, 10)
+	spawner, _ := NewSpawner(updater, NullRender{}, cl, nil, visioner, gameConfig)
+	spawner.Flags.lockFree = true
+	tank, err := buildManager.CreateBuilder("tank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(EventChanel, 100) //unit events must not reach gym game
+	spawner.AddBuilder("tank", func() interface{} {
+		unit := tank().(*Unit)
+		unit.ObservableObject.output = events
+		return unit
+	})
+	spawn := func() *Unit {
+		object, err := spawner.Spawn(Point{X: 1, Y: 1}, "tank", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spawner.Execute(0)
+		return object.(*Unit)
+	}
+	unit := spawn()
+	if !unit.HasTag("ai") || !unit.GetAttr().AI {
+		t.Fatal("tank blueprint is not ai")
+	}
+	game := &Game{SpawnManager: spawner}
+	control, _ := controller.NewNoneControl()
+	player := &Player{Name: "player", Control: control}
+	if err := game.TakeOver(player, unit); err != nil {
+		t.Fatal(err)
+	}
+	spawner.Execute(0)
+	if player.Unit != unit || !unit.HasTag("player") || !unit.GetAttr().Player {
+		t.Fatal("unit not taken over")
+	}
+
+	spawner.DeSpawn(unit)
+	spawner.Execute(0)
+	if respawned := spawn(); respawned != unit {
+		t.Fatal("unit not reused from pool")
+	}
+	if unit.HasTag("player") || !unit.HasTag("ai") || unit.GetAttr().Player || !unit.GetAttr().AI {
+		t.Error("pooled unit came back as player")
+	}
+}
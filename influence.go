@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	direct "github.com/buger/goterm"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	INFLUENCE_LANE_RANGE      = 10  //zones covered by tank gun
+	INFLUENCE_TRACE_DECAY     = 0.8 //projectile trace left after cycle
+	INFLUENCE_REBUILD_CYCLES  = 20  //full recalculation, catch destroyed walls and float drift
+	INFLUENCE_COVERAGE_WEIGHT = 0.25
+	INFLUENCE_NOISE           = 0.05 //less is no influence at all
+)
+
+// influenceLayer is map for one team: threat from enemies, coverage of friends and enemy projectile traces
+type influenceLayer struct {
+	team                    int8
+	threat, coverage, trace [][]float64
+}
+
+func (receiver *influenceLayer) danger(zone Zone) float64 {
+	return receiver.threat[zone.Y][zone.X] + receiver.trace[zone.Y][zone.X] -
+		receiver.coverage[zone.Y][zone.X]*INFLUENCE_COVERAGE_WEIGHT
+}
+
+// influenceSource cache lanes of one tank, recalculated only when it move or turn
+type influenceSource struct {
+	team    int8
+	zone    Zone
+	dir     Point
+	cells   []Zone
+	weights []float64
+	seen    bool
+}
+
+// InfluenceMap keep per team threat and influence over Location zones, updated every cycle
+type InfluenceMap struct {
+	*Location
+	Lockstep bool //sum sources in id order, repeatable gym runs
+	spawner  *SpawnManager
+	size     Zone
+	layers   map[int8]*influenceLayer
+	sources  map[*Unit]*influenceSource
+	blocked  [][]bool
+	cycle    int64
+	mutex    sync.RWMutex
+}
+
+func (receiver *InfluenceMap) Update(timeLeft time.Duration) error {
+	tanks := receiver.spawner.QuerySpawnedByTag("tank")
+	projectiles := receiver.spawner.QuerySpawnedByTag("projectile")
+	blocked := receiver.Location.BlockMap()
+
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.blocked = blocked
+	receiver.cycle++
+	full := receiver.cycle%INFLUENCE_REBUILD_CYCLES == 0
+	if full {
+		for _, layer := range receiver.layers {
+			receiver.reset(layer.threat)
+			receiver.reset(layer.coverage)
+		}
+	}
+	for _, source := range receiver.sources {
+		source.seen = false
+	}
+
+	for _, object := range tanks {
+		unit, ok := object.(*Unit)
+		if !ok || unit.destroyed {
+			continue
+		}
+		team, zone, dir := unit.GetAttr().Team, unit.GetZone(), unit.Direction
+		receiver.layer(team)
+		source, ok := receiver.sources[unit]
+		if !ok {
+			source = new(influenceSource)
+			receiver.sources[unit] = source
+		} else if !full && source.team == team && source.zone == zone && source.dir == dir {
+			source.seen = true
+			continue
+		} else if !full {
+			receiver.apply(source, -1)
+		}
+		source.team, source.zone, source.dir, source.seen = team, zone, dir, true
+		receiver.cast(source)
+		receiver.apply(source, 1)
+	}
+	for _, unit := range receiver.units() {
+		if source := receiver.sources[unit]; !source.seen {
+			if !full {
+				receiver.apply(source, -1)
+			}
+			delete(receiver.sources, unit)
+		}
+	}
+
+	for _, layer := range receiver.layers {
+		for _, row := range layer.trace {
+			for x := range row {
+				if row[x] *= INFLUENCE_TRACE_DECAY; row[x] < INFLUENCE_NOISE {
+					row[x] = 0
+				}
+			}
+		}
+	}
+	for _, object := range projectiles {
+		projectile, ok := object.(*Projectile)
+		if !ok || projectile.Owner == nil {
+			continue
+		}
+		team := projectile.Owner.GetAttr().Team
+		zone := receiver.NearestZoneByCoordinate(Point(projectile.GetCenter()))
+		for _, layer := range receiver.layers {
+			if layer.team == team {
+				continue
+			}
+			for step, weight := range []float64{1, 0.5, 0.25} { //where it fly next
+				next := Zone{X: zone.X + int(projectile.Direction.X)*step, Y: zone.Y + int(projectile.Direction.Y)*step}
+				if !receiver.inside(next) {
+					break
+				}
+				layer.trace[next.Y][next.X] += weight
+			}
+		}
+	}
+	return nil
+}
+
+// cast tank proximity and its line of fire until first obstacle
+func (receiver *InfluenceMap) cast(source *influenceSource) {
+	source.cells, source.weights = source.cells[0:0], source.weights[0:0]
+	add := func(zone Zone, weight float64) {
+		if receiver.inside(zone) {
+			source.cells = append(source.cells, zone)
+			source.weights = append(source.weights, weight)
+		}
+	}
+	add(source.zone, 1)
+	for _, dir := range []Zone{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+		add(Zone{X: source.zone.X + dir.X, Y: source.zone.Y + dir.Y}, 0.5)
+	}
+	dx, dy := int(source.dir.X), int(source.dir.Y)
+	if dx == 0 && dy == 0 {
+		return
+	}
+	for distance := 1; distance <= INFLUENCE_LANE_RANGE; distance++ {
+		zone := Zone{X: source.zone.X + dx*distance, Y: source.zone.Y + dy*distance}
+		if !receiver.inside(zone) || receiver.blocked[zone.Y][zone.X] {
+			break
+		}
+		add(zone, 1-0.5*float64(distance)/INFLUENCE_LANE_RANGE)
+	}
+}
+
+func (receiver *InfluenceMap) apply(source *influenceSource, sign float64) {
+	for _, layer := range receiver.layers {
+		grid := layer.threat
+		if layer.team == source.team {
+			grid = layer.coverage
+		}
+		for i, zone := range source.cells {
+			grid[zone.Y][zone.X] += sign * source.weights[i]
+		}
+	}
+}
+
+// layer of team, new layer get influence of all known tanks
+func (receiver *InfluenceMap) layer(team int8) *influenceLayer {
+	if layer, ok := receiver.layers[team]; ok {
+		return layer
+	}
+	layer := &influenceLayer{
+		team:     team,
+		threat:   receiver.grid(),
+		coverage: receiver.grid(),
+		trace:    receiver.grid(),
+	}
+	receiver.layers[team] = layer
+	for _, unit := range receiver.units() {
+		source := receiver.sources[unit]
+		grid := layer.threat
+		if layer.team == source.team {
+			grid = layer.coverage
+		}
+		for i, zone := range source.cells {
+			grid[zone.Y][zone.X] += source.weights[i]
+		}
+	}
+	return layer
+}
+
+// units of sources, in lockstep by id, so float sums don't depend on map order
+func (receiver *InfluenceMap) units() []*Unit {
+	units := make([]*Unit, 0, len(receiver.sources))
+	for unit := range receiver.sources {
+		units = append(units, unit)
+	}
+	if !receiver.Lockstep {
+		return units
+	}
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].ID < units[j].ID
+	})
+	return units
+}
+
+func (receiver *InfluenceMap) grid() [][]float64 {
+	grid := make([][]float64, receiver.size.Y)
+	for y := range grid {
+		grid[y] = make([]float64, receiver.size.X)
+	}
+	return grid
+}
+
+func (receiver *InfluenceMap) reset(grid [][]float64) {
+	for _, row := range grid {
+		for x := range row {
+			row[x] = 0
+		}
+	}
+}
+
+func (receiver *InfluenceMap) inside(zone Zone) bool {
+	return zone.X >= 0 && zone.Y >= 0 && zone.X < receiver.size.X && zone.Y < receiver.size.Y
+}
+
+// Threat of enemies and their projectiles in zone for team
+func (receiver *InfluenceMap) Threat(team int8, zone Zone) float64 {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	layer, ok := receiver.layers[team]
+	if !ok || !receiver.inside(zone) {
+		return 0
+	}
+	return layer.threat[zone.Y][zone.X] + layer.trace[zone.Y][zone.X]
+}
+
+// Coverage by friendly guns in zone
+func (receiver *InfluenceMap) Coverage(team int8, zone Zone) float64 {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	layer, ok := receiver.layers[team]
+	if !ok || !receiver.inside(zone) {
+		return 0
+	}
+	return layer.coverage[zone.Y][zone.X]
+}
+
+// SafestZone reachable in radius steps from zone, closer wins on equal danger
+func (receiver *InfluenceMap) SafestZone(team int8, from Zone, radius int) (Zone, bool) {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	layer, ok := receiver.layers[team]
+	if !ok || !receiver.inside(from) {
+		return NoZone, false
+	}
+	best, bestDanger := from, layer.danger(from)
+	receiver.reachable(from, radius, func(zone Zone, steps int) {
+		if danger := layer.danger(zone); danger < bestDanger {
+			best, bestDanger = zone, danger
+		}
+	})
+	return best, true
+}
+
+// BestLineZone reachable zone with clear line of fire on target and least danger
+func (receiver *InfluenceMap) BestLineZone(team int8, from Zone, target Zone, radius int) (Zone, bool) {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	layer, ok := receiver.layers[team]
+	if !ok || !receiver.inside(from) || !receiver.inside(target) {
+		return NoZone, false
+	}
+	best, bestScore, found := NoZone, 0.0, false
+	check := func(zone Zone, steps int) {
+		if zone == target || !receiver.clearLine(zone, target) {
+			return
+		}
+		if score := layer.danger(zone) + 0.1*float64(steps); !found || score < bestScore {
+			best, bestScore, found = zone, score, true
+		}
+	}
+	check(from, 0)
+	receiver.reachable(from, radius, check)
+	return best, found
+}
+
+// reachable breadth first walk over free zones, from itself is not visited
+func (receiver *InfluenceMap) reachable(from Zone, radius int, visit func(zone Zone, steps int)) {
+	visited := map[Zone]bool{from: true}
+	front := []Zone{from}
+	for steps := 1; steps <= radius && len(front) > 0; steps++ {
+		next := make([]Zone, 0, len(front)*2)
+		for _, zone := range front {
+			for _, dir := range []Zone{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				candidate := Zone{X: zone.X + dir.X, Y: zone.Y + dir.Y}
+				if !receiver.inside(candidate) || visited[candidate] || receiver.blocked[candidate.Y][candidate.X] {
+					continue
+				}
+				visited[candidate] = true
+				visit(candidate, steps)
+				next = append(next, candidate)
+			}
+		}
+		front = next
+	}
+}
+
+// clearLine same row or column without obstacles between
+func (receiver *InfluenceMap) clearLine(from Zone, to Zone) bool {
+	if from.X != to.X && from.Y != to.Y {
+		return false
+	}
+	dx, dy := sign(to.X-from.X), sign(to.Y-from.Y)
+	for zone := (Zone{X: from.X + dx, Y: from.Y + dy}); zone != to; zone = (Zone{X: zone.X + dx, Y: zone.Y + dy}) {
+		if receiver.blocked[zone.Y][zone.X] {
+			return false
+		}
+	}
+	return true
+}
+
+func sign(value int) int {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	}
+	return 0
+}
+
+func NewInfluenceMap(location *Location, spawner *SpawnManager) (*InfluenceMap, error) {
+	return &InfluenceMap{
+		Location: location,
+		spawner:  spawner,
+		size:     location.ZoneSize(),
+		layers:   make(map[int8]*influenceLayer),
+		sources:  make(map[*Unit]*influenceSource),
+		blocked:  location.BlockMap(),
+	}, nil
+}
+
+// InfluenceOverlay debug view of team layer: red is threat (darker more), yellow projectile trace, green friendly coverage
+type InfluenceOverlay struct {
+	*InfluenceMap
+	Team    int8
+	markers [][]*overlayMarker
+}
+
+func (receiver *InfluenceOverlay) Update(timeLeft time.Duration) error {
+	receiver.mutex.RLock()
+	defer receiver.mutex.RUnlock()
+	layer := receiver.layers[receiver.Team]
+	for y, row := range receiver.markers {
+		for x, marker := range row {
+			marker.Buf.Reset()
+			if layer == nil {
+				continue
+			}
+			threat, trace, coverage := layer.threat[y][x], layer.trace[y][x], layer.coverage[y][x]
+			switch {
+			case trace > threat && trace > INFLUENCE_NOISE:
+				fmt.Fprint(marker.Buf, direct.Color("*", direct.YELLOW))
+			case threat >= 2:
+				fmt.Fprint(marker.Buf, direct.Color("▓", direct.RED))
+			case threat >= 1:
+				fmt.Fprint(marker.Buf, direct.Color("▒", direct.RED))
+			case threat > INFLUENCE_NOISE:
+				fmt.Fprint(marker.Buf, direct.Color("░", direct.RED))
+			case coverage > INFLUENCE_NOISE:
+				fmt.Fprint(marker.Buf, direct.Color("·", direct.GREEN))
+			}
+		}
+	}
+	return nil
+}
+
+func NewInfluenceOverlay(influence *InfluenceMap, team int8, render Renderer) (*InfluenceOverlay, error) {
+	instance := &InfluenceOverlay{
+		InfluenceMap: influence,
+		Team:         team,
+		markers:      make([][]*overlayMarker, influence.size.Y),
+	}
+	for y := range instance.markers {
+		instance.markers[y] = make([]*overlayMarker, influence.size.X)
+		for x := range instance.markers[y] {
+			center, err := influence.CenterByIndex(x, y)
+			if err != nil {
+				return nil, err
+			}
+			marker := &overlayMarker{Point: Point(center), Sprite: NewSprite(), zIndex: 1} //under units
+			marker.Size.W, marker.Size.H = 1, 1
+			instance.markers[y][x] = marker
+			render.Add(marker)
+		}
+	}
+	return instance, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func testInfluenceMap(t *testing.T) *InfluenceMap {
+	location, _ := NewLocation(Point{}, Size{W: 100, H: 50})
+	location.SetupZones(Point{X: 10, Y: 5})
+	influence, err := NewInfluenceMap(location, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return influence
+}
+
+func TestInfluenceLane(t *testing.T) {
+	influence := testInfluenceMap(t)
+	influence.layer(0)
+	influence.blocked[5][5] = true
+	enemy := &influenceSource{team: 1, zone: Zone{X: 0, Y: 5}, dir: Point{X: 1}}
+	influence.cast(enemy)
+	influence.apply(enemy, 1)
+
+	if threat := influence.Threat(0, Zone{X: 3, Y: 5}); threat <= 0 {
+		t.Errorf("no threat on lane, got %f", threat)
+	}
+	if threat := influence.Threat(0, Zone{X: 7, Y: 5}); threat != 0 {
+		t.Errorf("lane pass obstacle, got %f", threat)
+	}
+	if safe, ok := influence.SafestZone(0, Zone{X: 3, Y: 5}, 3); !ok || safe.Y == 5 {
+		t.Errorf("safest zone %v is on the lane", safe)
+	}
+
+	influence.apply(enemy, -1)
+	if threat := influence.Threat(0, Zone{X: 3, Y: 5}); threat != 0 {
+		t.Errorf("threat left after source removed, got %f", threat)
+	}
+}
+
+func TestInfluenceBestLine(t *testing.T) {
+	influence := testInfluenceMap(t)
+	influence.layer(0)
+	influence.blocked[2][4] = true
+	zone, ok := influence.BestLineZone(0, Zone{X: 4, Y: 0}, Zone{X: 4, Y: 6}, 6)
+	if !ok {
+		t.Fatal("no line zone found")
+	}
+	if (zone.X != 4 && zone.Y != 6) || !influence.clearLine(zone, Zone{X: 4, Y: 6}) {
+		t.Errorf("zone %v has no line on target", zone)
+	}
+}
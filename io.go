@@ -10,6 +10,8 @@ import (
 const spritePath = "./sprite/"
 const statePath = "./state/"
 const scenarioPath = "./scenario/"
+const scriptPath = "./script/"
+const treePath = "./ai/"
 
 func loadSprite(filename string) ([]byte, error) {
 	return os.ReadFile(spritePath + filename)
@@ -23,6 +25,31 @@ func loadScenario(filename string) ([]byte, error) {
 	return os.ReadFile(scenarioPath + filename + ".json")
 }
 
+func loadTree(filename string) ([]byte, error) {
+	return os.ReadFile(treePath + filename + ".json")
+}
+
+// loadScript look in script dir first, then treat filename as path
+func loadScript(filename string) ([]byte, error) {
+	payload, err := os.ReadFile(scriptPath + filename)
+	if err != nil {
+		return os.ReadFile(filename)
+	}
+	return payload, nil
+}
+
+func loadScriptControl(filename string, isPlayer bool) (*controller.Control, error) {
+	payload, err := loadScript(filename)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := controller.ParseScript(string(payload))
+	if err != nil {
+		return nil, err
+	}
+	return controller.NewScriptControl(steps, isPlayer)
+}
+
 func saveConfig(config *GameConfig) (int, error) {
 	payload, err := json.Marshal(config)
 	if err != nil {
@@ -38,16 +65,18 @@ func loadConfig() (*GameConfig, error) {
 	}
 	config := new(GameConfig)
 	err = json.Unmarshal(payload, config)
-	kb, dType, _, _ := jsonparser.Get(payload, "keyBindings")
-	switch dType {
-	case jsonparser.Array:
-		idx := 0
-		jsonparser.ArrayEach(kb, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
-			bind := &controller.KeyBind{}
-			json.Unmarshal(value, bind)
-			config.KeyBindings[idx] = *bind
-			idx++
-		})
+	if _, _, _, inputErr := jsonparser.Get(payload, "input"); inputErr != nil {
+		config.Input.HoldConfig = controller.DefaultHoldConfig //old config
+	}
+	for idx, defaults := range controller.KeyboardBindingPool {
+		if idx < len(config.KeyBindings) {
+			if config.KeyBindings[idx] == nil {
+				config.KeyBindings[idx] = controller.KeyBind{}
+			}
+			config.KeyBindings[idx].Merge(defaults) //old config, missing actions
+		} else {
+			config.KeyBindings = append(config.KeyBindings, defaults.Copy())
+		}
 	}
 	return config, err
 }
@@ -14,6 +14,7 @@ type GameConfig struct {
 	RowHeight            float64              `json:"rowHeight"`
 	LockfreePool         bool                 `json:"lockfreePool"`
 	KeyBindings          []controller.KeyBind `json:"keyBindings"`
+	Input                InputConfig          `json:"input"`
 	Box                  Box                  `json:"box"`
 	disableCustomization bool
 }
@@ -24,8 +25,11 @@ func NewDefaultGameConfig() (*GameConfig, error) {
 		RowHeight:    1,
 		LockfreePool: true,
 		KeyBindings: []controller.KeyBind{
-			controller.Player1DefaultKeyBinding,
-			controller.Player2DefaultKeyBinding,
+			controller.Player1DefaultKeyBinding.Copy(),
+			controller.Player2DefaultKeyBinding.Copy(),
+		},
+		Input: InputConfig{
+			HoldConfig: controller.DefaultHoldConfig,
 		},
 		Box: Box{
 			Point{
@@ -40,6 +44,13 @@ func NewDefaultGameConfig() (*GameConfig, error) {
 	}, nil
 }
 
+type InputConfig struct {
+	controller.HoldConfig
+	KittyProtocol bool `json:"kittyProtocol"`
+	Mouse         bool `json:"mouse"`         //sgr mouse tracking: click menus, calibration
+	MouseWaypoint bool `json:"mouseWaypoint"` //click on map drive player tank there
+}
+
 type AnimationConfig struct {
 	SpriteerConfig
 	Duration       time.Duration          `json:"duration"`
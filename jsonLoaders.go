@@ -704,7 +704,21 @@ func ControlledObjectLoader(ctx context.Context, get LoaderGetter, collector *Lo
 		object *ControlledObject
 	)
 
-	if obj, err := lGetObject(ctx, "ai", get, collector, preset, payload); !collector.Add(err) {
+	if script, err := jsonparser.GetString(payload, "control", "script"); err == nil {
+		if control, err := loadScriptControl(script, false); !collector.Add(err) {
+			object, _ = NewControlledObject(control, nil)
+		}
+	} else if macro, err := jsonparser.GetString(payload, "control", "macro"); err == nil {
+		steps, err := controller.ParseScript(macro)
+		if !collector.Add(err) {
+			control, _ := controller.NewScriptControl(steps, false)
+			object, _ = NewControlledObject(control, nil)
+		}
+	} else if _, err := jsonparser.GetString(payload, "control", "bot"); err == nil {
+		if obj, err := lGetObject(ctx, "bot", get, collector, preset, payload); !collector.Add(err) {
+			object, _ = NewControlledObject(obj.(controller.Controller), nil)
+		}
+	} else if obj, err := lGetObject(ctx, "ai", get, collector, preset, payload); !collector.Add(err) {
 		object, _ = NewControlledObject(obj.(controller.Controller), nil)
 	} else {
 		collector.Add(fmt.Errorf("%s: %w", "ai", LoaderNotFoundError))
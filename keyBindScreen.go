@@ -0,0 +1,161 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"fmt"
+	"github.com/eiannone/keyboard"
+	"strings"
+)
+
+const OVERLAY_SCREEN_ZINDEX = 1000
+
+const keyBindScreenWidth = 52
+
+// KeyBindScreen is in game rebinding menu, it is driven by runner keyboard and work on copy of bindings
+type KeyBindScreen struct {
+	*Screen
+	bindings           []controller.KeyBind
+	player, cursor     int
+	capture, appendKey bool
+}
+
+func (receiver *KeyBindScreen) GetZIndex() int {
+	return OVERLAY_SCREEN_ZINDEX
+}
+
+func (receiver *KeyBindScreen) Bindings() []controller.KeyBind {
+	return receiver.bindings
+}
+
+// HandleKey process single key event, return true if screen must be closed
+func (receiver *KeyBindScreen) HandleKey(event keyboard.KeyEvent) (done bool) {
+	key := controller.EventKey(event)
+	if mouse, ok := controller.AsMouse(event); ok { //wheel scroll actions, mouse is never bound
+		switch {
+		case receiver.capture || mouse.Action != controller.MOUSE_PRESS:
+			return false
+		case mouse.Button == controller.MOUSE_WHEEL_UP:
+			key = keyboard.KeyArrowUp
+		case mouse.Button == controller.MOUSE_WHEEL_DOWN:
+			key = keyboard.KeyArrowDown
+		default:
+			return false
+		}
+	}
+	bind := receiver.bindings[receiver.player]
+	action := controller.Actions[receiver.cursor]
+
+	if receiver.capture {
+		receiver.capture = false
+		if key != keyboard.KeyEsc {
+			code := controller.KeyCode(key)
+			for _, other := range receiver.bindings {
+				other.Unbind(code) //players share one keyboard
+			}
+			if !receiver.appendKey {
+				bind[action] = []controller.KeyCode{}
+			}
+			bind[action] = append(bind[action], code)
+		}
+		receiver.redraw()
+		return false
+	}
+
+	switch key {
+	case keyboard.KeyArrowUp:
+		receiver.cursor = (receiver.cursor + len(controller.Actions) - 1) % len(controller.Actions)
+	case keyboard.KeyArrowDown:
+		receiver.cursor = (receiver.cursor + 1) % len(controller.Actions)
+	case keyboard.KeyTab:
+		receiver.player = (receiver.player + 1) % len(receiver.bindings)
+	case keyboard.KeyEnter:
+		receiver.capture, receiver.appendKey = true, false
+	case keyboard.KeySpace:
+		receiver.capture, receiver.appendKey = true, true
+	case keyboard.KeyBackspace, keyboard.KeyDelete:
+		bind[action] = []controller.KeyCode{}
+	case keyboard.KeyEsc:
+		return true
+	}
+	receiver.redraw()
+	return false
+}
+
+func (receiver *KeyBindScreen) redraw() {
+	lines := make([]string, 0, len(controller.Actions)+8)
+	lines = append(lines, fmt.Sprintf("KEY BINDINGS   < Player%d >", receiver.player+1), "")
+	bind := receiver.bindings[receiver.player]
+	for i, action := range controller.Actions {
+		cursor, keys := "  ", bind.Describe(action)
+		if i == receiver.cursor {
+			cursor = "> "
+			if receiver.capture {
+				keys = "press key... (esc cancel)"
+			}
+		}
+		if keys == "" {
+			keys = "-"
+		}
+		lines = append(lines, fmt.Sprintf("%s%-10s %s", cursor, action, keys))
+	}
+	lines = append(lines, "",
+		"enter: rebind   space: add key   backspace: clear",
+		"tab: next player   esc: save and close")
+
+	var buf strings.Builder
+	border := strings.Repeat("#", keyBindScreenWidth)
+	buf.WriteString(border)
+	for _, line := range lines {
+		if len(line) > keyBindScreenWidth-4 {
+			line = line[:keyBindScreenWidth-4]
+		}
+		buf.WriteString("\n# " + line + strings.Repeat(" ", keyBindScreenWidth-4-len(line)) + " #")
+	}
+	buf.WriteString("\n" + border)
+
+	sprite := NewContentSprite([]byte(buf.String()))
+	receiver.size = Point{X: float64(sprite.Size.W), Y: float64(sprite.Size.H)}
+	receiver.sprite = sprite
+}
+
+func NewKeyBindScreen(bindings []controller.KeyBind) (*KeyBindScreen, error) {
+	copies := make([]controller.KeyBind, 0, len(bindings))
+	for _, bind := range bindings {
+		copies = append(copies, bind.Copy())
+	}
+	if len(copies) == 0 {
+		copies = append(copies, controller.Player1DefaultKeyBinding.Copy())
+	}
+	screen, _ := NewScreen(nil)
+	instance := &KeyBindScreen{
+		Screen:   screen,
+		bindings: copies,
+	}
+	instance.redraw()
+	return instance, nil
+}
+
+// OverlayScreen is simple screen drawn above the game, eg pause
+type OverlayScreen struct {
+	*Screen
+}
+
+func (receiver *OverlayScreen) GetZIndex() int {
+	return OVERLAY_SCREEN_ZINDEX
+}
+
+// NewMessageScreen show one line framed message
+func NewMessageScreen(message string) (*OverlayScreen, error) {
+	border := strings.Repeat("#", len(message)+6)
+	sprite := NewContentSprite([]byte(border + "\n#  " + message + "  #\n" + border))
+	screen, _ := NewScreen(sprite)
+	screen.size = Point{X: float64(sprite.Size.W), Y: float64(sprite.Size.H)}
+	return &OverlayScreen{Screen: screen}, nil
+}
+
+func NewPauseScreen() (*OverlayScreen, error) {
+	sprite := NewContentSprite([]byte("##############\n#   PAUSED   #\n##############"))
+	screen, _ := NewScreen(sprite)
+	screen.size = Point{X: float64(sprite.Size.W), Y: float64(sprite.Size.H)}
+	return &OverlayScreen{Screen: screen}, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"github.com/eiannone/keyboard"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+const (
+	kittyEventRelease = "3"
+	kittyShift        = 1
+	kittyCtrl         = 4
+
+	sgrMotion = 32
+	sgrWheel  = 64
+
+	mouseEnable  = "\033[?1000h\033[?1006h" //button press and release, sgr encoding
+	mouseDisable = "\033[?1006l\033[?1000l"
+)
+
+var (
+	kittyCsiKeys = map[byte]keyboard.Key{
+		'A': keyboard.KeyArrowUp, 'B': keyboard.KeyArrowDown, 'C': keyboard.KeyArrowRight, 'D': keyboard.KeyArrowLeft,
+		'H': keyboard.KeyHome, 'F': keyboard.KeyEnd,
+		'P': keyboard.KeyF1, 'Q': keyboard.KeyF2, 'R': keyboard.KeyF3, 'S': keyboard.KeyF4,
+	}
+	kittyTildeKeys = map[int]keyboard.Key{
+		2: keyboard.KeyInsert, 3: keyboard.KeyDelete, 5: keyboard.KeyPgup, 6: keyboard.KeyPgdn,
+		7: keyboard.KeyHome, 8: keyboard.KeyEnd, 13: keyboard.KeyF3,
+		15: keyboard.KeyF5, 17: keyboard.KeyF6, 18: keyboard.KeyF7, 19: keyboard.KeyF8,
+		20: keyboard.KeyF9, 21: keyboard.KeyF10, 23: keyboard.KeyF11, 24: keyboard.KeyF12,
+	}
+)
+
+// KeyParser turn raw terminal input (legacy and kitty sequences) into key events,
+// source may be local tty or remote session
+type KeyParser struct {
+	events    chan keyboard.KeyEvent
+	Supported int32 //terminal answer on protocol query
+}
+
+// Feed parse input chunk, incomplete sequence is kept till next one
+func (receiver *KeyParser) Feed(pending, input []byte) []byte {
+	return receiver.parse(append(pending, input...), false)
+}
+
+// Flush called when no input for a while, lonely esc become key
+func (receiver *KeyParser) Flush(pending []byte) []byte {
+	if len(pending) == 0 {
+		return pending
+	}
+	return receiver.parse(pending, true)
+}
+
+func (receiver *KeyParser) Events() <-chan keyboard.KeyEvent {
+	return receiver.events
+}
+
+// parse emit all complete events and return rest of input
+func (receiver *KeyParser) parse(input []byte, flush bool) []byte {
+	for len(input) > 0 {
+		size, event, ok := receiver.extract(input, flush)
+		if size == 0 {
+			break
+		}
+		input = input[size:]
+		if ok {
+			receiver.events <- event
+		}
+	}
+	return append(input[0:0], input...)
+}
+
+func (receiver *KeyParser) extract(input []byte, flush bool) (size int, event keyboard.KeyEvent, ok bool) {
+	if input[0] != '\033' {
+		if input[0] < 0x20 || input[0] == 0x7F {
+			return 1, keyboard.KeyEvent{Key: keyboard.Key(input[0])}, true
+		}
+		if !utf8.FullRune(input) && !flush {
+			return 0, event, false
+		}
+		r, n := utf8.DecodeRune(input)
+		return n, keyboard.KeyEvent{Rune: r}, true
+	}
+	if len(input) == 1 {
+		if flush {
+			return 1, keyboard.KeyEvent{Key: keyboard.KeyEsc}, true
+		}
+		return 0, event, false
+	}
+	switch input[1] {
+	case '[':
+		for i := 2; i < len(input); i++ {
+			if input[i] >= 0x40 && input[i] <= 0x7E {
+				event, ok = receiver.csi(string(input[2:i]), input[i])
+				return i + 1, event, ok
+			}
+		}
+		if flush {
+			return len(input), event, false
+		}
+		return 0, event, false
+	case 'O':
+		if len(input) < 3 {
+			if flush {
+				return len(input), event, false
+			}
+			return 0, event, false
+		}
+		key, ok := kittyCsiKeys[input[2]]
+		return 3, keyboard.KeyEvent{Key: key}, ok
+	default:
+		return 1, keyboard.KeyEvent{}, false //alt prefix, key itself follow
+	}
+}
+
+// csi decode "CSI params final", params are "code[:alternates];modifiers[:event][;text]"
+func (receiver *KeyParser) csi(params string, final byte) (event keyboard.KeyEvent, ok bool) {
+	if strings.HasPrefix(params, "?") && final == 'u' {
+		atomic.StoreInt32(&receiver.Supported, 1)
+		return event, false
+	}
+	if strings.HasPrefix(params, "<") && (final == 'M' || final == 'm') {
+		return receiver.mouse(params[1:], final == 'm')
+	}
+	fields := strings.Split(params, ";")
+	codes := strings.Split(fields[0], ":")
+	code, _ := strconv.Atoi(codes[0])
+	modifiers, eventType := 0, ""
+	if len(fields) > 1 {
+		mod := strings.Split(fields[1], ":")
+		if m, err := strconv.Atoi(mod[0]); err == nil {
+			modifiers = m - 1
+		}
+		if len(mod) > 1 {
+			eventType = mod[1]
+		}
+	}
+	if eventType == kittyEventRelease {
+		event.Err = controller.KeyReleaseError
+	}
+
+	switch final {
+	case 'u':
+		switch {
+		case code == 13 || code == 9 || code == 27 || code == 32 || code == 127:
+			event.Key = keyboard.Key(code)
+		case code >= 57344: //keypad, media and modifier keys
+			return event, false
+		case code >= 'a' && code <= 'z' && modifiers&kittyCtrl != 0:
+			event.Key = keyboard.KeyCtrlA + keyboard.Key(code-'a')
+		case modifiers&kittyShift != 0 && len(codes) > 1 && codes[1] != "":
+			shifted, _ := strconv.Atoi(codes[1])
+			event.Rune = rune(shifted)
+		case code >= 'a' && code <= 'z' && modifiers&kittyShift != 0:
+			event.Rune = rune(code - 'a' + 'A')
+		default:
+			event.Rune = rune(code)
+		}
+		return event, true
+	case '~':
+		event.Key, ok = kittyTildeKeys[code]
+		return event, ok
+	default:
+		event.Key, ok = kittyCsiKeys[final]
+		return event, ok
+	}
+}
+
+// mouse decode SGR mouse report "button;x;y", coordinates are one based
+func (receiver *KeyParser) mouse(params string, release bool) (event keyboard.KeyEvent, ok bool) {
+	fields := strings.Split(params, ";")
+	if len(fields) != 3 {
+		return event, false
+	}
+	code, err1 := strconv.Atoi(fields[0])
+	x, err2 := strconv.Atoi(fields[1])
+	y, err3 := strconv.Atoi(fields[2])
+	if err1 != nil || err2 != nil || err3 != nil || code&sgrMotion != 0 {
+		return event, false
+	}
+	mouse := controller.MouseEvent{X: x - 1, Y: y - 1, Action: controller.MOUSE_PRESS}
+	if release {
+		mouse.Action = controller.MOUSE_RELEASE
+	}
+	switch button := code & 3; {
+	case code&sgrWheel != 0 && button == 0:
+		mouse.Button = controller.MOUSE_WHEEL_UP
+	case code&sgrWheel != 0 && button == 1:
+		mouse.Button = controller.MOUSE_WHEEL_DOWN
+	case code&sgrWheel != 0:
+		return event, false
+	default:
+		mouse.Button = button
+	}
+	return controller.NewMouseKeyEvent(mouse), true
+}
+
+func NewKeyParser(bufferSize int) (*KeyParser, error) {
+	return &KeyParser{
+		events: make(chan keyboard.KeyEvent, bufferSize),
+	}, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"github.com/eiannone/keyboard"
+	"testing"
+)
+
+// drain events parsed so far
+func drain(parser *KeyParser) []keyboard.KeyEvent {
+	var events []keyboard.KeyEvent
+	for {
+		select {
+		case event := <-parser.Events():
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestKeyParserKitty(t *testing.T) {
+	cases := []struct {
+		input   string
+		key     keyboard.Key
+		char    rune
+		release bool
+	}{
+		{input: "w", char: 'w'},
+		{input: "\033[A", key: keyboard.KeyArrowUp},
+		{input: "\033OD", key: keyboard.KeyArrowLeft},
+		{input: "\033[1;1:1A", key: keyboard.KeyArrowUp},
+		{input: "\033[1;1:2B", key: keyboard.KeyArrowDown},
+		{input: "\033[1;1:3C", key: keyboard.KeyArrowRight, release: true},
+		{input: "\033[97u", char: 'a'},
+		{input: "\033[97;1:3u", char: 'a', release: true},
+		{input: "\033[97;2u", char: 'A'},
+		{input: "\033[97:65;2u", char: 'A'},
+		{input: "\033[99;5u", key: keyboard.KeyCtrlC},
+		{input: "\033[13u", key: keyboard.KeyEnter},
+		{input: "\033[27;1:3u", key: keyboard.KeyEsc, release: true},
+		{input: "\033[3~", key: keyboard.KeyDelete},
+		{input: "\033[20;1:3~", key: keyboard.KeyF9, release: true},
+		{input: "\033[57441;2u"}, //left shift alone, skipped
+		{input: "\033[<0;5;3M"},  //mouse press, checked separately
+	}
+	for _, tc := range cases {
+		parser, _ := NewKeyParser(8)
+		if rest := parser.Feed(nil, []byte(tc.input)); len(rest) != 0 {
+			t.Errorf("%q: unparsed rest %q", tc.input, rest)
+		}
+		events := drain(parser)
+		if tc.key == 0 && tc.char == 0 {
+			if len(events) > 0 && events[0].Err == nil {
+				t.Errorf("%q: unexpected key event %+v", tc.input, events[0])
+			}
+			continue
+		}
+		if len(events) != 1 {
+			t.Errorf("%q: %d events", tc.input, len(events))
+			continue
+		}
+		if events[0].Key != tc.key || events[0].Rune != tc.char || controller.IsRelease(events[0]) != tc.release {
+			t.Errorf("%q: got key %d rune %q release %v", tc.input, events[0].Key, events[0].Rune, controller.IsRelease(events[0]))
+		}
+	}
+}
+
+func TestKeyParserSplitInput(t *testing.T) {
+	parser, _ := NewKeyParser(8)
+	input := "\033[1;1:3A\033[?15u\033"
+	var pending []byte
+	for i := 0; i < len(input); i++ {
+		pending = parser.Feed(pending, []byte{input[i]})
+	}
+	if parser.Supported != 1 {
+		t.Error("protocol answer not detected")
+	}
+	events := drain(parser)
+	if len(events) != 1 || events[0].Key != keyboard.KeyArrowUp || !controller.IsRelease(events[0]) {
+		t.Fatalf("split sequence parsed as %+v", events)
+	}
+	if string(pending) != "\033" {
+		t.Fatalf("lonely esc must wait for more input, pending %q", pending)
+	}
+	pending = parser.Flush(pending)
+	if events = drain(parser); len(pending) != 0 || len(events) != 1 || events[0].Key != keyboard.KeyEsc {
+		t.Errorf("flushed esc parsed as %+v, pending %q", events, pending)
+	}
+	pending = parser.Flush(parser.Feed(nil, []byte("\033[1;1")))
+	if events = drain(parser); len(pending) != 0 || len(events) != 0 {
+		t.Errorf("broken sequence parsed as %+v, pending %q", events, pending)
+	}
+}
+
+func TestKeyParserMouse(t *testing.T) {
+	parser, _ := NewKeyParser(8)
+	parser.Feed(nil, []byte("\033[<0;5;3M\033[<0;5;3m\033[<64;1;1M\033[<32;2;2M"))
+	events := drain(parser)
+	if len(events) != 3 {
+		t.Fatalf("%d mouse events, motion must be skipped", len(events))
+	}
+	expected := []controller.MouseEvent{
+		{X: 4, Y: 2, Button: controller.MOUSE_LEFT, Action: controller.MOUSE_PRESS},
+		{X: 4, Y: 2, Button: controller.MOUSE_LEFT, Action: controller.MOUSE_RELEASE},
+		{X: 0, Y: 0, Button: controller.MOUSE_WHEEL_UP, Action: controller.MOUSE_PRESS},
+	}
+	for i, event := range events {
+		mouse, ok := controller.AsMouse(event)
+		if !ok || *mouse != expected[i] {
+			t.Errorf("event %d: got %+v, expected %+v", i, mouse, expected[i])
+		}
+	}
+}
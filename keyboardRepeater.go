@@ -1,34 +1,167 @@
 package main
 
-import "github.com/eiannone/keyboard"
+import (
+	"GoConsoleBT/controller"
+	"github.com/eiannone/keyboard"
+	"sync"
+	"sync/atomic"
+)
 
+const KEYBOARD_SUBSCRIBER_BUFFER = 16
+
+const (
+	KEYBOARD_MODE_PRESS   = iota //key presses, only if nobody capture input
+	KEYBOARD_MODE_ALL            //presses and releases
+	KEYBOARD_MODE_CAPTURE        //exclusive input, top of focus stack
+	KEYBOARD_MODE_OBSERVE        //every press, regardless of focus
+)
+
+type keyboardSubscriber struct {
+	chanel  chan keyboard.KeyEvent
+	mode    int
+	dropped int64
+}
+
+// KeyboardRepeater broadcast keys to subscribers. Capture push exclusive consumer to focus stack (menu, console),
+// while stack not empty plain subscribers receive nothing but releases. Sends never block, slow subscriber lose keys.
 type KeyboardRepeater struct {
 	origin      <-chan keyboard.KeyEvent
-	subscribers []chan keyboard.KeyEvent
+	subscribers []*keyboardSubscriber
+	focus       []*keyboardSubscriber
+	mutex       sync.Mutex
+	dropped     int64
 }
 
+// Subscribe return key presses only
 func (receiver *KeyboardRepeater) Subscribe() <-chan keyboard.KeyEvent {
-	ch := make(chan keyboard.KeyEvent)
-	receiver.subscribers = append(receiver.subscribers, ch)
-	return ch
+	return receiver.subscribe(KEYBOARD_MODE_PRESS)
+}
+
+// SubscribeAll return key presses and key releases (if input support them)
+func (receiver *KeyboardRepeater) SubscribeAll() <-chan keyboard.KeyEvent {
+	return receiver.subscribe(KEYBOARD_MODE_ALL)
+}
+
+// Observe return every key press even if input captured, eg. for exit handler
+func (receiver *KeyboardRepeater) Observe() <-chan keyboard.KeyEvent {
+	return receiver.subscribe(KEYBOARD_MODE_OBSERVE)
+}
+
+// Capture take input exclusively until Unsubscribe, last capture win
+func (receiver *KeyboardRepeater) Capture() <-chan keyboard.KeyEvent {
+	return receiver.subscribe(KEYBOARD_MODE_CAPTURE)
 }
 
 func (receiver *KeyboardRepeater) Unsubscribe(chanel <-chan keyboard.KeyEvent) {
-	newSubscribers := make([]chan keyboard.KeyEvent, 0, maxInt(len(receiver.subscribers)-1, 1))
-	for idx, candidate := range receiver.subscribers {
-		if candidate == chanel {
-			newSubscribers = append(newSubscribers, receiver.subscribers[:idx]...)
-			newSubscribers = append(newSubscribers, receiver.subscribers[idx+1:]...)
-			close(candidate)
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.subscribers = removeKeyboardSubscriber(receiver.subscribers, chanel)
+	receiver.focus = removeKeyboardSubscriber(receiver.focus, chanel)
+}
+
+// IsCaptured return true if some consumer hold input exclusively
+func (receiver *KeyboardRepeater) IsCaptured() bool {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	return len(receiver.focus) > 0
+}
+
+// Dropped return count of keys lost by slow subscribers, total if chanel is nil
+func (receiver *KeyboardRepeater) Dropped(chanel <-chan keyboard.KeyEvent) int64 {
+	if chanel == nil {
+		return atomic.LoadInt64(&receiver.dropped)
+	}
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	for _, subscriber := range receiver.all() {
+		if subscriber.chanel == chanel {
+			return atomic.LoadInt64(&subscriber.dropped)
 		}
 	}
-	receiver.subscribers = newSubscribers
+	return 0
+}
+
+func (receiver *KeyboardRepeater) subscribe(mode int) <-chan keyboard.KeyEvent {
+	subscriber := &keyboardSubscriber{
+		chanel: make(chan keyboard.KeyEvent, KEYBOARD_SUBSCRIBER_BUFFER),
+		mode:   mode,
+	}
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if mode == KEYBOARD_MODE_CAPTURE {
+		receiver.focus = append(receiver.focus, subscriber)
+	} else {
+		receiver.subscribers = append(receiver.subscribers, subscriber)
+	}
+	return subscriber.chanel
+}
+
+func (receiver *KeyboardRepeater) dispatch(key keyboard.KeyEvent) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	release := controller.IsRelease(key)
+	captured := len(receiver.focus) > 0
+	if captured && !release {
+		receiver.send(receiver.focus[len(receiver.focus)-1], key)
+	}
+	for _, subscriber := range receiver.subscribers {
+		switch subscriber.mode {
+		case KEYBOARD_MODE_OBSERVE:
+			if !release {
+				receiver.send(subscriber, key)
+			}
+		case KEYBOARD_MODE_ALL:
+			if release || !captured {
+				receiver.send(subscriber, key) //release always pass, otherwise key stuck in held state
+			}
+		default:
+			if !release && !captured {
+				receiver.send(subscriber, key)
+			}
+		}
+	}
+}
+
+func (receiver *KeyboardRepeater) send(subscriber *keyboardSubscriber, key keyboard.KeyEvent) {
+	select {
+	case subscriber.chanel <- key:
+	default:
+		atomic.AddInt64(&subscriber.dropped, 1)
+		if dropped := atomic.AddInt64(&receiver.dropped, 1); DEBUG_EVENT {
+			logger.Printf("keyboard subscriber is slow, key dropped, total %d \n", dropped)
+		}
+	}
+}
+
+func (receiver *KeyboardRepeater) closeAll() {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	for _, subscriber := range receiver.all() {
+		close(subscriber.chanel)
+	}
+	receiver.subscribers, receiver.focus = nil, nil
+}
+
+func (receiver *KeyboardRepeater) all() []*keyboardSubscriber {
+	all := make([]*keyboardSubscriber, 0, len(receiver.subscribers)+len(receiver.focus))
+	return append(append(all, receiver.subscribers...), receiver.focus...)
+}
+
+func removeKeyboardSubscriber(subscribers []*keyboardSubscriber, chanel <-chan keyboard.KeyEvent) []*keyboardSubscriber {
+	for idx, candidate := range subscribers {
+		if candidate.chanel == chanel {
+			close(candidate.chanel)
+			return append(subscribers[:idx], subscribers[idx+1:]...)
+		}
+	}
+	return subscribers
 }
 
 func NewKeyboardRepeater(origin <-chan keyboard.KeyEvent) (*KeyboardRepeater, error) {
 	instance := &KeyboardRepeater{
 		origin:      origin,
-		subscribers: make([]chan keyboard.KeyEvent, 0, 1),
+		subscribers: make([]*keyboardSubscriber, 0, 1),
+		focus:       make([]*keyboardSubscriber, 0, 1),
 	}
 	go repeatDispatcher(instance)
 	return instance, nil
@@ -38,16 +171,11 @@ func repeatDispatcher(repeater *KeyboardRepeater) {
 	for {
 		select {
 		case key, ok := <-repeater.origin:
-			for _, subscriber := range repeater.subscribers {
-				if !ok {
-					close(subscriber)
-				} else {
-					subscriber <- key
-				}
-			}
 			if !ok {
+				repeater.closeAll()
 				return
 			}
+			repeater.dispatch(key)
 		}
 	}
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"github.com/eiannone/keyboard"
+	"testing"
+)
+
+func received(chanel <-chan keyboard.KeyEvent) int {
+	count := 0
+	for {
+		select {
+		case _, ok := <-chanel:
+			if !ok {
+				return count
+			}
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+func TestKeyboardRepeaterFocus(t *testing.T) {
+	repeater, _ := NewKeyboardRepeater(make(chan keyboard.KeyEvent))
+	plain, all, observer := repeater.Subscribe(), repeater.SubscribeAll(), repeater.Observe()
+	press, release := keyboard.KeyEvent{Rune: 'w'}, keyboard.KeyEvent{Rune: 'w', Err: controller.KeyReleaseError}
+	expect := func(step string, chanels []<-chan keyboard.KeyEvent, counts []int) {
+		t.Helper()
+		for i, chanel := range chanels {
+			if got := received(chanel); got != counts[i] {
+				t.Errorf("%s: subscriber %d received %d, expected %d", step, i, got, counts[i])
+			}
+		}
+	}
+
+	repeater.dispatch(press)
+	expect("free", []<-chan keyboard.KeyEvent{plain, all, observer}, []int{1, 1, 1})
+
+	menu := repeater.Capture()
+	console := repeater.Capture()
+	if !repeater.IsCaptured() {
+		t.Error("capture not reported")
+	}
+	repeater.dispatch(press)
+	repeater.dispatch(release)
+	expect("captured", []<-chan keyboard.KeyEvent{plain, all, observer, menu, console}, []int{0, 1, 1, 0, 1})
+
+	repeater.Unsubscribe(console)
+	repeater.dispatch(press)
+	expect("console closed", []<-chan keyboard.KeyEvent{plain, menu}, []int{0, 1})
+
+	repeater.Unsubscribe(menu)
+	if repeater.IsCaptured() {
+		t.Error("capture left after unsubscribe")
+	}
+	repeater.dispatch(press)
+	expect("menu closed", []<-chan keyboard.KeyEvent{plain, all, observer}, []int{1, 1, 2})
+}
+
+func TestKeyboardRepeaterDropped(t *testing.T) {
+	repeater, _ := NewKeyboardRepeater(make(chan keyboard.KeyEvent))
+	slow, fast := repeater.Subscribe(), repeater.Observe()
+	for i := 0; i < KEYBOARD_SUBSCRIBER_BUFFER+3; i++ {
+		repeater.dispatch(keyboard.KeyEvent{Rune: 'w'})
+		received(fast)
+	}
+	if dropped := repeater.Dropped(slow); dropped != 3 {
+		t.Errorf("slow subscriber dropped %d, expected 3", dropped)
+	}
+	if dropped := repeater.Dropped(fast); dropped != 0 {
+		t.Errorf("fast subscriber dropped %d", dropped)
+	}
+	if dropped := repeater.Dropped(nil); dropped != 3 {
+		t.Errorf("total dropped %d, expected 3", dropped)
+	}
+	if got := received(slow); got != KEYBOARD_SUBSCRIBER_BUFFER {
+		t.Errorf("slow subscriber received %d, expected full buffer", got)
+	}
+}
@@ -0,0 +1,91 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"github.com/eiannone/keyboard"
+	"golang.org/x/sys/unix"
+	"sync/atomic"
+)
+
+const (
+	kittyPush  = "\033[>15u" //disambiguate, event types, alternate keys, all keys as escape codes
+	kittyPop   = "\033[<u"
+	kittyQuery = "\033[?u"
+)
+
+// KittyKeyboard read terminal input directly and turn on kitty keyboard protocol,
+// so key releases are reported. Legacy sequences are parsed too, terminal without protocol still usable.
+type KittyKeyboard struct {
+	*KeyParser
+	fd     int
+	origin unix.Termios
+	done   int32
+	leave  string //restore terminal modes on close
+}
+
+func (receiver *KittyKeyboard) Close() error {
+	if !atomic.CompareAndSwapInt32(&receiver.done, 0, 1) {
+		return nil
+	}
+	unix.Write(receiver.fd, []byte(receiver.leave))
+	return unix.IoctlSetTermios(receiver.fd, unix.TCSETS, &receiver.origin)
+}
+
+func (receiver *KittyKeyboard) read() {
+	defer close(receiver.events)
+	defer unix.Close(receiver.fd)
+	buf := make([]byte, 256)
+	pending := make([]byte, 0, 256)
+	for atomic.LoadInt32(&receiver.done) == 0 {
+		n, err := unix.Read(receiver.fd, buf) //blocking fd, VTIME give timeout
+		if err != nil || n <= 0 {
+			pending = receiver.Flush(pending) //lonely esc, no continuation in time
+			continue
+		}
+		pending = receiver.Feed(pending, buf[:n])
+	}
+}
+
+// GetTerminalKeys replace keyboard.GetKeys, kitty enable key release reports, mouse enable sgr mouse tracking.
+// Close function must be called on exit to restore terminal
+func GetTerminalKeys(bufferSize int, kitty, mouse bool) (<-chan keyboard.KeyEvent, func() error, error) {
+	fd, err := unix.Open("/dev/tty", unix.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	origin, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		unix.Close(fd)
+		return nil, nil, err
+	}
+	tios := *origin
+	tios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	tios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	tios.Cflag &^= unix.CSIZE | unix.PARENB
+	tios.Cflag |= unix.CS8
+	tios.Cc[unix.VMIN] = 0
+	tios.Cc[unix.VTIME] = 1 //read return every 100ms, so close is noticed and lonely esc flushed
+	if err = unix.IoctlSetTermios(fd, unix.TCSETS, &tios); err != nil {
+		unix.Close(fd)
+		return nil, nil, err
+	}
+
+	parser, _ := NewKeyParser(bufferSize)
+	instance := &KittyKeyboard{
+		KeyParser: parser,
+		fd:        fd,
+		origin:    *origin,
+	}
+	enter := ""
+	if kitty {
+		enter, instance.leave = enter+kittyPush+kittyQuery, kittyPop+instance.leave
+	}
+	if mouse {
+		enter, instance.leave = enter+mouseEnable, mouseDisable+instance.leave
+	}
+	unix.Write(fd, []byte(enter))
+	go instance.read()
+	return instance.events, instance.Close, nil
+}
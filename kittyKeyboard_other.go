@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+	"github.com/eiannone/keyboard"
+)
+
+var KittyNotSupportedError = errors.New("raw terminal input (kitty keyboard protocol, mouse) supported only on linux")
+
+func GetTerminalKeys(bufferSize int, kitty, mouse bool) (<-chan keyboard.KeyEvent, func() error, error) {
+	return nil, nil, KittyNotSupportedError
+}
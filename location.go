@@ -40,6 +40,19 @@ type Trackable interface {
 	GetLayer() int
 }
 
+// ZoneChange object entered zone of layer (Was is nil) or left it (Object is nil)
+type ZoneChange struct {
+	Zone   Zone
+	Layer  int
+	Object Trackable
+	Was    Trackable
+}
+
+// ZoneTracker get zone changes of location once per cycle, changes are valid only during the call
+type ZoneTracker interface {
+	OnZoneChange(changes []ZoneChange)
+}
+
 type Location struct {
 	left, right, top, bottom *collider.ClBody
 	box                      Box
@@ -48,6 +61,11 @@ type Location struct {
 	zonesLeft                [3]int
 	sizeZone                 Zone
 	zoneLock                 sync.Mutex
+	zoneTrackers             []ZoneTracker
+	changes, published       []ZoneChange
+	Lockstep                 bool //trackers notify subscribers in game loop, repeatable gym runs
+	moved                    []*Tracker
+	movedLock                sync.Mutex
 }
 
 func (receiver *Location) Add(object Trackable) {
@@ -85,6 +103,7 @@ func (receiver *Location) Remove(object Trackable) {
 			receiver.zones[layeri][zyi][zxi] = nil
 			tracker.Manager = nil
 			receiver.zonesLeft[layeri]++
+			receiver.changed(Zone{X: zxi, Y: zyi}, layeri, nil, object)
 			return
 		} else {
 			logger.Println("Position::Remove wrong index")
@@ -97,6 +116,7 @@ func (receiver *Location) Remove(object Trackable) {
 					receiver.zones[layeri][yi][xi] = nil
 					tracker.Manager = nil
 					receiver.zonesLeft[layeri]++
+					receiver.changed(Zone{X: xi, Y: yi}, layeri, nil, object)
 				}
 			}
 		}
@@ -112,6 +132,20 @@ func (receiver *Location) NeedCompact() bool {
 }
 
 func (receiver *Location) Execute(timeLeft time.Duration) {
+	if changes, trackers := receiver.updateZones(); len(changes) > 0 {
+		for _, tracker := range trackers {
+			if tracker != nil {
+				tracker.OnZoneChange(changes)
+			}
+		}
+	}
+	for _, tracker := range receiver.takeMoved() {
+		tracker.indexUpdate()
+	}
+}
+
+// updateZones move objects to their new zones, returns changes of cycle and trackers to publish them to out of lock
+func (receiver *Location) updateZones() ([]ZoneChange, []ZoneTracker) {
 	receiver.zoneLock.Lock()
 	defer receiver.zoneLock.Unlock()
 	for layeri, layer := range receiver.zones {
@@ -129,11 +163,55 @@ func (receiver *Location) Execute(timeLeft time.Duration) {
 					err := receiver.putInZone(object)
 					if err == nil {
 						receiver.zones[layeri][yi][xi] = nil
+						receiver.changed(Zone{X: xi, Y: yi}, layeri, nil, object)
 					}
 				}
 			}
 		}
 	}
+	changes := receiver.changes
+	receiver.changes, receiver.published = receiver.published[:0], changes
+	return changes, receiver.zoneTrackers
+}
+
+// indexMoved notify subscribers of tracker on next Execute, lockstep alternative of own goroutine
+func (receiver *Location) indexMoved(tracker *Tracker) {
+	receiver.movedLock.Lock()
+	defer receiver.movedLock.Unlock()
+	receiver.moved = append(receiver.moved, tracker)
+}
+
+func (receiver *Location) takeMoved() []*Tracker {
+	receiver.movedLock.Lock()
+	defer receiver.movedLock.Unlock()
+	moved := receiver.moved
+	receiver.moved = nil
+	return moved
+}
+
+// SubscribeZones get zone changes on every Execute
+func (receiver *Location) SubscribeZones(tracker ZoneTracker) {
+	receiver.zoneLock.Lock()
+	defer receiver.zoneLock.Unlock()
+	receiver.zoneTrackers = append(receiver.zoneTrackers, tracker)
+}
+
+func (receiver *Location) UnsubscribeZones(tracker ZoneTracker) {
+	receiver.zoneLock.Lock()
+	defer receiver.zoneLock.Unlock()
+	for index, candidate := range receiver.zoneTrackers {
+		if tracker == candidate {
+			receiver.zoneTrackers[index] = nil
+		}
+	}
+}
+
+// changed record zone change for trackers, must be called under zone lock
+func (receiver *Location) changed(zone Zone, layeri int, object, was Trackable) {
+	if len(receiver.zoneTrackers) == 0 {
+		return
+	}
+	receiver.changes = append(receiver.changes, ZoneChange{Zone: zone, Layer: layeri, Object: object, Was: was})
 }
 
 func (receiver *Location) Minimap(withSpawnPoint bool, applyRoutes [][]Zone) ([][]byte, error) {
@@ -205,6 +283,64 @@ func (receiver *Location) Mapdata() ([]*Tracker, error) {
 	return mapdata, nil
 }
 
+// PathCost fill grid with traversal cost of every zone, cost get objects of all layers in zone
+func (receiver *Location) PathCost(grid *PathGrid, cost func(objects []Trackable) int32) {
+	receiver.zoneLock.Lock()
+	defer receiver.zoneLock.Unlock()
+	grid.Resize(receiver.sizeZone.X, receiver.sizeZone.Y)
+	objects := make([]Trackable, len(receiver.zones))
+	for yi := 0; yi < receiver.sizeZone.Y; yi++ {
+		for xi := 0; xi < receiver.sizeZone.X; xi++ {
+			for layeri, layer := range receiver.zones {
+				objects[layeri] = nil
+				if layer != nil && layer[yi][xi] != ZoneSpawnPlaceholder {
+					objects[layeri] = layer[yi][xi]
+				}
+			}
+			grid.Cost[yi*receiver.sizeZone.X+xi] = cost(objects)
+		}
+	}
+}
+
+// ZoneObject what is in zone of layer, nil if it is empty or out of range
+func (receiver *Location) ZoneObject(zone Zone, layeri int) Trackable {
+	receiver.zoneLock.Lock()
+	defer receiver.zoneLock.Unlock()
+	if zone.X < 0 || zone.Y < 0 || zone.X >= receiver.sizeZone.X || zone.Y >= receiver.sizeZone.Y ||
+		receiver.zones[layeri] == nil || receiver.zones[layeri][zone.Y][zone.X] == ZoneSpawnPlaceholder {
+		return nil
+	}
+	return receiver.zones[layeri][zone.Y][zone.X]
+}
+
+// BlockMap snapshot of zones projectiles and tanks can't pass, indexed [y][x]
+func (receiver *Location) BlockMap() [][]bool {
+	receiver.zoneLock.Lock()
+	defer receiver.zoneLock.Unlock()
+	blocked := make([][]bool, receiver.sizeZone.Y)
+	for yi, row := range receiver.zones[LOCATION_LAYER_UNIT] {
+		blocked[yi] = make([]bool, receiver.sizeZone.X)
+		for xi, object := range row {
+			if tagged, ok := object.(interface{ HasTag(string) bool }); ok {
+				blocked[yi][xi] = tagged.HasTag("obstacle") && !tagged.HasTag("low")
+			}
+		}
+	}
+	return blocked
+}
+
+func (receiver *Location) ZoneSize() Zone {
+	return receiver.sizeZone
+}
+
+// ClampZone nearest zone inside map, eg. for squad slot next to map border
+func (receiver *Location) ClampZone(zone Zone) Zone {
+	return Zone{
+		X: maxInt(minInt(zone.X, receiver.sizeZone.X-1), 0),
+		Y: maxInt(minInt(zone.Y, receiver.sizeZone.Y-1), 0),
+	}
+}
+
 func (receiver *Location) Coordinate2Spawn(empty bool, layeri int) (Point, error) {
 	if receiver.zones[layeri] == nil {
 		return NoPos, ZoneSetupError
@@ -444,6 +580,10 @@ func (receiver *Location) setup(box Box) error {
 	return nil
 }
 
+func (receiver *Location) GetBox() Box {
+	return receiver.box
+}
+
 func (receiver *Location) GetClBody() *collider.ClBody {
 	return receiver.left
 }
@@ -496,6 +636,7 @@ func (receiver *Location) putInZone(object Trackable) error {
 		logger.Printf("put in zone %d %d", zxi, zyi)
 	}
 	receiver.zones[layeri][zyi][zxi] = object
+	receiver.changed(Zone{X: zxi, Y: zyi}, layeri, object, nil)
 	return nil
 }
 
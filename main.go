@@ -14,6 +14,8 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -25,28 +27,30 @@ import (
 
 const CYCLE = 100 * time.Millisecond
 
-const DEBUG = false
-const DEBUG_SPAWN = false
-const DEBUG_EVENT = false
-const DEBUG_EXEC = false
-const DEBUG_STATE = false
-const DEBUG_NO_AI = false
-const DEBUG_SHAKE = false
-const DEBUG_IMMORTAL_PLAYER = false
-const DEBUG_FREEZ_AI = false
-const DEBUG_AI_PATH = false
-const DEBUG_AI_BEHAVIOR = false
-const DEBUG_FIRE_SOLUTION = false
-const DEBUG_MINIMAP = false
-const DEBUG_DISABLE_VISION = false
-const DEBUG_SHUTDOWN = false
-const DEBUG_OPPORTUNITY_FIRE = false
-const DEBUG_DISABLE_UI = false
-const DEBUG_DISARM_AI = false
-const DEBUG_SHOW_ID = false
-const DEBUG_SHOW_AI_BEHAVIOR = false
-const DEBUG_FREE_SPACES = false
-const DEBUG_SPAWN_POINT_STATUS = false
+// runtime switches, see DebugFlags
+var (
+	DEBUG                    = false
+	DEBUG_SPAWN              = false
+	DEBUG_EVENT              = false
+	DEBUG_EXEC               = false
+	DEBUG_STATE              = false
+	DEBUG_NO_AI              = false
+	DEBUG_SHAKE              = false
+	DEBUG_IMMORTAL_PLAYER    = false
+	DEBUG_FREEZ_AI           = false
+	DEBUG_AI_PATH            = false
+	DEBUG_AI_BEHAVIOR        = false
+	DEBUG_FIRE_SOLUTION      = false
+	DEBUG_MINIMAP            = false
+	DEBUG_DISABLE_VISION     = false
+	DEBUG_SHUTDOWN           = false
+	DEBUG_OPPORTUNITY_FIRE   = false
+	DEBUG_DISABLE_UI         = false
+	DEBUG_SHOW_ID            = false
+	DEBUG_SHOW_AI_BEHAVIOR   = false
+	DEBUG_FREE_SPACES        = false
+	DEBUG_SPAWN_POINT_STATUS = false
+)
 
 var (
 	buf, bufErr     = os.OpenFile("log.txt", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 644)
@@ -77,6 +81,20 @@ var (
 	profileDelay                 time.Duration
 	withColor, withSound         bool
 	simplifyAi                   bool
+	aiDifficulty                 string
+	debugInfluence               string
+	debugAi                      bool
+	debugFlags                   string
+	kittyKeyboard                bool
+	mouse, mouseWaypoint         bool
+	playerScripts                [2]string
+	bots                         = make(TeamBots)
+	gymMode                      bool
+	gymAgents                    int
+	hostAddr, joinAddr           string
+	hostPlayers                  int
+	sshAddr, sshHostKey          string
+	spectateAddr                 string
 	osSignal                     chan os.Signal
 )
 
@@ -100,6 +118,24 @@ func init() {
 	flag.BoolVar(&withColor, "withColor", false, "enable color mode (3bit mode (8 color))")
 	flag.BoolVar(&withSound, "withSound", false, "enable sound mode (the sounds will be played on the machine where the game is running)")
 	flag.BoolVar(&simplifyAi, "simplifyAi", false, "disable ai behaviors")
+	flag.StringVar(&debugInfluence, "debug.influence", "", "show threat map of team, eg. -1 for players, 1 for default ai team")
+	flag.StringVar(&debugFlags, "debug.flags", "", "enable comma separated debug flags, eg. immortal_player,show_id (see console set)")
+	flag.BoolVar(&debugAi, "debug.ai", false, "show ai debug overlay from start, toggled in game by debugAi key (f9)")
+	flag.StringVar(&aiDifficulty, "ai.difficulty", "", "ai difficulty, one of [easy, normal, hard, insane], default from scenario or normal")
+	flag.StringVar(&playerScripts[0], "script.player1", "", "drive player 1 by macro script instead of keyboard, skip setup dialogs")
+	flag.StringVar(&playerScripts[1], "script.player2", "", "drive player 2 by macro script instead of keyboard, skip setup dialogs")
+	flag.Var(bots, "bot", "drive units of team by external bot process, eg. --bot '1=python3 bots/hunter.py', repeatable")
+	flag.BoolVar(&gymMode, "gym", false, "headless json-rpc training mode over stdin/stdout, see README")
+	flag.IntVar(&gymAgents, "gym.agents", 1, "player tanks driven by gym step actions")
+	flag.StringVar(&hostAddr, "host", "", "host network game on address, eg. :7777")
+	flag.IntVar(&hostPlayers, "host.players", 1, "remote players to wait before game start")
+	flag.StringVar(&sshAddr, "ssh", "", "serve game over ssh on address, eg. :2222, every session join as new player")
+	flag.StringVar(&sshHostKey, "ssh.hostKey", "./ssh_host_key", "ssh host key file, generated if not exist")
+	flag.StringVar(&spectateAddr, "spectate", "", "serve read only browser view on address, eg. :8080")
+	flag.StringVar(&joinAddr, "join", "", "join network game hosted on address, eg. 127.0.0.1:7777")
+	flag.BoolVar(&kittyKeyboard, "kittyKeyboard", false, "use kitty keyboard protocol for real key release (same as input.kittyProtocol in config)")
+	flag.BoolVar(&mouse, "mouse", false, "enable mouse for menus and calibration (same as input.mouse in config)")
+	flag.BoolVar(&mouseWaypoint, "mouse.waypoint", false, "click on map drive player tank there, left click player 1, right click player 2 (same as input.mouseWaypoint in config)")
 
 	osSignal = make(chan os.Signal, 1)
 
@@ -107,7 +143,10 @@ func init() {
 	signal.Notify(osSignal, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGKILL)
 
 	output.DEBUG = DEBUG
-	controller.DEBUG_DISARM_AI = DEBUG_DISARM_AI
+	controller.CycleSource = func() int64 {
+		return atomic.LoadInt64(&CycleID)
+	}
+	controller.Clock = Clock
 }
 
 func main() {
@@ -126,8 +165,26 @@ func main() {
 	//os.Exit(0)
 	flag.Parse()
 
+	if err := SetDebugFlags(debugFlags); err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+
 	rand.Seed(seed)
 
+	if gymMode {
+		gym, err := NewGym(GymConfig{Scenario: scenarioName, Agents: gymAgents, Difficulty: aiDifficulty, TankCnt: tankCnt, WallCnt: wallCnt})
+		if err != nil {
+			log.Print(err)
+			os.Exit(1)
+		}
+		if err := gym.Serve(os.Stdin, os.Stdout); err != nil {
+			log.Print(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	gameConfig, err = loadConfig()
 	if err != nil {
 		if !calibrate {
@@ -142,16 +199,30 @@ func main() {
 	gameConfig.disableCustomization = !withColor
 
 	//input
-	keysEvents, err := keyboard.GetKeys(1)
-	if err != nil {
-		panic(err)
+	var keysEvents <-chan keyboard.KeyEvent
+	closeKeyboard := keyboard.Close
+	mouseWaypoint = mouseWaypoint || gameConfig.Input.MouseWaypoint
+	mouse = mouse || mouseWaypoint || gameConfig.Input.Mouse
+	kittyKeyboard = kittyKeyboard || gameConfig.Input.KittyProtocol
+	if kittyKeyboard || mouse {
+		keysEvents, closeKeyboard, err = GetTerminalKeys(1, kittyKeyboard, mouse)
+		if err != nil {
+			logger.Println("raw terminal input unavailable, fallback to default input: ", err)
+		}
+	}
+	if keysEvents == nil {
+		closeKeyboard = keyboard.Close
+		keysEvents, err = keyboard.GetKeys(1)
+		if err != nil {
+			panic(err)
+		}
 	}
 	repeater, _ := NewKeyboardRepeater(keysEvents)
-	closingEvents := repeater.Subscribe()
+	closingEvents := repeater.Observe()
 
 	//closing
 	defer func() {
-		_ = keyboard.Close()
+		_ = closeKeyboard()
 		profileStop()
 		render.Free()
 		buf.Sync()
@@ -171,7 +242,14 @@ func main() {
 	pipe.AnimationManager = animator
 
 	//render
-	render, _ = NewRenderZIndex(100)
+	primaryRender, _ := NewRenderZIndex(100)
+	var multiRender *MultiRender
+	if sshAddr != "" || spectateAddr != "" {
+		multiRender, _ = NewMultiRender(primaryRender)
+		render = multiRender
+	} else {
+		render = primaryRender
+	}
 	pipe.Render = render
 
 	//updater
@@ -222,6 +300,25 @@ func main() {
 	navigation, _ := NewNavigation(location, detector)
 	pipe.Navigation = navigation
 
+	influence, _ := NewInfluenceMap(location, spawner)
+	updater.Add(influence)
+	if debugInfluence != "" {
+		team, err := strconv.ParseInt(debugInfluence, 10, 8)
+		var overlay *InfluenceOverlay
+		if err == nil {
+			overlay, err = NewInfluenceOverlay(influence, int8(team), render)
+		}
+		if err != nil {
+			logger.Println(err)
+		} else {
+			updater.Add(overlay)
+		}
+	}
+
+	aiDebug, _ := NewAiDebugOverlay(spawner, location, render)
+	aiDebug.Enable(debugAi)
+	updater.Add(aiDebug)
+
 	//builder
 	buildManager, _ = NewBlueprintManager()
 	Require = func(blueprint string) error {
@@ -235,6 +332,16 @@ func main() {
 	//ai
 	if !simplifyAi {
 		aibuilder, _ = NewAIControlBuilder(detector, location, navigation)
+		if aiDifficulty == "" {
+			aiDifficulty = scenario.Difficulty
+		}
+		aibuilder.Influence = influence
+		aibuilder.Difficulty, err = GetAiDifficulty(aiDifficulty)
+		if err != nil {
+			logger.Print(err)
+			log.Print(err)
+			os.Exit(1)
+		}
 	}
 
 	if withSound {
@@ -246,6 +353,9 @@ func main() {
 	game.Location = location
 	game.EffectManager = pipe.EffectManager
 	game.SoundManager = sound
+	game.AiBuilder = aibuilder
+	game.Navigation = navigation
+	game.Bots = bots
 
 	//ui
 	if !DEBUG_DISABLE_UI {
@@ -266,6 +376,42 @@ func main() {
 	runner.Renderer = render
 	runner.SoundManager = sound
 	runner.UI = ui
+	runner.Pipeline = pipe
+	runner.PlayerScripts = playerScripts[:]
+	runner.MouseWaypoint = mouseWaypoint
+	runner.AiDebug = aiDebug
+	if hostAddr != "" {
+		runner.NetHost, err = NewNetHost(hostAddr, spawner)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		runner.RemotePlayers = hostPlayers
+		updater.Add(runner.NetHost)
+		defer runner.NetHost.Close()
+	}
+	if multiRender != nil {
+		multiRender.Bounds = location.GetBox()
+	}
+	if sshAddr != "" {
+		sshServer, err := NewSSHServer(sshAddr, sshHostKey, game, multiRender, gameConfig)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		sshServer.OnPlayersChange = runner.RefreshUI
+		go sshServer.Serve()
+		defer sshServer.Close()
+	}
+	if spectateAddr != "" {
+		spectators, err := NewSpectatorServer(spectateAddr, multiRender)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		go spectators.Serve()
+		defer spectators.Close()
+	}
 
 	//time
 	cycleTime := CYCLE
@@ -281,8 +427,24 @@ func main() {
 	if calibrate {
 		calibration, _ = NewCalibration(updater, render, detector, location, finChanel)
 		calibration.GameConfig = gameConfig
-		control, _ := controller.NewPlayerControl(keysEvents, controller.Player1DefaultKeyBinding)
+		if mouse {
+			calibration.Mouse = repeater.Subscribe()
+		}
+		control, _ := controller.NewPlayerControl(repeater.SubscribeAll(), controller.Player1DefaultKeyBinding, gameConfig.Input.HoldConfig) //share input with mouse
 		go calibration.Run(control)
+	} else if joinAddr != "" {
+		client, err := NewNetClient(joinAddr)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		client.KeyboardRepeater = repeater
+		client.GameConfig = gameConfig
+		client.BlueprintManager = buildManager
+		client.SpawnManager = spawner
+		client.Renderer = render
+		updater.Add(client)
+		go client.Run(finChanel)
 	} else {
 		go runner.Run(game, scenario, finChanel)
 	}
@@ -352,9 +514,9 @@ func main() {
 			}
 			cycleTimer.Reset(cycleTime)
 			if CycleID == math.MaxInt64 {
-				CycleID = 0
+				atomic.StoreInt64(&CycleID, 0)
 			} else {
-				CycleID++
+				atomic.AddInt64(&CycleID, 1)
 			}
 		}
 	}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// FollowFunc return point viewport should keep in view, false if nothing to follow
+type FollowFunc func() (Point, bool)
+
+type renderView struct {
+	*Render
+	follow FollowFunc
+}
+
+// MultiRender draw same scene to main terminal and every attached view (eg. ssh session),
+// each view have own size and viewport
+type MultiRender struct {
+	*Render
+	Bounds         Box //world size, viewport never leave it
+	views          []*renderView
+	pending        []*renderView
+	detached       []*Render
+	shakeX, shakeY int
+	mutex          sync.Mutex
+}
+
+func (receiver *MultiRender) Add(object Renderable) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.Render.Add(object)
+	for _, view := range receiver.views {
+		view.Add(object)
+	}
+}
+
+func (receiver *MultiRender) Remove(object Renderable) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.Render.Remove(object)
+	for _, view := range receiver.views {
+		view.Remove(object)
+	}
+}
+
+func (receiver *MultiRender) Execute(timeLeft time.Duration) {
+	receiver.sync()
+	receiver.Render.Execute(timeLeft)
+	for _, view := range receiver.views {
+		offsetX, offsetY := 0, 0
+		if view.follow != nil {
+			if pos, ok := view.follow(); ok {
+				offsetX = viewportOffset(pos.X, receiver.Bounds.X, receiver.Bounds.W, view.Width())
+				offsetY = viewportOffset(pos.Y, receiver.Bounds.Y, receiver.Bounds.H, view.Height())
+			}
+		}
+		view.SetOffset(offsetX+receiver.shakeX, offsetY+receiver.shakeY)
+		view.Execute(timeLeft)
+	}
+}
+
+// sync apply attach and detach requests, must run in render thread. Views change under lock, so object added
+// meanwhile is not missed by attached view
+func (receiver *MultiRender) sync() {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	pending, detached := receiver.pending, receiver.detached
+	receiver.pending, receiver.detached = nil, nil
+
+	for _, view := range pending {
+		receiver.Render.Each(view.Add)
+		receiver.views = append(receiver.views, view)
+	}
+	for _, render := range detached {
+		for i, view := range receiver.views {
+			if view.Render == render {
+				receiver.views = append(receiver.views[:i], receiver.views[i+1:]...)
+				render.Free()
+				break
+			}
+		}
+	}
+}
+
+// Attach view, it receive everything already rendered. Thread safe
+func (receiver *MultiRender) Attach(render *Render, follow FollowFunc) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.pending = append(receiver.pending, &renderView{Render: render, follow: follow})
+}
+
+// Detach view, it is freed on next frame. Thread safe
+func (receiver *MultiRender) Detach(render *Render) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.detached = append(receiver.detached, render)
+}
+
+// SetOffset is screen shake, applied to every view on top of viewport
+func (receiver *MultiRender) SetOffset(x, y int) {
+	receiver.shakeX, receiver.shakeY = x, y
+	receiver.Render.SetOffset(x, y)
+}
+
+func (receiver *MultiRender) Compact() {
+	receiver.Render.Compact()
+	for _, view := range receiver.views {
+		view.Compact()
+	}
+}
+
+func (receiver *MultiRender) Free() {
+	receiver.Render.Free()
+	for _, view := range receiver.views {
+		view.Free()
+	}
+}
+
+// viewportOffset center pos on screen, world smaller than screen is never scrolled
+func viewportOffset(pos, worldFrom, worldSize float64, screen int) int {
+	worldTo := worldFrom + worldSize
+	if worldTo <= float64(screen) {
+		return 0
+	}
+	offset := float64(screen)/2 - pos
+	offset = math.Min(offset, 0)
+	offset = math.Max(offset, float64(screen)-worldTo)
+	return int(math.Round(offset))
+}
+
+func NewMultiRender(primary *Render) (*MultiRender, error) {
+	return &MultiRender{
+		Render: primary,
+		views:  make([]*renderView, 0, 2),
+	}, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import "time"
+
+const (
+	NAV_REPAIR_HORIZON  = 6                      //zones past taken one local repair rejoins the path at
+	NAV_REPLAN_INTERVAL = 250 * time.Millisecond //path is replanned for shortcut at most that often
+)
+
+// PathFollower receiver moving along delivered path, the path is repaired while map changes on the way
+type PathFollower interface {
+	PathReceiver
+	PathPosition() (Zone, Trackable) //zone follower is in and object it moves, own moves don't change the path
+}
+
+// NavRoute rest of path delivered to follower
+type NavRoute struct {
+	path     []Zone
+	to       Zone
+	priority int
+	replanAt time.Time
+	replan   *NavJob //scheduled by navigation, canceled with the route
+}
+
+// OnZoneChange collect changes of objects paths depend on, they are applied on next navigation cycle
+func (receiver *Navigation) OnZoneChange(changes []ZoneChange) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	for _, change := range changes {
+		if !onPath(change.Object) && !onPath(change.Was) {
+			continue
+		}
+		receiver.changes = append(receiver.changes, change)
+		if !movingUnit(change.Object) || !movingUnit(change.Was) {
+			receiver.flowsDirty = true
+		}
+	}
+}
+
+// Forget follower route, its path is not repaired anymore and replan on the way is canceled
+func (receiver *Navigation) Forget(owner PathReceiver) {
+	if follower, ok := owner.(PathFollower); ok {
+		receiver.mutex.Lock()
+		receiver.drop(follower, nil)
+		receiver.mutex.Unlock()
+	}
+}
+
+// drop route of follower with its replan unless replan is delivered, must be called under lock
+func (receiver *Navigation) drop(follower PathFollower, delivered *NavJob) {
+	if route, ok := receiver.routes[follower]; ok {
+		if route.replan != nil && route.replan != delivered {
+			route.replan.Cancel()
+		}
+		delete(receiver.routes, follower)
+	}
+}
+
+// follow keep route of delivered path to repair it, must be called under lock
+func (receiver *Navigation) follow(job *NavJob) {
+	follower, ok := job.owner.(PathFollower)
+	if !ok {
+		return
+	}
+	receiver.drop(follower, job)
+	if len(job.output) < 2 {
+		return
+	}
+	receiver.routes[follower] = &NavRoute{path: job.output, to: job.to, priority: job.priority}
+}
+
+// repairRoutes apply zone changes since last cycle to followers paths: zone taken on the way is bypassed
+// locally, obstacle gone next to the way replan whole path as shortcut may appear. Must be called under lock
+func (receiver *Navigation) repairRoutes(grid func() *PathGrid) {
+	changes := receiver.changes
+	receiver.changes = changes[:0]
+	if len(changes) == 0 || len(receiver.routes) == 0 {
+		return
+	}
+	now := Clock.Now()
+	for follower, route := range receiver.routes {
+		position, self := follower.PathPosition()
+		start := zoneIndex(route.path, position)
+		if start == -1 {
+			continue //off the way, follower replan itself
+		}
+		if route.path = route.path[start:]; len(route.path) < 2 {
+			receiver.drop(follower, nil)
+			continue
+		}
+		taken, shortcut := -1, false
+		for _, change := range changes {
+			if change.Object == self || change.Was == self {
+				continue
+			}
+			if change.Object != nil {
+				//goal is target surrounding, target moves there
+				if index := zoneIndex(route.path[1:len(route.path)-1], change.Zone); index != -1 && (taken == -1 || index+1 < taken) {
+					taken = index + 1
+				}
+			} else if _, ok := change.Was.(*Wall); ok && !shortcut {
+				shortcut = nearPath(route.path, change.Zone)
+			}
+		}
+		switch {
+		case taken != -1:
+			receiver.bypass(follower, route, taken, grid())
+		case shortcut && now.After(route.replanAt):
+			route.replanAt = now.Add(NAV_REPLAN_INTERVAL)
+			receiver.replan(follower, route)
+		}
+	}
+}
+
+// bypass taken zone of route: path from zone before it to one NAV_REPAIR_HORIZON further is spliced in
+func (receiver *Navigation) bypass(follower PathFollower, route *NavRoute, taken int, grid *PathGrid) {
+	end, blocked := minInt(taken+NAV_REPAIR_HORIZON, len(route.path)-1), route.path[taken]
+	detour := grid.FindPath(route.path[taken-1], route.path[end], receiver.Diagonal)
+	if len(detour) == 0 {
+		receiver.replan(follower, route)
+		return
+	}
+	if equalZones(detour, route.path[taken-1:end+1]) {
+		return //still the cheapest way
+	}
+	path := make([]Zone, 0, taken-1+len(detour)+len(route.path)-end-1)
+	path = append(path, route.path[:taken-1]...)
+	path = append(path, detour...)
+	path = append(path, route.path[end+1:]...)
+	route.path = path
+	receiver.metrics.Repaired++
+	if DEBUG_AI_PATH {
+		logger.Printf("cycleID %d path repaired around %d, %d \n", CycleID, blocked.X, blocked.Y)
+	}
+	receiver.delivered = append(receiver.delivered, navDelivery{owner: follower, path: path, jobId: genId()})
+}
+
+// replan whole path of route from follower position, previous replan is stale. Must be called under lock
+func (receiver *Navigation) replan(follower PathFollower, route *NavRoute) {
+	job, err := receiver.schedule(route.path[0], route.to, follower, route.priority)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	if route.replan != nil {
+		route.replan.Cancel()
+	}
+	route.replan = job
+	receiver.metrics.Replanned++
+}
+
+// onPath object zone cost depends on
+func onPath(object Trackable) bool {
+	switch object.(type) {
+	case *Unit, *Wall:
+		return true
+	}
+	return false
+}
+
+// movingUnit tank, flow fields ignore them
+func movingUnit(object Trackable) bool {
+	unit, ok := object.(*Unit)
+	return object == nil || (ok && !unit.HasTag("base"))
+}
+
+func zoneIndex(path []Zone, zone Zone) int {
+	for index, candidate := range path {
+		if candidate == zone {
+			return index
+		}
+	}
+	return -1
+}
+
+func nearPath(path []Zone, zone Zone) bool {
+	for _, candidate := range path {
+		if absInt(candidate.X-zone.X)+absInt(candidate.Y-zone.Y) <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func equalZones(a, b []Zone) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"GoConsoleBT/collider"
 	"fmt"
-	pathfinding "github.com/xarg/gopathfinding"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,11 +15,23 @@ type PathReceiver interface {
 }
 
 const (
-	NJ_STATE_NEW int = iota - 1
+	NJ_STATE_NEW int32 = iota - 1
 	NJ_STATE_WORK
 	NJ_STATE_DONE
 )
 
+// path job priorities, higher are searched first
+const (
+	NAV_PRIORITY_LOW    = iota //allies keeping post
+	NAV_PRIORITY_NORMAL        //
+	NAV_PRIORITY_HIGH          //units after player or base, player waypoints
+)
+
+const (
+	NAV_WORKER_LIMIT   = 4  //path searches at once, fewer on small machines
+	NAV_LATENCY_WEIGHT = 10 //latency average moves by 1/weight of new sample
+)
+
 var (
 	NoZone = Zone{
 		X: -100,
@@ -25,136 +39,356 @@ var (
 	}
 )
 
+// NavJob is handle of scheduled path, owner receive the path unless job is canceled
 type NavJob struct {
-	jobId  int64
-	input  []*Tracker
-	from   Zone
-	to     Zone
-	output []Zone
-	state  int
-	owner  PathReceiver
+	jobId    int64
+	priority int
+	grid     *PathGrid
+	from     Zone
+	to       Zone
+	output   []Zone
+	state    int32
+	canceled int32
+	leader   *NavJob //identical job which search is shared
+	skipped  bool    //canceled before its search started, output is not a path
+	owner    PathReceiver
+	queuedAt time.Time
+}
+
+func (receiver *NavJob) ID() int64 {
+	return receiver.jobId
+}
+
+// Cancel job, owner won't receive the path. Search already running is finished for duplicates, ones waiting for
+// search not started yet search again
+func (receiver *NavJob) Cancel() {
+	atomic.StoreInt32(&receiver.canceled, 1)
+}
+
+func (receiver *NavJob) Canceled() bool {
+	return atomic.LoadInt32(&receiver.canceled) == 1
+}
+
+func (receiver *NavJob) getState() int32 {
+	return atomic.LoadInt32(&receiver.state)
+}
+
+func (receiver *NavJob) setState(state int32) {
+	atomic.StoreInt32(&receiver.state, state)
+}
+
+// NavMetrics queue state, counters since start and latency from schedule to delivery
+type NavMetrics struct {
+	Queued, Working                                   int //waiting for worker, searched or waiting for duplicate
+	Done, Canceled, Deduplicated, Repaired, Replanned int64
+	Latency, MaxLatency                               time.Duration //moving average and max
+}
+
+// navDelivery path handed to receiver after navigation cycle, out of lock
+type navDelivery struct {
+	owner PathReceiver
+	path  []Zone
+	jobId int64
 }
 
 type Navigation struct {
 	*Location
 	*collider.Collider
-	queue           []*NavJob
-	mutex           sync.Mutex
-	mapDataTemplate pathfinding.MapData
-	NavData         [][]Zone
+	Diagonal   bool                            //8-connectivity, tanks drive only 4 directions
+	Cost       func(objects []Trackable) int32 //zone cost by objects of all layers, ZoneCost by default
+	FlowCost   func(objects []Trackable) int32 //same for flow fields, StaticZoneCost by default
+	Lockstep   bool                            //search paths in game loop and deliver them in order, repeatable gym runs
+	queue      []*NavJob
+	pending    []*NavJob
+	searched   map[[2]Zone]*NavJob //jobs which search is running or done this cycle, by from and to
+	work       chan *NavJob
+	metrics    NavMetrics
+	routes     map[PathFollower]*NavRoute
+	changes    []ZoneChange //since last cycle
+	delivered  []navDelivery
+	mutex      sync.Mutex
+	NavData    [][]Zone
+	flows      map[Zone]*FlowField
+	flowGrid   *PathGrid //costs flow fields are up to date with
+	flowAt     time.Time
+	flowsDirty bool //static costs changed
+	lowered    []int32
 }
 
 func (receiver *Navigation) Execute(timeLeft time.Duration) {
 	receiver.mutex.Lock()
-	defer receiver.mutex.Unlock()
-	if len(receiver.queue) > 0 {
-		mapData, err := receiver.Location.Mapdata()
-		if err == nil {
-			emptyUntil := -1
-			for index, job := range receiver.queue {
-				if job != nil && emptyUntil == -1 {
-					emptyUntil = index
-				}
-				if job == nil {
-					continue
-				}
-				if job.state == NJ_STATE_DONE {
-					//notify object: plan ready
-					if job.output != nil && len(job.output) > 0 {
-						receiver.NavData = append(receiver.NavData, job.output)
-					}
-					if job.owner != nil {
-						go job.owner.ReceivePath(job.output, job.jobId)
-					}
-					receiver.queue[index] = nil
-				} else if job.state == NJ_STATE_NEW {
-					job.state = NJ_STATE_WORK
-					job.input = mapData
-					go receiver.buildPath(job)
-				}
+	receiver.execute()
+	delivered, inline := receiver.delivered, receiver.Lockstep
+	receiver.delivered = nil
+	receiver.mutex.Unlock()
+	for _, delivery := range delivered {
+		if inline {
+			delivery.owner.ReceivePath(delivery.path, delivery.jobId)
+		} else {
+			go delivery.owner.ReceivePath(delivery.path, delivery.jobId)
+		}
+	}
+}
+
+// execute cycle of repairs and jobs, must be called under lock
+func (receiver *Navigation) execute() {
+	var grid *PathGrid //built once for repairs and new jobs of cycle
+	cycleGrid := func() *PathGrid {
+		if grid == nil {
+			grid = receiver.pathGrid()
+		}
+		return grid
+	}
+	defer func() {
+		if grid != nil {
+			grid.release()
+		}
+	}()
+	receiver.updateFlows()
+	receiver.repairRoutes(cycleGrid)
+	if len(receiver.queue) == 0 {
+		return
+	}
+	for key := range receiver.searched {
+		delete(receiver.searched, key)
+	}
+	queue, pending, working := receiver.queue[:0], receiver.pending[:0], 0
+	for _, job := range receiver.queue {
+		if job.Canceled() {
+			receiver.metrics.Canceled++
+			continue //running search put the grid back itself
+		}
+		if leader := job.leader; leader != nil && leader.getState() == NJ_STATE_DONE {
+			if leader.skipped {
+				job.leader = nil //first of them lead new search, rest join it
+				job.setState(NJ_STATE_NEW)
+			} else {
+				job.output = append([]Zone(nil), leader.output...) //owners cut their paths
+				job.setState(NJ_STATE_DONE)
+			}
+		}
+		switch job.getState() {
+		case NJ_STATE_DONE:
+			if job.leader == nil {
+				receiver.searched[[2]Zone{job.from, job.to}] = job
 			}
-			if emptyUntil > 0 {
-				//receiver.queue = receiver.queue[emptyUntil:]
-				//todo implement compact
+			receiver.deliver(job)
+			continue
+		case NJ_STATE_NEW:
+			pending = append(pending, job)
+		default:
+			if job.leader == nil {
+				receiver.searched[[2]Zone{job.from, job.to}] = job
 			}
-		} else {
-			logger.Printf("error acquire map data\n")
+			working++
 		}
+		queue = append(queue, job)
+	}
+	for i := len(queue); i < len(receiver.queue); i++ {
+		receiver.queue[i] = nil //compacted, let delivered jobs go
+	}
+	receiver.queue = queue
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].priority > pending[j].priority
+	})
+	for _, job := range pending {
+		key := [2]Zone{job.from, job.to}
+		if leader, ok := receiver.searched[key]; ok {
+			job.leader = leader
+			job.setState(NJ_STATE_WORK)
+			receiver.metrics.Deduplicated++
+			working++
+			continue
+		}
+		if !receiver.Lockstep && len(receiver.work) == cap(receiver.work) {
+			continue //workers busy, wait for next cycle, duplicates still join running searches
+		}
+		job.grid = cycleGrid()
+		job.grid.retain()
+		job.setState(NJ_STATE_WORK)
+		receiver.searched[key] = job
+		working++
+		if receiver.Lockstep {
+			receiver.buildPath(job) //delivered next cycle as worker results are
+			continue
+		}
+		receiver.work <- job
+	}
+	receiver.pending = pending[:0]
+	receiver.metrics.Queued, receiver.metrics.Working = len(queue)-working, working
+}
+
+// deliver path to owner, must be called under lock
+func (receiver *Navigation) deliver(job *NavJob) {
+	if DEBUG_MINIMAP && len(job.output) > 0 {
+		receiver.NavData = append(receiver.NavData, job.output)
+	}
+	if job.owner != nil {
+		receiver.follow(job)
+		receiver.delivered = append(receiver.delivered, navDelivery{owner: job.owner, path: job.output, jobId: job.jobId})
+	}
+	latency := time.Since(job.queuedAt)
+	receiver.metrics.Done++
+	receiver.metrics.Latency += (latency - receiver.metrics.Latency) / NAV_LATENCY_WEIGHT
+	if latency > receiver.metrics.MaxLatency {
+		receiver.metrics.MaxLatency = latency
+	}
+}
+
+// Metrics snapshot of path jobs queue
+func (receiver *Navigation) Metrics() NavMetrics {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	return receiver.metrics
+}
+
+func (receiver *Navigation) pathGrid() *PathGrid {
+	size := receiver.Location.ZoneSize()
+	grid, _ := NewPathGrid(size.X, size.Y)
+	receiver.Location.PathCost(grid, receiver.Cost)
+	return grid
+}
+
+// Flow shared flow field to goal, not ready until next navigation cycle. Goal must not move: units of siege
+// heading to the base
+func (receiver *Navigation) Flow(goal Zone) *FlowField {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	field, ok := receiver.flows[goal]
+	if !ok {
+		field, _ = NewFlowField(goal)
+		receiver.flows[goal] = field
+	}
+	field.usedAt = Clock.Now()
+	return field
+}
+
+// updateFlows drop unused fields, compute new ones and bring others up to date with map changes
+func (receiver *Navigation) updateFlows() {
+	if len(receiver.flows) == 0 {
+		return
+	}
+	now := Clock.Now()
+	fresh := false
+	for goal, field := range receiver.flows {
+		if now.Sub(field.usedAt) > FLOW_FIELD_TTL {
+			delete(receiver.flows, goal)
+		} else if !field.Ready() {
+			fresh = true
+		}
+	}
+	if !fresh && (!receiver.flowsDirty || now.Sub(receiver.flowAt) < FLOW_FIELD_REFRESH) {
+		return
+	}
+	receiver.flowAt, receiver.flowsDirty = now, false
+	size := receiver.Location.ZoneSize()
+	grid, _ := NewPathGrid(size.X, size.Y)
+	receiver.Location.PathCost(grid, receiver.FlowCost)
+	var raised bool
+	receiver.lowered, raised = grid.Changes(receiver.flowGrid, receiver.lowered[:0])
+	for _, field := range receiver.flows {
+		if raised || !field.Ready() {
+			field.compute(grid, receiver.Diagonal)
+		} else if len(receiver.lowered) > 0 {
+			field.lower(grid, receiver.lowered, receiver.Diagonal)
+		}
+	}
+	if receiver.flowGrid != nil {
+		receiver.flowGrid.release()
+	}
+	receiver.flowGrid = grid
+}
+
+// worker search paths of jobs one by one, Navigation runs at most NAV_WORKER_LIMIT of them
+func (receiver *Navigation) worker() {
+	for job := range receiver.work {
+		receiver.buildPath(job)
 	}
 }
 
 func (receiver *Navigation) buildPath(job *NavJob) error {
-	mapData := make(pathfinding.MapData, len(receiver.mapDataTemplate))
-	copy(mapData, receiver.mapDataTemplate)
-	for i, _ := range receiver.mapDataTemplate {
-		mapData[i] = make([]int, len(receiver.mapDataTemplate[i]))
-		copy(mapData[i], receiver.mapDataTemplate[i])
-	}
-	for _, track := range job.input {
-		x, y := track.GetIndexes()
-		mapData[y][x] = pathfinding.WALL
-	}
-	mapData[job.from.Y][job.from.X] = pathfinding.START
-	mapData[job.to.Y][job.to.X] = pathfinding.STOP
-	graph := pathfinding.NewGraph(&mapData)
-	pathNodes := pathfinding.Astar(graph)
-	zones := make([]Zone, 0, len(pathNodes))
-	for _, node := range pathNodes {
-		zones = append(zones, Zone{
-			//lib has wrong y, x axis
-			X: node.Y,
-			Y: node.X,
-		})
-	}
-	job.output = zones
-	job.state = NJ_STATE_DONE
+	if job.Canceled() {
+		job.skipped = true
+	} else {
+		job.output = job.grid.FindPath(job.from, job.to, receiver.Diagonal)
+	}
+	job.grid.release()
+	job.grid = nil
+	job.setState(NJ_STATE_DONE)
 	if DEBUG_AI_PATH {
 		logger.Printf("cycleID %d ScheduledPath id %d: is complete \n", CycleID, job.jobId)
 	}
 	return nil
 }
 
-func (receiver *Navigation) SchedulePath(from Zone, to Zone, owner PathReceiver) error {
-	if from == to {
-		return fmt.Errorf("unable to schedule path: %w", ZoneCollisionError)
+// SchedulePath queue path search, owner receive it on one of next cycles unless returned job is canceled. Identical
+// jobs share one search
+func (receiver *Navigation) SchedulePath(from Zone, to Zone, owner PathReceiver, priority int) (*NavJob, error) {
+	if size := receiver.Location.ZoneSize(); from.X < 0 || from.Y < 0 || to.X < 0 || to.Y < 0 ||
+		from.X >= size.X || from.Y >= size.Y || to.X >= size.X || to.Y >= size.Y {
+		return nil, fmt.Errorf("unable to schedule path: %w", ZoneRangeError)
 	}
 	receiver.mutex.Lock()
 	defer receiver.mutex.Unlock()
+	return receiver.schedule(from, to, owner, priority)
+}
+
+// schedule job, must be called under lock
+func (receiver *Navigation) schedule(from Zone, to Zone, owner PathReceiver, priority int) (*NavJob, error) {
+	if from == to {
+		return nil, fmt.Errorf("unable to schedule path: %w", ZoneCollisionError)
+	}
 	id := genId()
-	receiver.queue = append(receiver.queue, &NavJob{
-		jobId:  id,
-		input:  nil,
-		from:   from,
-		to:     to,
-		output: nil,
-		state:  NJ_STATE_NEW,
-		owner:  owner,
-	})
+	job := &NavJob{
+		jobId:    id,
+		priority: priority,
+		grid:     nil,
+		from:     from,
+		to:       to,
+		output:   nil,
+		state:    NJ_STATE_NEW,
+		owner:    owner,
+		queuedAt: time.Now(),
+	}
+	receiver.queue = append(receiver.queue, job)
 	if DEBUG_AI_PATH {
 		logger.Printf("cycleID %d SchedulePath id %d: %d, %d -> %d, %d \n", CycleID, id, from.X, from.Y, to.X, to.Y)
 	}
-	return nil
+	return job, nil
 }
 
-func NewNavigation(location *Location, collider *collider.Collider) (*Navigation, error) {
-	var template pathfinding.MapData
+// Close stop workers, no path is searched after
+func (receiver *Navigation) Close() error {
+	close(receiver.work)
+	return nil
+}
 
+// newNavigation build navigation with room for given number of workers, none is started
+func newNavigation(location *Location, collider *collider.Collider, workers int) *Navigation {
+	navigation := &Navigation{
+		Location: location,
+		Collider: collider,
+		Cost:     ZoneCost,
+		FlowCost: StaticZoneCost,
+		queue:    make([]*NavJob, 0, 10),
+		searched: make(map[[2]Zone]*NavJob),
+		work:     make(chan *NavJob, workers),
+		mutex:    sync.Mutex{},
+		NavData:  make([][]Zone, 0, 10),
+		flows:    make(map[Zone]*FlowField),
+		routes:   make(map[PathFollower]*NavRoute),
+	}
 	if location != nil {
-		x, y := location.sizeZone.X, location.sizeZone.Y
-		template = *pathfinding.NewMapData(y, x)
-		for r := 0; r < location.sizeZone.Y; r++ {
-			for c := 0; c < location.sizeZone.X; c++ {
-				template[r][c] = pathfinding.LAND
-			}
-		}
+		location.SubscribeZones(navigation)
 	}
+	return navigation
+}
 
-	return &Navigation{
-		Location:        location,
-		Collider:        collider,
-		queue:           make([]*NavJob, 0, 10),
-		mutex:           sync.Mutex{},
-		mapDataTemplate: template,
-		NavData:         make([][]Zone, 0, 10),
-	}, nil
+func NewNavigation(location *Location, collider *collider.Collider) (*Navigation, error) {
+	workers := minInt(runtime.NumCPU(), NAV_WORKER_LIMIT)
+	navigation := newNavigation(location, collider, workers)
+	for i := 0; i < workers; i++ {
+		go navigation.worker()
+	}
+	return navigation, nil
 }
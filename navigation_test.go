@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+type pathSink chan []Zone
+
+func (receiver pathSink) ReceivePath(path []Zone, jobId int64) {
+	receiver <- path
+}
+
+func TestNavigationJobs(t *testing.T) {
+	location, _ := NewLocation(Point{}, Size{W: 100, H: 50})
+	location.SetupZones(Point{X: 10, Y: 5})
+	navigation, _ := NewNavigation(location, nil)
+	defer navigation.Close()
+	first, second, canceled := make(pathSink, 1), make(pathSink, 1), make(pathSink, 1)
+	from, to := Zone{}, Zone{X: 5, Y: 5}
+	navigation.SchedulePath(from, to, first, NAV_PRIORITY_LOW)
+	navigation.SchedulePath(from, to, second, NAV_PRIORITY_HIGH)
+	job, _ := navigation.SchedulePath(from, Zone{X: 9, Y: 9}, canceled, NAV_PRIORITY_HIGH)
+	job.Cancel()
+	if _, err := navigation.SchedulePath(from, from, first, NAV_PRIORITY_LOW); err == nil {
+		t.Error("path to itself scheduled")
+	}
+	for i := 0; i < 100 && navigation.Metrics().Done < 2; i++ {
+		navigation.Execute(0)
+		time.Sleep(time.Millisecond)
+	}
+	for _, sink := range []pathSink{first, second} {
+		select {
+		case path := <-sink:
+			if len(path) != 11 || path[0] != from || path[10] != to {
+				t.Error("path", path)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("path not delivered")
+		}
+	}
+	if len(canceled) != 0 {
+		t.Error("canceled job delivered")
+	}
+	metrics := navigation.Metrics()
+	if metrics.Done != 2 || metrics.Canceled != 1 || metrics.Deduplicated != 1 || metrics.Queued+metrics.Working != 0 {
+		t.Errorf("metrics %+v", metrics)
+	}
+	if len(navigation.queue) != 0 {
+		t.Error("queue not compacted", len(navigation.queue))
+	}
+}
+
+func TestNavigationCanceledLeader(t *testing.T) {
+	location, _ := NewLocation(Point{}, Size{W: 100, H: 50})
+	location.SetupZones(Point{X: 10, Y: 5})
+	navigation := newNavigation(location, nil, 1) //no workers, test run the searches
+	work := func() {
+		select {
+		case job := <-navigation.work:
+			navigation.buildPath(job)
+		default:
+			t.Fatal("no search dispatched")
+		}
+	}
+	leader, follower := make(pathSink, 1), make(pathSink, 1)
+	from, to := Zone{}, Zone{X: 5, Y: 5}
+	job, _ := navigation.SchedulePath(from, to, leader, NAV_PRIORITY_HIGH)
+	navigation.SchedulePath(from, to, follower, NAV_PRIORITY_LOW)
+	navigation.Execute(0)
+	job.Cancel() //after dispatch, before worker took it
+	work()
+	navigation.Execute(0) //follower lead own search
+	work()
+	navigation.Execute(0)
+	select {
+	case path := <-follower:
+		if len(path) != 11 || path[0] != from || path[10] != to {
+			t.Error("path", path)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("path not delivered")
+	}
+	if len(leader) != 0 {
+		t.Error("canceled job delivered")
+	}
+}
+
+type testFollower struct {
+	pathSink
+	self Trackable
+}
+
+func (receiver *testFollower) PathPosition() (Zone, Trackable) {
+	return Zone{}, receiver.self
+}
+
+func TestNavigationRepair(t *testing.T) {
+	location, _ := NewLocation(Point{}, Size{W: 100, H: 50})
+	location.SetupZones(Point{X: 10, Y: 5})
+	navigation, _ := NewNavigation(location, nil)
+	defer navigation.Close()
+	follower := &testFollower{pathSink: make(pathSink, 1), self: slotTarget(Point{})}
+	receive := func() []Zone {
+		for i := 0; i < 100; i++ {
+			navigation.Execute(0)
+			select {
+			case path := <-follower.pathSink:
+				return path
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return nil
+	}
+	navigation.SchedulePath(Zone{}, Zone{X: 9}, follower, NAV_PRIORITY_NORMAL)
+	if path := receive(); len(path) != 10 {
+		t.Fatal("straight path expected", path)
+	}
+	//own move and tank stepping on the way
+	tank := slotTarget(Point{})
+	location.zones[LOCATION_LAYER_UNIT][0][4] = tank
+	navigation.OnZoneChange([]ZoneChange{{Zone: Zone{X: 1}, Object: follower.self}, {Zone: Zone{X: 4}, Object: tank}})
+	path := receive()
+	if len(path) != 12 || path[0] != (Zone{}) || path[11] != (Zone{X: 9}) || zoneIndex(path, Zone{X: 4}) != -1 {
+		t.Error("bypass expected", path)
+	}
+	//wall gone next to the way
+	navigation.OnZoneChange([]ZoneChange{{Zone: Zone{X: 5, Y: 2}, Was: &Wall{}}})
+	if path := receive(); len(path) != 12 {
+		t.Error("replanned path expected", path)
+	}
+	//replan on the way is canceled with the route
+	navigation.OnZoneChange([]ZoneChange{{Zone: Zone{X: 5, Y: 2}, Was: &Wall{}}})
+	navigation.Execute(0)
+	navigation.Forget(follower)
+	if path := receive(); path != nil {
+		t.Error("path of forgotten route delivered", path)
+	}
+	if metrics := navigation.Metrics(); metrics.Repaired != 1 || metrics.Replanned != 2 || metrics.Canceled != 1 {
+		t.Errorf("metrics %+v", metrics)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// NetClient render host snapshots and send local player commands, no game logic here
+type NetClient struct {
+	*KeyboardRepeater
+	*GameConfig
+	*BlueprintManager
+	*SpawnManager
+	Renderer
+	Name     string
+	Player   int
+	peer     *netPeer
+	objects  map[int64]ObjectInterface
+	free     map[string][]ObjectInterface //rendered objects for reuse
+	broken   map[string]bool              //blueprint which can't be built
+	snapshot []ObjectSnapshot
+	mutex    sync.Mutex
+}
+
+func (receiver *NetClient) Run(done EventChanel) {
+	receiver.BlueprintManager.AddLoaderPackage(NewJsonPackage())
+	receiver.BlueprintManager.GameConfig = receiver.GameConfig
+	receiver.BlueprintManager.EventChanel = receiver.SpawnManager.UnitEventChanel
+	go func() {
+		for range receiver.SpawnManager.UnitEventChanel {
+			//objects are never spawned locally, nobody care about their events
+		}
+	}()
+
+	result := GAME_END_LOSE
+	if err := receiver.join(); err != nil {
+		logger.Println(err)
+	} else {
+		result = receiver.play()
+	}
+
+	receiver.mutex.Lock()
+	receiver.snapshot = receiver.snapshot[0:0]
+	receiver.mutex.Unlock()
+	time.Sleep(2 * CYCLE) //let Update clear the screen
+
+	var screen *Screen
+	if result == GAME_END_WIN {
+		screen, _ = NewWinScreen()
+	} else {
+		screen, _ = NewLoseScreen()
+	}
+	if screen != nil {
+		receiver.Renderer.Add(screen)
+		time.Sleep(10 * time.Second)
+		receiver.Renderer.Remove(screen)
+	}
+	done <- Event{EType: result}
+}
+
+func (receiver *NetClient) join() error {
+	receiver.peer.Send(NetMessage{Type: NET_MSG_HELLO, Name: receiver.Name})
+	welcome, err := receiver.peer.Receive()
+	if err != nil {
+		return err
+	}
+	switch welcome.Type {
+	case NET_MSG_WELCOME:
+		receiver.Player = welcome.Player
+		logger.Printf("joined as player %d \n", welcome.Player+1)
+		return nil
+	case NET_MSG_ERROR:
+		return fmt.Errorf("%w: %s", NetRejectedError, welcome.Error)
+	}
+	return NetProtocolError
+}
+
+// play forward commands and receive snapshots until game end or disconnect
+func (receiver *NetClient) play() int {
+	keyboard := receiver.KeyboardRepeater.SubscribeAll()
+	defer receiver.KeyboardRepeater.Unsubscribe(keyboard)
+	control, _ := controller.NewPlayerControl(keyboard, receiver.GameConfig.KeyBindings[0], receiver.GameConfig.Input.HoldConfig)
+	control.Enable()
+	defer control.Disable()
+	go func() {
+		for command := range control.GetCommandChanel() {
+			command := command
+			receiver.peer.Send(NetMessage{Type: NET_MSG_COMMAND, Command: &command})
+		}
+	}()
+
+	defer receiver.peer.Close()
+	for {
+		message, err := receiver.peer.Receive()
+		if err != nil {
+			logger.Println("connection to host lost: ", err)
+			return GAME_END_LOSE
+		}
+		switch message.Type {
+		case NET_MSG_SNAPSHOT:
+			receiver.mutex.Lock()
+			receiver.snapshot = message.Objects
+			receiver.mutex.Unlock()
+		case NET_MSG_END:
+			return message.Result
+		}
+	}
+}
+
+// Update apply last snapshot, run in pipeline so render is not disturbed
+func (receiver *NetClient) Update(timeLeft time.Duration) error {
+	receiver.mutex.Lock()
+	snapshot := receiver.snapshot
+	receiver.mutex.Unlock()
+
+	seen := make(map[int64]bool, len(snapshot))
+	for _, item := range snapshot {
+		seen[item.ID] = true
+		object, ok := receiver.objects[item.ID]
+		if !ok {
+			if object = receiver.build(item.Blueprint); object == nil {
+				continue
+			}
+			receiver.objects[item.ID] = object
+			receiver.Renderer.Add(object)
+		}
+		object.Move(item.X, item.Y)
+		if item.State != "" {
+			if state := getObjectState(object); state != nil && state.GetPath() != item.State {
+				if err := state.Enter(item.State); err != nil && DEBUG_STATE {
+					logger.Println(err)
+				}
+			}
+		}
+		switch object.(type) {
+		case *Unit:
+			object.(*Unit).HP = item.HP
+		case *Wall:
+			object.(*Wall).HP = item.HP
+		}
+	}
+	for id, object := range receiver.objects {
+		if !seen[id] {
+			receiver.Renderer.Remove(object)
+			delete(receiver.objects, id)
+			blueprint := object.GetAttr().Blueprint
+			receiver.free[blueprint] = append(receiver.free[blueprint], object)
+		}
+	}
+	return nil
+}
+
+func (receiver *NetClient) build(blueprint string) ObjectInterface {
+	if free := receiver.free[blueprint]; len(free) > 0 {
+		object := free[len(free)-1]
+		receiver.free[blueprint] = free[:len(free)-1]
+		object.Reset()
+		return object
+	}
+	if receiver.broken[blueprint] {
+		return nil
+	}
+	if !receiver.SpawnManager.HasBuilder(blueprint) {
+		if _, err := receiver.BlueprintManager.Get(blueprint); err != nil {
+			logger.Printf("unable to render %s: %s \n", blueprint, err)
+			receiver.broken[blueprint] = true
+			return nil
+		}
+		recursiveRequire(blueprint, receiver.BlueprintManager, receiver.SpawnManager, nil)
+	}
+	object, err := receiver.SpawnManager.Build(blueprint)
+	if err != nil {
+		logger.Println(err)
+		receiver.broken[blueprint] = true
+		return nil
+	}
+	object.Reset()
+	return object
+}
+
+func NewNetClient(addr string) (*NetClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &NetClient{
+		peer:     newNetPeer(conn),
+		objects:  make(map[int64]ObjectInterface, 100),
+		free:     make(map[string][]ObjectInterface, 10),
+		broken:   make(map[string]bool),
+		snapshot: make([]ObjectSnapshot, 0),
+	}, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// NetHost run authoritative game, remote players send commands and receive snapshots
+type NetHost struct {
+	spawner  *SpawnManager
+	listener net.Listener
+	peers    []*netPeer
+	mutex    sync.Mutex
+}
+
+// Accept wait for count remote players, first is game slot index of first remote player
+func (receiver *NetHost) Accept(count, first int) ([]*Player, error) {
+	players := make([]*Player, 0, count)
+	for len(players) < count {
+		conn, err := receiver.listener.Accept()
+		if err != nil {
+			return players, err
+		}
+		player, err := receiver.join(conn, first+len(players))
+		if err != nil {
+			logger.Println(err)
+			continue
+		}
+		players = append(players, player)
+	}
+	go receiver.reject()
+	return players, nil
+}
+
+func (receiver *NetHost) join(conn net.Conn, slot int) (*Player, error) {
+	peer := newNetPeer(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	hello, err := peer.Receive()
+	conn.SetReadDeadline(time.Time{})
+	if err == nil && hello.Type != NET_MSG_HELLO {
+		err = NetProtocolError
+	}
+	if err != nil {
+		peer.Close()
+		return nil, err
+	}
+
+	commands := make(chan controller.Command)
+	control, _ := controller.NewRemoteControl(commands)
+	name := hello.Name
+	if name == "" {
+		name = "Remote" + strconv.Itoa(slot+1)
+	}
+	player, _ := NewPlayer(name, control)
+	go receiver.read(peer, commands)
+
+	receiver.mutex.Lock()
+	receiver.peers = append(receiver.peers, peer)
+	receiver.mutex.Unlock()
+	peer.Send(NetMessage{Type: NET_MSG_WELCOME, Player: slot})
+	logger.Printf("player %s joined from %s \n", name, conn.RemoteAddr())
+	return player, nil
+}
+
+func (receiver *NetHost) read(peer *netPeer, commands chan controller.Command) {
+	defer close(commands)
+	for {
+		message, err := peer.Receive()
+		if err != nil {
+			logger.Printf("peer %s disconnected: %s \n", peer.conn.RemoteAddr(), err)
+			receiver.remove(peer)
+			return
+		}
+		if message.Type == NET_MSG_COMMAND && message.Command != nil {
+			commands <- *message.Command
+		}
+	}
+}
+
+// reject late joiners, game already started
+func (receiver *NetHost) reject() {
+	for {
+		conn, err := receiver.listener.Accept()
+		if err != nil {
+			return
+		}
+		peer := newNetPeer(conn)
+		peer.Send(NetMessage{Type: NET_MSG_ERROR, Error: GameInProgressError.Error()})
+		peer.Close()
+	}
+}
+
+func (receiver *NetHost) remove(peer *netPeer) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	for i, candidate := range receiver.peers {
+		if candidate == peer {
+			receiver.peers = append(receiver.peers[:i], receiver.peers[i+1:]...)
+			break
+		}
+	}
+	peer.Close()
+}
+
+func (receiver *NetHost) broadcast(message NetMessage) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	for _, peer := range receiver.peers {
+		peer.Send(message)
+	}
+}
+
+// Update stream snapshot every cycle
+func (receiver *NetHost) Update(timeLeft time.Duration) error {
+	receiver.mutex.Lock()
+	empty := len(receiver.peers) == 0
+	receiver.mutex.Unlock()
+	if empty {
+		return nil
+	}
+	receiver.broadcast(NetMessage{Type: NET_MSG_SNAPSHOT, Cycle: CycleID, Objects: receiver.spawner.Snapshot()})
+	return nil
+}
+
+// End send game result and close all connections
+func (receiver *NetHost) End(result int) {
+	receiver.broadcast(NetMessage{Type: NET_MSG_END, Result: result})
+	receiver.Close()
+}
+
+func (receiver *NetHost) Close() error {
+	receiver.mutex.Lock()
+	for _, peer := range receiver.peers {
+		peer.Close()
+	}
+	receiver.peers = nil
+	receiver.mutex.Unlock()
+	return receiver.listener.Close()
+}
+
+func (receiver *NetHost) Addr() net.Addr {
+	return receiver.listener.Addr()
+}
+
+func NewNetHost(addr string, spawner *SpawnManager) (*NetHost, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &NetHost{
+		spawner:  spawner,
+		listener: listener,
+		peers:    make([]*netPeer, 0, 1),
+	}, nil
+}
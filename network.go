@@ -0,0 +1,171 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// protocol is newline separated json messages, one NetMessage per line
+const (
+	NET_MSG_HELLO    = "hello"    //client -> host, first message
+	NET_MSG_WELCOME  = "welcome"  //host -> client, player slot assigned
+	NET_MSG_COMMAND  = "command"  //client -> host, controller command
+	NET_MSG_SNAPSHOT = "snapshot" //host -> client, all spawned objects each cycle
+	NET_MSG_END      = "end"      //host -> client, game result
+	NET_MSG_ERROR    = "error"    //host -> client, join rejected
+)
+
+const NET_SEND_BUFFER = 8
+
+var (
+	NetProtocolError = errors.New("unexpected network message")
+	NetRejectedError = errors.New("join rejected by host")
+)
+
+type NetMessage struct {
+	Type    string              `json:"t"`
+	Name    string              `json:"name,omitempty"`
+	Player  int                 `json:"player,omitempty"`
+	Command *controller.Command `json:"cmd,omitempty"`
+	Cycle   int64               `json:"cycle,omitempty"`
+	Objects []ObjectSnapshot    `json:"obj,omitempty"`
+	Result  int                 `json:"result,omitempty"`
+	Error   string              `json:"err,omitempty"`
+}
+
+// ObjectSnapshot is minimum to render object on remote side, object is rebuilt there from blueprint
+type ObjectSnapshot struct {
+	ID        int64   `json:"i"`
+	Blueprint string  `json:"b"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	State     string  `json:"s,omitempty"`
+	HP        int     `json:"h,omitempty"`
+}
+
+// netPeer is one connection, send never block game: slow peer lose snapshots and commands, other messages
+// take place of oldest queued one
+type netPeer struct {
+	conn    net.Conn
+	decoder *json.Decoder
+	send    chan NetMessage
+	closed  bool
+	mutex   sync.Mutex //send and close
+	dropped int64
+}
+
+func (receiver *netPeer) Send(message NetMessage) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.closed {
+		return
+	}
+	for {
+		select {
+		case receiver.send <- message:
+			return
+		default:
+		}
+		if dropped := atomic.AddInt64(&receiver.dropped, 1); DEBUG_EVENT {
+			logger.Printf("peer %s is slow, message dropped, total %d \n", receiver.conn.RemoteAddr(), dropped)
+		}
+		if !reliableMessage(message.Type) {
+			return
+		}
+		select {
+		case <-receiver.send:
+		default:
+		}
+	}
+}
+
+func (receiver *netPeer) Receive() (NetMessage, error) {
+	var message NetMessage
+	err := receiver.decoder.Decode(&message)
+	return message, err
+}
+
+// Close flush pending messages and close connection
+func (receiver *netPeer) Close() {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if !receiver.closed {
+		receiver.closed = true
+		close(receiver.send)
+	}
+}
+
+// reliableMessage game flow message, unlike snapshots and commands next one never repeat it
+func reliableMessage(messageType string) bool {
+	return messageType != NET_MSG_SNAPSHOT && messageType != NET_MSG_COMMAND
+}
+
+func (receiver *netPeer) writer() {
+	encoder := json.NewEncoder(receiver.conn)
+	for message := range receiver.send {
+		if err := encoder.Encode(message); err != nil {
+			logger.Println(err)
+			break
+		}
+	}
+	receiver.conn.Close()
+}
+
+func newNetPeer(conn net.Conn) *netPeer {
+	instance := &netPeer{
+		conn:    conn,
+		decoder: json.NewDecoder(conn),
+		send:    make(chan NetMessage, NET_SEND_BUFFER),
+	}
+	go instance.writer()
+	return instance
+}
+
+// Snapshot collect all spawned objects built from blueprint
+func (manager *SpawnManager) Snapshot() []ObjectSnapshot {
+	manager.deSpawnMutex.Lock()
+	manager.spawnMutex.Lock()
+	defer manager.spawnMutex.Unlock()
+	defer manager.deSpawnMutex.Unlock()
+	result := make([]ObjectSnapshot, 0, len(manager.spawned))
+	for object, spawned := range manager.spawned {
+		if !spawned || object.GetAttr().Blueprint == "" {
+			continue
+		}
+		xy := object.GetXY()
+		snapshot := ObjectSnapshot{
+			ID:        object.GetAttr().ID,
+			Blueprint: object.GetAttr().Blueprint,
+			X:         math.Round(xy.X*100) / 100,
+			Y:         math.Round(xy.Y*100) / 100,
+			State:     getObjectState(object).GetPath(),
+		}
+		switch object.(type) {
+		case *Unit:
+			snapshot.HP = object.(*Unit).HP
+		case *Wall:
+			snapshot.HP = object.(*Wall).HP
+		}
+		result = append(result, snapshot)
+	}
+	return result
+}
+
+func getObjectState(object ObjectInterface) *State {
+	switch object.(type) {
+	case *Unit:
+		return object.(*Unit).State
+	case *Wall:
+		return object.(*Wall).State
+	case *Projectile:
+		return object.(*Projectile).State
+	case *Collectable:
+		return object.(*Collectable).State
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNetLoopback(t *testing.T) {
+	host, err := NewNetHost("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+
+	joined := make(chan error)
+	var client *NetClient
+	go func() {
+		var err error
+		if client, err = NewNetClient(host.Addr().String()); err == nil {
+			client.Name = "friend"
+			err = client.join()
+		}
+		joined <- err
+	}()
+
+	players, err := host.Accept(1, 1)
+	if err != nil || len(players) != 1 {
+		t.Fatalf("accept players %v, err %v", players, err)
+	}
+	if err := <-joined; err != nil {
+		t.Fatal(err)
+	}
+	if client.Player != 1 || players[0].Name != "friend" {
+		t.Errorf("client slot %d, player name %s", client.Player, players[0].Name)
+	}
+
+	players[0].Control.Enable()
+	sent := controller.Command{CType: controller.CTYPE_FIRE, Pos: controller.PosIrrelevant, Action: true}
+	client.peer.Send(NetMessage{Type: NET_MSG_COMMAND, Command: &sent})
+	select {
+	case command := <-players[0].Control.GetCommandChanel():
+		if command != sent {
+			t.Errorf("expected %+v, got %+v", sent, command)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("command not delivered")
+	}
+
+	host.End(GAME_END_WIN)
+	message, err := client.peer.Receive()
+	if err != nil || message.Type != NET_MSG_END || message.Result != GAME_END_WIN {
+		t.Errorf("expected end message, got %+v, err %v", message, err)
+	}
+}
+
+func TestNetPeerEndDelivered(t *testing.T) {
+	local, remote := net.Pipe()
+	peer := newNetPeer(local)
+	//nobody reads yet, buffer is full of snapshots
+	for i := 0; i < NET_SEND_BUFFER*2; i++ {
+		peer.Send(NetMessage{Type: NET_MSG_SNAPSHOT, Cycle: int64(i)})
+	}
+	peer.Send(NetMessage{Type: NET_MSG_END, Result: GAME_END_WIN})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() { //forwarder racing with close
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				peer.Send(NetMessage{Type: NET_MSG_COMMAND})
+			}
+		}()
+	}
+	peer.Close()
+	wg.Wait()
+	decoder, last := json.NewDecoder(remote), NetMessage{}
+	for {
+		var message NetMessage
+		if err := decoder.Decode(&message); err != nil {
+			break
+		}
+		last = message
+	}
+	if last.Type != NET_MSG_END || last.Result != GAME_END_WIN {
+		t.Errorf("end message lost, last %+v", last)
+	}
+}
@@ -10,6 +10,7 @@ const (
 	CLIP_MODE_NONE = iota
 	CLIP_MODE_LT  		//left top
 	CLIP_MODE_RB		//right bottom
+	CLIP_MODE_CUT		//cut sprite by clip rect
 )
 
 var (
@@ -40,6 +41,7 @@ type ConsoleOutput interface {
 	MoveCursor(x int, y int)
 	CursorVisibility(visibility bool)
 	ClipMode(mode int)
+	ClipRect(x, y, w, h int)
 	Color(str string, color int) string
 	Clear()
 	Flush()
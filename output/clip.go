@@ -0,0 +1,186 @@
+package output
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const escReset = "\033[0m"
+
+// ClipRect is the visible area of output in 00 coord system
+type ClipRect struct {
+	X, Y, W, H int
+}
+
+func (r ClipRect) IsEmpty() bool {
+	return r.W <= 0 || r.H <= 0
+}
+
+func (r ClipRect) Intersect(x, y, w, h int) bool {
+	return x < r.X+r.W && x+w > r.X && y < r.Y+r.H && y+h > r.Y
+}
+
+// ClipSprite cut sprite string placed at x,y by rect, row by row and column by column.
+// Result must be placed at cx, cy. Escape sequences are respected: color state is restored
+// at the left edge and reset at the right edge, cursor forward (transparency) is shortened.
+func ClipSprite(str string, x, y int, rect ClipRect) (clipped string, cx, cy int, visible bool) {
+	if rect.IsEmpty() {
+		return "", x, y, false
+	}
+	lines := strings.Split(str, "\n")
+	from, to := rect.X-x, rect.X+rect.W-x
+	if to <= 0 || from > 0 && !lineReach(lines, from) {
+		return "", x, y, false
+	}
+	first := maxInt(rect.Y-y, 0)
+	last := minInt(rect.Y+rect.H-y, len(lines))
+	if first >= last {
+		return "", x, y, false
+	}
+
+	cx, cy = maxInt(x, rect.X), y+first
+	if from <= 0 && first == 0 && last == len(lines) && lineFits(lines, to) {
+		return str, cx, cy, true //fast path: fully inside
+	}
+
+	out := make([]string, 0, last-first)
+	for _, line := range lines[first:last] {
+		out = append(out, SliceLine(line, from, to))
+	}
+	return strings.Join(out, "\n"), cx, cy, true
+}
+
+// SliceLine return columns [from, to) of single sprite line
+func SliceLine(line string, from, to int) string {
+	if from < 0 {
+		from = 0
+	}
+	if to <= from {
+		return ""
+	}
+	var (
+		out    strings.Builder
+		sgr    []string //active color state
+		col    int
+		opened bool //color state restored, line content in progress
+	)
+	out.Grow(len(line))
+
+	open := func() {
+		if opened {
+			return
+		}
+		opened = true
+		for _, seq := range sgr {
+			out.WriteString(seq)
+		}
+	}
+
+	for i := 0; i < len(line) && col < to; {
+		if line[i] == '\033' {
+			seq, final, param := readEscape(line, i)
+			i += len(seq)
+			switch final {
+			case 'm':
+				if param == "" || param == "0" {
+					sgr = sgr[0:0]
+				} else {
+					sgr = append(sgr, seq)
+				}
+				if opened {
+					out.WriteString(seq)
+				}
+			case 'C':
+				n := cursorForward(param)
+				if cut := minInt(col+n, to) - maxInt(col, from); cut > 0 {
+					open()
+					out.WriteString("\033[" + strconv.Itoa(cut) + "C")
+				}
+				col += n
+			default:
+				if opened {
+					out.WriteString(seq)
+				}
+			}
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		if line[i] == '\r' {
+			i += size
+			continue
+		}
+		if col >= from {
+			open()
+			out.WriteString(line[i : i+size])
+		}
+		col++
+		i += size
+	}
+	if opened && len(sgr) > 0 {
+		out.WriteString(escReset)
+	}
+	return out.String()
+}
+
+func lineFits(lines []string, width int) bool {
+	for _, line := range lines {
+		if len(line) <= width { //bytes >= columns, no need to parse
+			continue
+		}
+		if visibleLen(line) > width {
+			return false
+		}
+	}
+	return true
+}
+
+func lineReach(lines []string, col int) bool {
+	for _, line := range lines {
+		if len(line) > col && visibleLen(line) > col {
+			return true
+		}
+	}
+	return false
+}
+
+func visibleLen(line string) int {
+	col := 0
+	for i := 0; i < len(line); {
+		if line[i] == '\033' {
+			seq, final, param := readEscape(line, i)
+			i += len(seq)
+			if final == 'C' {
+				col += cursorForward(param)
+			}
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		if line[i] != '\r' {
+			col++
+		}
+		i += size
+	}
+	return col
+}
+
+// readEscape read CSI sequence started at i, unknown sequences treated as 2 byte escape
+func readEscape(line string, i int) (seq string, final byte, param string) {
+	if i+1 >= len(line) || line[i+1] != '[' {
+		end := minInt(i+2, len(line))
+		return line[i:end], 0, ""
+	}
+	for j := i + 2; j < len(line); j++ {
+		if c := line[j]; c >= 0x40 && c <= 0x7E {
+			return line[i : j+1], c, line[i+2 : j]
+		}
+	}
+	return line[i:], 0, ""
+}
+
+func cursorForward(param string) int {
+	if n, err := strconv.Atoi(param); err == nil {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,51 @@
+package output
+
+import "testing"
+
+func TestSliceLine(t *testing.T) {
+	cases := []struct {
+		line     string
+		from, to int
+		expect   string
+	}{
+		{"abcdef", 2, 4, "cd"},
+		{"abc", 0, 10, "abc"},
+		{"\033[31mabc\033[0m", 1, 2, "\033[31mb\033[0m"},
+		{"\033[31mab\033[0mcd", 1, 4, "\033[31mb\033[0mcd"},
+		{"a\033[4Cb", 2, 6, "\033[3Cb"},
+		{"a\033[4Cb", 0, 3, "a\033[2C"},
+		{"██░░", 1, 3, "█░"},
+		{"abc\r", 0, 3, "abc"},
+	}
+	for _, c := range cases {
+		if got := SliceLine(c.line, c.from, c.to); got != c.expect {
+			t.Errorf("SliceLine(%q, %d, %d) = %q, expect %q", c.line, c.from, c.to, got, c.expect)
+		}
+	}
+}
+
+func TestClipSprite(t *testing.T) {
+	rect := ClipRect{W: 10, H: 5}
+
+	if _, _, _, visible := ClipSprite("ab\ncd", 10, 0, rect); visible {
+		t.Error("sprite right of rect must be invisible")
+	}
+	if _, _, _, visible := ClipSprite("ab\ncd", -2, 0, rect); visible {
+		t.Error("sprite left of rect must be invisible")
+	}
+
+	str, x, y, visible := ClipSprite("abc\ndef\nghi", -1, -1, rect)
+	if !visible || x != 0 || y != 0 || str != "ef\nhi" {
+		t.Errorf("left top cut: %q %d %d %v", str, x, y, visible)
+	}
+
+	str, x, y, visible = ClipSprite("abc\ndef\nghi", 8, 3, rect)
+	if !visible || x != 8 || y != 3 || str != "ab\nde" {
+		t.Errorf("right bottom cut: %q %d %d %v", str, x, y, visible)
+	}
+
+	in := "abc\ndef"
+	if str, _, _, _ = ClipSprite(in, 1, 1, rect); str != in {
+		t.Errorf("inner sprite must be untouched: %q", str)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -29,10 +30,16 @@ type ConsoleOutputLine struct {
 	rowsRepaintCnt  int
 	needFullRepaint bool
 	clipMode  int
+	clipRect  ClipRect
+	customClip bool
+	clipLock  sync.Mutex //clip rect is changed by resize dispatcher
 	width, height, wTolerance, hTolerance int
 }
 
 func (co *ConsoleOutputLine) PrintSprite(stringer fmt.Stringer, x, y, w, h int) (n int, err error) {
+	if co.clipMode == CLIP_MODE_CUT {
+		return co.printCut(stringer, x, y)
+	}
 	if co.clipTest(x,y,w,h) {
 		if DEBUG {
 			log.Print("\n clip: ", x,y,w,h, output.Width(), output.Height())
@@ -40,42 +47,48 @@ func (co *ConsoleOutputLine) PrintSprite(stringer fmt.Stringer, x, y, w, h int)
 		return 0, OutOfRenderRangeError
 	}
 	str := stringer.String()
-	for i := maxInt(y, 0); i < y + h; i++ {
-		co.rowsRepaint[i] = true
-		co.rowsRepaintCnt++
-	}
+	co.markRows(y, h)
 	str = co.MoveTo(str, x, y)
 	return output.Print(str)
 }
 
 func (co *ConsoleOutputLine) PrintDynamicSprite(stringer fmt.Stringer, x, y, w, h, xOld, yOld, wOld, hOld int) (n int, err error) {
-	if co.clipTest(x,y,w,h) {
+	return co.PrintSprite(stringer, x, y, w, h)
+}
+
+func (co *ConsoleOutputLine) printCut(stringer fmt.Stringer, x, y int) (n int, err error) {
+	clip := co.clip()
+	str, cx, cy, visible := ClipSprite(stringer.String(), x, y, clip)
+	if !visible {
 		if DEBUG {
-			log.Print("\n clip: ", x,y,w,h, output.Width(), output.Height())
+			log.Print("\n clip: ", x, y, clip)
 		}
 		return 0, OutOfRenderRangeError
 	}
-	str := stringer.String()
-	for i := maxInt(y, 0); i < y + h; i++ {
+	co.markRows(cy, strings.Count(str, "\n") + 1)
+	str = co.MoveTo(str, cx, cy)
+	return output.Print(str)
+}
+
+func (co *ConsoleOutputLine) markRows(y, h int) {
+	for i := maxInt(y, 0); i < minInt(y + h, len(co.rowsRepaint)); i++ {
 		co.rowsRepaint[i] = true
 		co.rowsRepaintCnt++
 	}
-	str = co.MoveTo(str, x, y)
-	return output.Print(str)
 }
 
 func (co *ConsoleOutputLine) Print(str string) (n int, err error) {
 	strH := len(strings.Split(str, "\n"))
-	if co.clipTest(0, co.currY,0, strH) {
+	if clip := co.clip(); co.clipMode == CLIP_MODE_CUT && !clip.Intersect(clip.X, co.currY, 1, strH) {
+		return 0, OutOfRenderRangeError
+	}
+	if co.clipMode != CLIP_MODE_CUT && co.clipTest(0, co.currY,0, strH) {
 		if DEBUG {
 			log.Print("\n clip: ", co.currY + strH, output.Height())
 		}
 		return 0, OutOfRenderRangeError
 	}
-	for i := maxInt(co.currY, 0); i < co.currY+ strH; i++ {
-		co.rowsRepaint[i] = true
-		co.rowsRepaintCnt++
-	}
+	co.markRows(co.currY, strH)
 	return output.Print(str)
 }
 
@@ -99,6 +112,23 @@ func (co *ConsoleOutputLine) ClipMode(mode int) {
 	co.clipMode = mode
 }
 
+//set custom clip rect (viewport), empty rect restore full screen clipping
+func (co *ConsoleOutputLine) ClipRect(x, y, w, h int) {
+	co.clipLock.Lock()
+	defer co.clipLock.Unlock()
+	co.clipRect = ClipRect{X: x, Y: y, W: w, H: h}
+	co.customClip = !co.clipRect.IsEmpty()
+	if !co.customClip {
+		co.clipRect = ClipRect{W: co.width, H: co.height}
+	}
+}
+
+func (co *ConsoleOutputLine) clip() ClipRect {
+	co.clipLock.Lock()
+	defer co.clipLock.Unlock()
+	return co.clipRect
+}
+
 func (co *ConsoleOutputLine) MoveTo(str string, x int, y int) (out string) {
 	return output.MoveTo(str, x + 1, y + 1)
 }
@@ -192,6 +222,11 @@ func updateSizesDispatcher(cOut *ConsoleOutputLine)  {
 				cOut.rowsRepaint = cOut.rowsRepaint[:withTolerance]
 			}
 			cOut.width, cOut.height = w,h
+			cOut.clipLock.Lock()
+			if !cOut.customClip {
+				cOut.clipRect = ClipRect{W: w, H: h}
+			}
+			cOut.clipLock.Unlock()
 			cOut.needFullRepaint = true
 		}
 		time.AfterFunc(time.Second / 2, check)
@@ -0,0 +1,248 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	output "github.com/buger/goterm"
+	"io"
+	"strings"
+	"sync"
+)
+
+// FULL_FRAME_MARK is in every frame which repaint whole screen, everything after it is self-contained
+const FULL_FRAME_MARK = "\033[2J"
+
+// ConsoleOutputStream is ConsoleOutputLine for any writer (ssh session, websocket), size set by owner.
+// Frames are written async, if writer is busy frame is dropped and next one repaint whole screen,
+// so slow consumer never stall render
+type ConsoleOutputStream struct {
+	screen          bytes.Buffer
+	frames          chan []byte
+	currY           int
+	rowsRepaint     []bool
+	rowsRepaintCnt  int
+	needFullRepaint bool
+	clipMode        int
+	clipRect        ClipRect
+	customClip      bool
+	cursorHidden    bool
+	width, height   int
+	mutex           sync.Mutex
+	err             error
+	dropped         int64
+	done            chan struct{}
+}
+
+func (co *ConsoleOutputStream) PrintSprite(stringer fmt.Stringer, x, y, w, h int) (n int, err error) {
+	str, cx, cy, visible := stringer.String(), x, y, true
+	if clip := co.clip(); co.clipMode == CLIP_MODE_CUT {
+		str, cx, cy, visible = ClipSprite(str, x, y, clip)
+	} else if co.clipMode != CLIP_MODE_NONE {
+		visible = clip.Intersect(x, y, w, h)
+	}
+	if !visible {
+		return 0, OutOfRenderRangeError
+	}
+	co.markRows(cy, strings.Count(str, "\n")+1)
+	return co.screen.WriteString(co.MoveTo(str, cx, cy))
+}
+
+func (co *ConsoleOutputStream) PrintDynamicSprite(stringer fmt.Stringer, x, y, w, h, xOld, yOld, wOld, hOld int) (n int, err error) {
+	return co.PrintSprite(stringer, x, y, w, h)
+}
+
+func (co *ConsoleOutputStream) Print(str string) (n int, err error) {
+	strH := strings.Count(str, "\n") + 1
+	if clip := co.clip(); co.clipMode != CLIP_MODE_NONE && !clip.Intersect(clip.X, co.currY, 1, strH) {
+		return 0, OutOfRenderRangeError
+	}
+	co.markRows(co.currY, strH)
+	return co.screen.WriteString(str)
+}
+
+func (co *ConsoleOutputStream) markRows(y, h int) {
+	for i := maxInt(y, 0); i < minInt(y+h, len(co.rowsRepaint)); i++ {
+		co.rowsRepaint[i] = true
+		co.rowsRepaintCnt++
+	}
+}
+
+func (co *ConsoleOutputStream) MoveTo(str string, x int, y int) (out string) {
+	return output.MoveTo(str, x+1, y+1)
+}
+
+func (co *ConsoleOutputStream) MoveCursor(x int, y int) {
+	co.currY = y
+	fmt.Fprintf(&co.screen, "\033[%d;%dH", y+1, x+1)
+}
+
+func (co *ConsoleOutputStream) CursorVisibility(visibility bool) {
+	co.cursorHidden = !visibility
+	if visibility {
+		co.screen.WriteString("\033[?25h")
+	} else {
+		co.screen.WriteString("\033[?25l")
+	}
+}
+
+func (co *ConsoleOutputStream) ClipMode(mode int) {
+	co.clipMode = mode
+}
+
+func (co *ConsoleOutputStream) ClipRect(x, y, w, h int) {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	co.clipRect = ClipRect{X: x, Y: y, W: w, H: h}
+	co.customClip = !co.clipRect.IsEmpty()
+	if !co.customClip {
+		co.clipRect = ClipRect{W: co.width, H: co.height}
+	}
+}
+
+func (co *ConsoleOutputStream) clip() ClipRect {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	return co.clipRect
+}
+
+func (co *ConsoleOutputStream) Color(str string, color int) string {
+	return output.Color(str, color)
+}
+
+func (co *ConsoleOutputStream) Clear() {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	if co.needFullRepaint {
+		co.screen.WriteString(FULL_FRAME_MARK)
+		if co.cursorHidden {
+			co.screen.WriteString("\033[?25l")
+		}
+		co.needFullRepaint = false
+		for index := range co.rowsRepaint {
+			co.rowsRepaint[index] = false
+		}
+		co.rowsRepaintCnt = 0
+		return
+	}
+	if co.rowsRepaintCnt < 1 {
+		return
+	}
+	for index, repaint := range co.rowsRepaint {
+		if repaint {
+			fmt.Fprintf(&co.screen, "\033[%d;1H\033[2K", index+1)
+		}
+		co.rowsRepaint[index] = false
+	}
+	co.rowsRepaintCnt = 0
+}
+
+// Flush pass frame to writer, never block
+func (co *ConsoleOutputStream) Flush() {
+	frame := make([]byte, co.screen.Len())
+	copy(frame, co.screen.Bytes())
+	co.screen.Reset()
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	if co.frames == nil {
+		return
+	}
+	select {
+	case co.frames <- frame:
+	default:
+		co.needFullRepaint = true
+		co.dropped++
+	}
+}
+
+func (co *ConsoleOutputStream) Width() int {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	return co.width
+}
+
+func (co *ConsoleOutputStream) Height() int {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	return co.height
+}
+
+// Resize on terminal window change
+func (co *ConsoleOutputStream) Resize(w, h int) {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	if w == co.width && h == co.height {
+		return
+	}
+	if len(co.rowsRepaint) < h {
+		co.rowsRepaint = append(co.rowsRepaint, make([]bool, h-len(co.rowsRepaint))...)
+	}
+	co.width, co.height = w, h
+	if !co.customClip {
+		co.clipRect = ClipRect{W: w, H: h}
+	}
+	co.needFullRepaint = true
+}
+
+// Repaint force full screen repaint, eg. for late joined consumer
+func (co *ConsoleOutputStream) Repaint() {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	co.needFullRepaint = true
+}
+
+// Err return write error, stream is dead after it
+func (co *ConsoleOutputStream) Err() error {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	return co.err
+}
+
+func (co *ConsoleOutputStream) Dropped() int64 {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	return co.dropped
+}
+
+// Close stop writer and wait till pending frame is written
+func (co *ConsoleOutputStream) Close() {
+	co.stop()
+	<-co.done
+}
+
+func (co *ConsoleOutputStream) stop() {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	if co.frames != nil {
+		close(co.frames)
+		co.frames = nil
+	}
+}
+
+func (co *ConsoleOutputStream) write(writer io.Writer, frames chan []byte) {
+	defer close(co.done)
+	for frame := range frames {
+		if _, err := writer.Write(frame); err != nil {
+			co.mutex.Lock()
+			co.err = err
+			co.mutex.Unlock()
+			co.stop()
+			for range frames {
+			}
+			return
+		}
+	}
+}
+
+func NewConsoleOutputStream(writer io.Writer, width, height int) (*ConsoleOutputStream, error) {
+	instance := &ConsoleOutputStream{
+		frames:          make(chan []byte, 1),
+		done:            make(chan struct{}),
+		rowsRepaint:     make([]bool, height),
+		needFullRepaint: true,
+		width:           width,
+		height:          height,
+		clipRect:        ClipRect{W: width, H: height},
+	}
+	go instance.write(writer, instance.frames)
+	return instance, nil
+}
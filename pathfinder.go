@@ -0,0 +1,291 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// zone traversal costs, empty zone is 10 so other costs are relative to it
+const (
+	PATH_COST_BLOCKED = -1
+	PATH_COST_FREE    = 10
+	PATH_COST_ICE     = 14 //slippery, tank slides past turns
+	PATH_COST_FOREST  = 16 //slow
+	PATH_COST_UNIT    = 40 //tank in the way may move off or be shot
+	PATH_COST_SHOT    = 15 //one shot at destructible obstacle, about reload time in zones of move
+	PATH_SHOT_DAMAGE  = 40 //damage of default projectile
+	PATH_COST_DIAG    = 14 //diagonal step weight, straight one is 10
+)
+
+var (
+	pathGridPool   = sync.Pool{New: func() interface{} { return new(PathGrid) }}
+	pathSearchPool = sync.Pool{New: func() interface{} { return new(pathSearch) }}
+	pathSteps4     = []Zone{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}}
+	pathSteps8     = []Zone{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}, {X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1}}
+)
+
+// ZoneCost traversal cost of zone by objects of all location layers in it (nil for empty layer)
+func ZoneCost(objects []Trackable) int32 {
+	return zoneCost(objects, true)
+}
+
+// StaticZoneCost cost without tanks in the way, they move off long before shared flow field is recalculated
+func StaticZoneCost(objects []Trackable) int32 {
+	return zoneCost(objects, false)
+}
+
+func zoneCost(objects []Trackable, units bool) int32 {
+	cost := int32(PATH_COST_FREE)
+	for _, trackable := range objects {
+		switch object := trackable.(type) {
+		case *Unit:
+			if object.HasTag("base") {
+				return PATH_COST_BLOCKED
+			}
+			if units {
+				cost += PATH_COST_UNIT
+			}
+		case *Wall:
+			switch {
+			case object.destroyed: //disappearing, in the way for a while
+				cost += PATH_COST_UNIT
+			case object.HasTag("water"):
+				return PATH_COST_BLOCKED
+			case object.HasTag("obstacle") && !object.HasTag("low"):
+				if !object.HasTag("vulnerable") {
+					return PATH_COST_BLOCKED
+				}
+				shots := (object.HP + PATH_SHOT_DAMAGE - 1) / PATH_SHOT_DAMAGE
+				cost += int32(PATH_COST_SHOT * maxInt(shots, 1))
+			case object.HasTag("ice"):
+				cost += PATH_COST_ICE - PATH_COST_FREE
+			case object.HasTag("forest"):
+				cost += PATH_COST_FOREST - PATH_COST_FREE
+			}
+		}
+	}
+	return cost
+}
+
+// PathGrid cost of every zone [y*W+x], location snapshot shared by path jobs of one navigation cycle
+type PathGrid struct {
+	W, H int
+	Cost []int32
+	refs int32
+}
+
+func (receiver *PathGrid) Resize(w, h int) {
+	receiver.W, receiver.H = w, h
+	if cap(receiver.Cost) < w*h {
+		receiver.Cost = make([]int32, w*h)
+	}
+	receiver.Cost = receiver.Cost[:w*h]
+}
+
+func (receiver *PathGrid) At(zone Zone) int32 {
+	if !receiver.inside(zone) {
+		return PATH_COST_BLOCKED
+	}
+	return receiver.Cost[zone.Y*receiver.W+zone.X]
+}
+
+func (receiver *PathGrid) inside(zone Zone) bool {
+	return zone.X >= 0 && zone.Y >= 0 && zone.X < receiver.W && zone.Y < receiver.H
+}
+
+// Changes zones cheaper than in old grid appended to lowered, raised if any got costlier or size differs
+func (receiver *PathGrid) Changes(old *PathGrid, lowered []int32) ([]int32, bool) {
+	if old == nil || old.W != receiver.W || old.H != receiver.H {
+		return lowered, true
+	}
+	raised := false
+	for index, cost := range receiver.Cost {
+		was := old.Cost[index]
+		switch {
+		case cost == was:
+		case cost == PATH_COST_BLOCKED || (was != PATH_COST_BLOCKED && cost > was):
+			raised = true
+		default:
+			lowered = append(lowered, int32(index))
+		}
+	}
+	return lowered, raised
+}
+
+func (receiver *PathGrid) retain() {
+	atomic.AddInt32(&receiver.refs, 1)
+}
+
+// release grid, last user return it to pool
+func (receiver *PathGrid) release() {
+	if atomic.AddInt32(&receiver.refs, -1) == 0 {
+		pathGridPool.Put(receiver)
+	}
+}
+
+// FindPath cheapest path from zone to zone, both included; empty if there is none. Goal is always enterable:
+// it is target tank or base
+func (receiver *PathGrid) FindPath(from, to Zone, diagonal bool) []Zone {
+	if !receiver.inside(from) || !receiver.inside(to) {
+		return []Zone{}
+	}
+	search := pathSearchPool.Get().(*pathSearch)
+	defer pathSearchPool.Put(search)
+	search.reset(len(receiver.Cost))
+	steps := pathSteps4
+	if diagonal {
+		steps = pathSteps8
+	}
+	start, goal := int32(from.Y*receiver.W+from.X), int32(to.Y*receiver.W+to.X)
+	search.visit(start, 0, -1)
+	search.open.push(start, pathHeuristic(from, to, diagonal))
+	for len(search.open) > 0 {
+		current, _ := search.open.pop()
+		if search.closed[current] == search.stamp {
+			continue
+		}
+		search.closed[current] = search.stamp
+		if current == goal {
+			return search.path(goal, receiver.W)
+		}
+		zone := Zone{X: int(current) % receiver.W, Y: int(current) / receiver.W}
+		for _, step := range steps {
+			next := Zone{X: zone.X + step.X, Y: zone.Y + step.Y}
+			if !receiver.inside(next) {
+				continue
+			}
+			index := int32(next.Y*receiver.W + next.X)
+			if search.closed[index] == search.stamp {
+				continue
+			}
+			cost := receiver.Cost[index]
+			if index == goal && cost == PATH_COST_BLOCKED {
+				cost = PATH_COST_FREE
+			}
+			if cost == PATH_COST_BLOCKED {
+				continue
+			}
+			if step.X != 0 && step.Y != 0 {
+				//no corner cutting
+				if receiver.At(Zone{X: next.X, Y: zone.Y}) == PATH_COST_BLOCKED || receiver.At(Zone{X: zone.X, Y: next.Y}) == PATH_COST_BLOCKED {
+					continue
+				}
+				cost = cost * PATH_COST_DIAG / PATH_COST_FREE
+			}
+			g := search.g[current] + cost
+			if search.seen[index] != search.stamp || g < search.g[index] {
+				search.visit(index, g, current)
+				search.open.push(index, g+pathHeuristic(next, to, diagonal))
+			}
+		}
+	}
+	return []Zone{}
+}
+
+// pathHeuristic manhattan or octile distance by cheapest zone cost
+func pathHeuristic(from, to Zone, diagonal bool) int32 {
+	dx, dy := int32(absInt(from.X-to.X)), int32(absInt(from.Y-to.Y))
+	if !diagonal {
+		return (dx + dy) * PATH_COST_FREE
+	}
+	if dx > dy {
+		dx, dy = dy, dx
+	}
+	return dy*PATH_COST_FREE + dx*(PATH_COST_DIAG-PATH_COST_FREE)
+}
+
+// pathSearch buffers of one search, pooled. Stamp tells which entries belong to current search, so buffers
+// are not cleared between searches
+type pathSearch struct {
+	g, parent    []int32
+	seen, closed []uint32
+	stamp        uint32
+	open         pathHeap
+}
+
+type pathNode struct {
+	index, f int32
+}
+
+// pathHeap binary heap of nodes by f, smallest on top
+type pathHeap []pathNode
+
+func (receiver *pathSearch) reset(size int) {
+	if len(receiver.g) < size {
+		receiver.g, receiver.parent = make([]int32, size), make([]int32, size)
+		receiver.seen, receiver.closed = make([]uint32, size), make([]uint32, size)
+		receiver.stamp = 0
+	}
+	receiver.stamp++
+	if receiver.stamp == 0 { //wrapped, old stamps may match again
+		for i := range receiver.seen {
+			receiver.seen[i], receiver.closed[i] = 0, 0
+		}
+		receiver.stamp = 1
+	}
+	receiver.open = receiver.open[:0]
+}
+
+func (receiver *pathSearch) visit(index, g, parent int32) {
+	receiver.g[index], receiver.parent[index], receiver.seen[index] = g, parent, receiver.stamp
+}
+
+func (receiver *pathSearch) path(goal int32, w int) []Zone {
+	length := 0
+	for index := goal; index != -1; index = receiver.parent[index] {
+		length++
+	}
+	path := make([]Zone, length)
+	for index := goal; index != -1; index = receiver.parent[index] {
+		length--
+		path[length] = Zone{X: int(index) % w, Y: int(index) / w}
+	}
+	return path
+}
+
+func (receiver *pathHeap) push(index, f int32) {
+	open := append(*receiver, pathNode{index: index, f: f})
+	i := len(open) - 1
+	for i > 0 {
+		parent := (i - 1) / 2
+		if open[parent].f <= open[i].f {
+			break
+		}
+		open[parent], open[i] = open[i], open[parent]
+		i = parent
+	}
+	*receiver = open
+}
+
+// pop index with smallest f and its f
+func (receiver *pathHeap) pop() (int32, int32) {
+	open := *receiver
+	top := open[0]
+	last := len(open) - 1
+	open[0] = open[last]
+	open = open[:last]
+	for i := 0; ; {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < len(open) && open[left].f < open[smallest].f {
+			smallest = left
+		}
+		if right < len(open) && open[right].f < open[smallest].f {
+			smallest = right
+		}
+		if smallest == i {
+			break
+		}
+		open[i], open[smallest] = open[smallest], open[i]
+		i = smallest
+	}
+	*receiver = open
+	return top.index, top.f
+}
+
+// NewPathGrid take grid from pool, caller own one reference
+func NewPathGrid(w, h int) (*PathGrid, error) {
+	grid := pathGridPool.Get().(*PathGrid)
+	grid.Resize(w, h)
+	grid.refs = 1
+	return grid, nil
+}
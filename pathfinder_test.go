@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// costGrid from map: . free, # blocked, w destructible wall, ~ ice, s start, e goal
+func costGrid(t testing.TB, text string) (*PathGrid, Zone, Zone) {
+	rows := strings.Split(text, "\n")
+	grid, _ := NewPathGrid(len(rows[0]), len(rows))
+	var from, to Zone
+	for y, row := range rows {
+		for x, char := range row {
+			cost := int32(PATH_COST_FREE)
+			switch char {
+			case '#':
+				cost = PATH_COST_BLOCKED
+			case 'w':
+				cost = PATH_COST_FREE + 2*PATH_COST_SHOT
+			case '~':
+				cost = PATH_COST_ICE
+			case 's':
+				from = Zone{X: x, Y: y}
+			case 'e':
+				to = Zone{X: x, Y: y}
+			}
+			grid.Cost[y*grid.W+x] = cost
+		}
+	}
+	return grid, from, to
+}
+
+func pathCost(grid *PathGrid, path []Zone) (cost int32) {
+	for _, zone := range path[1:] {
+		cost += grid.At(zone)
+	}
+	return cost
+}
+
+func TestFindPathWeighted(t *testing.T) {
+	//short detour is cheaper than shooting through
+	grid, from, to := costGrid(t, "s.w.e\n.....")
+	if path := grid.FindPath(from, to, false); len(path) != 7 || pathCost(grid, path) != 6*PATH_COST_FREE {
+		t.Error("detour expected", path)
+	}
+	//long one is not
+	grid, from, to = costGrid(t, "s.w.e\n##.##\n.....")
+	if path := grid.FindPath(from, to, false); len(path) != 5 || path[2] != (Zone{X: 2}) {
+		t.Error("path through wall expected", path)
+	}
+	grid, from, to = costGrid(t, "s~.\n..e")
+	if path := grid.FindPath(from, to, false); pathCost(grid, path) != 3*PATH_COST_FREE {
+		t.Error("ice not avoided", path)
+	}
+	grid, from, to = costGrid(t, "s.#..\n..#..\n..#.e")
+	if path := grid.FindPath(from, to, false); len(path) != 0 {
+		t.Error("path through blocked zones", path)
+	}
+}
+
+func TestFindPathConnectivity(t *testing.T) {
+	grid, from, to := costGrid(t, "s....\n.....\n....e")
+	if path := grid.FindPath(from, to, false); len(path) != 7 || path[0] != from || path[6] != to {
+		t.Error("4-connected path", path)
+	}
+	if path := grid.FindPath(from, to, true); len(path) != 5 {
+		t.Error("8-connected path", path)
+	}
+	//no corner cutting
+	grid, from, to = costGrid(t, "s#\n.e")
+	if path := grid.FindPath(from, to, true); len(path) != 3 {
+		t.Error("corner cut", path)
+	}
+	//blocked goal (base) is still reachable
+	grid, from, to = costGrid(t, "s.e")
+	grid.Cost[2] = PATH_COST_BLOCKED
+	if path := grid.FindPath(from, to, false); len(path) != 3 {
+		t.Error("blocked goal not reached", path)
+	}
+}
+
+func TestFindPathReuseBuffers(t *testing.T) {
+	if raceEnabled {
+		t.Skip("pool is not reliable under race detector")
+	}
+	grid, from, to := costGrid(t, strings.Repeat("..........\n", 9)+"..........")
+	grid.FindPath(from, Zone{X: 9, Y: 9}, false)
+	allocs := testing.AllocsPerRun(100, func() {
+		grid.FindPath(from, to, false)
+		grid.FindPath(from, Zone{X: 9, Y: 9}, false)
+	})
+	if allocs > 2 { //only returned paths
+		t.Errorf("%.1f allocations per search pair", allocs)
+	}
+}
+
+// mazeGrid 100x60 with long walls, passage alternates sides
+func mazeGrid(t testing.TB) *PathGrid {
+	rows := make([]string, 60)
+	for y := range rows {
+		row := []byte(strings.Repeat(".", 100))
+		if y%6 == 3 {
+			for x := y % 12; x < 95; x++ {
+				row[x] = '#'
+			}
+		}
+		rows[y] = string(row)
+	}
+	grid, _, _ := costGrid(t, strings.Join(rows, "\n"))
+	return grid
+}
+
+func BenchmarkFindPath(b *testing.B) {
+	grid := mazeGrid(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		grid.FindPath(Zone{}, Zone{X: 99, Y: 59}, false)
+	}
+}
@@ -8,8 +8,10 @@ import (
 
 type Player struct {
 	*controller.Control
-	Keyboard <-chan keyboard.KeyEvent
-	Unit     *Unit
+	Autopilot controller.Controller //ai driving player tank, nil if player drive himself
+	Waypoint  *Waypoint             //click to move in progress
+	Keyboard  <-chan keyboard.KeyEvent
+	Unit      *Unit
 	*CustomizeMap
 	Name      string
 	Blueprint string
@@ -14,6 +14,25 @@ type Renderable interface {
 	GetSprite() Spriteer
 }
 
+// overlayMarker is debug text placed on screen coordinate, empty buffer draw nothing
+type overlayMarker struct {
+	Point
+	*Sprite
+	zIndex int
+}
+
+func (receiver *overlayMarker) GetXY() Point {
+	return receiver.Point
+}
+
+func (receiver *overlayMarker) GetSprite() Spriteer {
+	return receiver.Sprite
+}
+
+func (receiver *overlayMarker) GetZIndex() int {
+	return receiver.zIndex
+}
+
 type ZIndexed interface {
 	GetZIndex() int
 	//todo updateZIndexCb
@@ -29,6 +48,17 @@ type Renderer interface {
 	Free()
 }
 
+// NullRender draw nothing, headless run (gym)
+type NullRender struct{}
+
+func (receiver NullRender) Add(object Renderable)          {}
+func (receiver NullRender) Remove(object Renderable)       {}
+func (receiver NullRender) Execute(timeLeft time.Duration) {}
+func (receiver NullRender) SetOffset(x, y int)             {}
+func (receiver NullRender) NeedCompact() bool              { return false }
+func (receiver NullRender) Compact()                       {}
+func (receiver NullRender) Free()                          {}
+
 var minFps float64 = math.MaxFloat64
 var maxFps float64 = 0
 
@@ -162,10 +192,33 @@ func (receiver *Render) draw(sprite Spriteer, x, y, w, h int) {
 	}
 }
 
+// Each visit every queued object, order is not guaranteed
+func (receiver *Render) Each(fn func(object Renderable)) {
+	for _, zIndex := range receiver.zIndex {
+		for _, object := range receiver.zQueue[zIndex] {
+			if object != nil {
+				fn(object)
+			}
+		}
+	}
+}
+
+func (receiver *Render) Width() int {
+	return receiver.output.Width()
+}
+
+func (receiver *Render) Height() int {
+	return receiver.output.Height()
+}
+
 func NewRenderZIndex(queueSize int) (*Render, error) {
 	backend, _ := output.NewConsoleOutputLine()
+	return NewRenderWithOutput(queueSize, backend)
+}
+
+func NewRenderWithOutput(queueSize int, backend output.ConsoleOutput) (*Render, error) {
 	backend.CursorVisibility(false)
-	backend.ClipMode(output.CLIP_MODE_RB)
+	backend.ClipMode(output.CLIP_MODE_CUT)
 	return &Render{
 		zIndex:           make([]int, 0, 5),
 		zQueue:           make(map[int][]Renderable),
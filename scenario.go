@@ -43,6 +43,7 @@ type ScenarioStateInfo struct {
 	Location                           Box
 	player1Blueprint, player2Blueprint string
 	limits                             ScenarioLimits
+	difficulty                         string
 }
 
 type Scenario struct {
@@ -54,6 +55,7 @@ type Scenario struct {
 	player1Blueprint, player2Blueprint string
 	limits                             ScenarioLimits
 	Location                           Box
+	Difficulty                         string //ai difficulty profile, command line take precedence
 }
 
 func (receiver *Scenario) ApplyState(current *StateItem) error { /*
@@ -87,6 +89,22 @@ func (receiver *Scenario) DeclareBlueprint(fn func(blueprint string)) {
 	receiver.declareBlueprint = fn //todo queue
 }
 
+// Declared blueprints of all states, some may repeat
+func (receiver *Scenario) Declared() []string {
+	var declared []string
+	var walk func(item *StateItem)
+	walk = func(item *StateItem) {
+		if info, ok := item.StateInfo.(*ScenarioStateInfo); ok {
+			declared = append(declared, info.Declare...)
+		}
+		for _, child := range item.items {
+			walk(child)
+		}
+	}
+	walk(receiver.State.root)
+	return declared
+}
+
 func (receiver *Scenario) DropBlueprint(fn func(blueprint string)) {
 	receiver.declareBlueprint = fn //todo queue
 }
@@ -172,6 +190,7 @@ func NewFileScenario(filepath string) (*Scenario, error) {
 			instance.Location = info.Location
 			instance.player1Blueprint = info.player1Blueprint
 			instance.player2Blueprint = info.player2Blueprint
+			instance.Difficulty = info.difficulty
 		}
 	}
 
@@ -210,6 +229,9 @@ func GetScenarioState(id string) (*State, error) { //todo refactor
 			ssi.Location.W = locationMap["w"].(float64)
 			ssi.Location.H = locationMap["h"].(float64)
 		}
+		if difficulty, ok := m["difficulty"]; ok {
+			ssi.difficulty, _ = difficulty.(string)
+		}
 		if limits, ok := m["limits"]; ok {
 			limitsMap := limits.(map[string]interface{})
 			ssi.limits.AiUnits = int64(limitsMap["aiUnit"].(float64))
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"GoConsoleBT/controller"
 	direct "github.com/buger/goterm"
 	"github.com/eiannone/keyboard"
 	"math"
@@ -58,6 +59,12 @@ type DialogInfo struct {
 	Done  bool
 }
 
+// dialogOption is clickable area relative to dialog, click enter state and complete dialog
+type dialogOption struct {
+	Box
+	path string
+}
+
 type Dialog struct {
 	*Screen
 	keyboard   <-chan keyboard.KeyEvent
@@ -67,6 +74,7 @@ type Dialog struct {
 	CompleteEvent Event
 	active        bool
 	Value         int
+	options       []dialogOption
 }
 
 func (receiver *Dialog) ApplyState(current *StateItem) error {
@@ -99,6 +107,20 @@ func (receiver *Dialog) Activate() error {
 	return nil
 }
 
+func (receiver *Dialog) click(x, y int) {
+	origin := receiver.GetXY()
+	point := Point{X: float64(x) - math.Round(origin.X), Y: float64(y) - math.Round(origin.Y)}
+	for _, option := range receiver.options {
+		if point.X >= option.X && point.X < option.X+option.W && point.Y >= option.Y && point.Y < option.Y+option.H {
+			if option.path != "/done" {
+				receiver.Enter(option.path)
+			}
+			receiver.Enter("/done")
+			return
+		}
+	}
+}
+
 func NewScreen(sprite Spriteer) (*Screen, error) {
 	return &Screen{
 		sprite: sprite,
@@ -172,6 +194,7 @@ func NewSetupSizeDialog(config Box, keyboard <-chan keyboard.KeyEvent, chanel Ev
 		ObservableObject: oo,
 		CompleteEvent:    SetupSizeEvent,
 		Value:            1,
+		options:          []dialogOption{{Box: Box{Size: Size{W: math.MaxInt32, H: math.MaxInt32}}, path: "/done"}},
 	}
 
 	dialog.ObservableObject.Owner = dialog
@@ -245,6 +268,10 @@ func NewPlayerSelectDialog(keyboard <-chan keyboard.KeyEvent, chanel EventChanel
 		ObservableObject: oo,
 		CompleteEvent:    DialogPlayerSelectEvent,
 		Value:            1,
+		options: []dialogOption{ //option sprite list both choices, one per half
+			{Box: Box{Point{float64(offsetX), float64(offsetY)}, Size{float64(pSize.W), float64(pSize.H / 2)}}, path: "/one"},
+			{Box: Box{Point{float64(offsetX), float64(offsetY + pSize.H/2)}, Size{float64(pSize.W), float64(pSize.H - pSize.H/2)}}, path: "/two"},
+		},
 	}
 
 	screen.ObservableObject.Owner = screen
@@ -268,6 +295,17 @@ func dialogEventDispatcher(screen *Dialog, kb <-chan keyboard.KeyEvent, terminat
 			if !ok {
 				return
 			}
+			if mouse, ok := controller.AsMouse(event); ok {
+				switch {
+				case mouse.IsClick():
+					screen.click(mouse.X, mouse.Y)
+				case mouse.Button == controller.MOUSE_WHEEL_DOWN:
+					screen.Enter("/two")
+				case mouse.Button == controller.MOUSE_WHEEL_UP:
+					screen.Enter("/one")
+				}
+				continue
+			}
 			switch event.Key {
 			case keyboard.KeyArrowDown:
 				screen.Enter("/two")
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	lfpool "github.com/xiaonanln/go-lockfree-pool"
 	"math"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -41,6 +42,9 @@ type SpawnManager struct {
 	spawned                      map[ObjectInterface]bool
 	builders                     map[string]Builder
 	pendingSpawn, pendingDeSpawn []ObjectInterface
+	pendingHandOff               []handOffRequest
+	origin                       map[*Unit]unitOrigin
+	handOffMutex                 sync.Mutex
 	respawn                      map[string]Pooler
 	UnitEventChanel              EventChanel
 	spawnMutex, deSpawnMutex     sync.Mutex
@@ -48,13 +52,17 @@ type SpawnManager struct {
 	planeDeSpawnAllCb            func()
 	cycleSpawned                 int64 //only pooled
 	cycleCreated                 int64 //only pooled
+	Lockstep                     bool  //no pooling, despawn all in id order and its callback in game loop, gym
 	Flags                        struct {
 		lockFree bool
 	}
 }
 
 func (manager *SpawnManager) Execute(timeLeft time.Duration) {
-	var deSpawnAll bool
+	var (
+		deSpawnAll bool
+		callback   func()
+	)
 
 	manager.deSpawnMutex.Lock()
 
@@ -68,6 +76,11 @@ func (manager *SpawnManager) Execute(timeLeft time.Duration) {
 				manager.pendingDeSpawn = append(manager.pendingDeSpawn, object)
 			}
 		}
+		if manager.Lockstep {
+			sort.Slice(manager.pendingDeSpawn, func(i, j int) bool {
+				return manager.pendingDeSpawn[i].GetAttr().ID < manager.pendingDeSpawn[j].GetAttr().ID
+			})
+		}
 	}
 
 	for i, object := range manager.pendingDeSpawn {
@@ -78,16 +91,21 @@ func (manager *SpawnManager) Execute(timeLeft time.Duration) {
 		if manager.location != nil {
 			manager.location.Remove(object)
 		}
-		if object.GetAttr().AI {
-			manager.updater.Remove(object.(*Unit).Control.(*BehaviorControl))
+		if unit, ok := object.(*Unit); ok {
+			if control, ok := unit.Control.(Updateable); ok {
+				manager.updater.Remove(control) //behavior ai, bot
+			}
 		}
 
 		manager.pendingDeSpawn[i] = nil
 		delete(manager.spawned, object)
 		object.DeSpawn()
+		if unit, ok := object.(*Unit); ok {
+			manager.restoreControl(unit)
+		}
 		bl := object.GetAttr().Blueprint
 		if bl != "" {
-			if !deSpawnAll && manager.Flags.lockFree {
+			if !deSpawnAll && manager.Flags.lockFree && !manager.Lockstep {
 				if poll, ok := manager.respawn[bl]; ok {
 					poll.Put(object)
 				}
@@ -107,7 +125,11 @@ func (manager *SpawnManager) Execute(timeLeft time.Duration) {
 	if deSpawnAll {
 		manager.pendingSpawn = manager.pendingSpawn[0:0]
 		if manager.planeDeSpawnAllCb != nil {
-			go manager.planeDeSpawnAllCb()
+			if manager.Lockstep {
+				callback = manager.planeDeSpawnAllCb
+			} else {
+				go manager.planeDeSpawnAllCb()
+			}
 			manager.planeDeSpawnAllCb = nil
 		}
 	}
@@ -120,8 +142,10 @@ func (manager *SpawnManager) Execute(timeLeft time.Duration) {
 		if manager.location != nil {
 			manager.location.Add(object)
 		}
-		if object.GetAttr().AI {
-			manager.updater.Add(object.(*Unit).Control.(*BehaviorControl))
+		if unit, ok := object.(*Unit); ok {
+			if control, ok := unit.Control.(Updateable); ok {
+				manager.updater.Add(control)
+			}
 		}
 		manager.pendingSpawn[i] = nil
 		manager.spawned[object] = true
@@ -139,6 +163,11 @@ func (manager *SpawnManager) Execute(timeLeft time.Duration) {
 	}
 	manager.pendingSpawn = manager.pendingSpawn[0:0]
 	manager.spawnMutex.Unlock()
+
+	manager.executeHandOff()
+	if callback != nil {
+		callback()
+	}
 }
 
 func (manager *SpawnManager) Collect() {
@@ -149,7 +178,7 @@ func (manager *SpawnManager) Collect() {
 
 	// that's effective than collect after despawn, probably pool depletion
 
-	if manager.Flags.lockFree {
+	if manager.Flags.lockFree || manager.Lockstep {
 		return
 	}
 
@@ -205,6 +234,19 @@ func (manager *SpawnManager) Spawn(coordinate Point, blueprint string, configura
 	return object, nil
 }
 
+// Build create object without spawning it, eg. to render remote object
+func (manager *SpawnManager) Build(blueprint string) (ObjectInterface, error) {
+	builder, ok := manager.builders[blueprint]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", blueprint, BuilderNotFoundError)
+	}
+	object, ok := builder().(ObjectInterface)
+	if !ok {
+		return nil, errors.New("unable to create object")
+	}
+	return object, nil
+}
+
 func (manager *SpawnManager) SpawnPlayerTank(coordinate Point, blueprint string, player *Player) (ObjectInterface, error) {
 	if DEBUG_SPAWN {
 		logger.Printf("spawn<user-item> attempt %s \n", blueprint)
@@ -265,9 +307,27 @@ func (manager *SpawnManager) QuerySpawnedByTag(tag string) []ObjectInterface {
 			result = append(result, object)
 		}
 	}
+	if manager.Lockstep {
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].GetAttr().ID < result[j].GetAttr().ID
+		})
+	}
 	return result
 }
 
+func (manager *SpawnManager) QuerySpawnedById(id int64) ObjectInterface {
+	manager.deSpawnMutex.Lock()
+	manager.spawnMutex.Lock()
+	defer manager.spawnMutex.Unlock()
+	defer manager.deSpawnMutex.Unlock()
+	for object, spawned := range manager.spawned {
+		if spawned && object.GetAttr().ID == id {
+			return object
+		}
+	}
+	return nil
+}
+
 func (manager *SpawnManager) QuerySpawnedByTagCount(tag string) int64 {
 	manager.deSpawnMutex.Lock()
 	manager.spawnMutex.Lock()
@@ -329,6 +389,7 @@ func NewSpawner(updater *Updater, render Renderer, collider *collider.Collider,
 		builders:        make(map[string]Builder, 5),
 		pendingSpawn:    make([]ObjectInterface, 0, 25),
 		pendingDeSpawn:  make([]ObjectInterface, 0, 25),
+		origin:          make(map[*Unit]unitOrigin),
 		respawn:         make(map[string]Pooler, 0),
 		UnitEventChanel: make(EventChanel),
 		spawnMutex:      sync.Mutex{},
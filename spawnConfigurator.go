@@ -51,6 +51,10 @@ func PlayerConfigurator(object ObjectInterface, config interface{}) ObjectInterf
 		unit.removeTag("ai")
 	}
 	unit.Control = player.Control
+	if player.Autopilot != nil {
+		unit.Control = player.Autopilot
+		_, object.GetAttr().AI = player.Autopilot.(*BehaviorControl)
+	}
 	player.Unit = unit
 	return object
 }
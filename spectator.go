@@ -0,0 +1,222 @@
+package main
+
+import (
+	output "GoConsoleBT/output"
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+)
+
+const (
+	SPECTATOR_BUFFER     = 4 //frames queued per spectator before drop
+	SPECTATOR_MIN_WIDTH  = 80
+	SPECTATOR_MIN_HEIGHT = 24
+)
+
+// spectatorFiles are scripts of the page, served by game itself so it works without internet
+//
+//go:embed spectator
+var spectatorFiles embed.FS
+
+const spectatorPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GoConsoleBT spectator</title>
+<script src="term.js"></script>
+<style>
+body { margin: 0; background: #000; }
+#screen { font: 14px monospace; color: #e5e5e5; white-space: pre; }
+#screen div { height: 1.2em; line-height: 1.2em; }
+</style>
+</head>
+<body>
+<div id="screen"></div>
+<script>
+var term = new Terminal(document.getElementById("screen"), %d, %d);
+var ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws");
+ws.binaryType = "arraybuffer";
+ws.onmessage = function (event) { term.write(new Uint8Array(event.data)); };
+ws.onclose = function () { term.write("\x1b[?25h\r\n\x1b[31mstream closed\x1b[0m"); };
+</script>
+</body>
+</html>
+`
+
+type spectator struct {
+	conn   *wsConn
+	frames chan []byte
+	ready  bool //got full frame, diff frames make sense now
+}
+
+func (receiver *spectator) write() {
+	for frame := range receiver.frames {
+		if _, err := receiver.conn.Write(frame); err != nil {
+			receiver.conn.Close() //reader notice and leave
+			for range receiver.frames {
+			}
+			return
+		}
+	}
+}
+
+// SpectatorServer serve read only browser view of the game. All spectators share one render view,
+// it exist only while somebody watch. Slow spectator lose frames, game is never waiting for it
+type SpectatorServer struct {
+	Render     *MultiRender
+	server     *http.Server
+	listener   net.Listener
+	spectators []*spectator
+	view       *Render
+	stream     *output.ConsoleOutputStream
+	mutex      sync.Mutex
+}
+
+// Write broadcast frame to every spectator, called by stream writer
+func (receiver *SpectatorServer) Write(frame []byte) (int, error) {
+	repaint := false
+	receiver.mutex.Lock()
+	full := bytes.Contains(frame, []byte(output.FULL_FRAME_MARK))
+	for _, spectator := range receiver.spectators {
+		if !spectator.ready && !full {
+			continue
+		}
+		spectator.ready = true
+		select {
+		case spectator.frames <- frame:
+		default:
+			spectator.ready = false
+			repaint = true
+		}
+	}
+	stream := receiver.stream
+	receiver.mutex.Unlock()
+	if repaint && stream != nil {
+		stream.Repaint() //clear leftovers of dropped frames
+	}
+	return len(frame), nil
+}
+
+func (receiver *SpectatorServer) page(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		http.NotFound(writer, request)
+		return
+	}
+	width, height := receiver.size()
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(writer, spectatorPage, width, height)
+}
+
+func (receiver *SpectatorServer) watch(writer http.ResponseWriter, request *http.Request) {
+	conn, err := wsUpgrade(writer, request)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	spectator := &spectator{conn: conn, frames: make(chan []byte, SPECTATOR_BUFFER)}
+	receiver.join(spectator)
+	go spectator.write()
+	logger.Printf("spectator %s joined \n", conn.conn.RemoteAddr())
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break //read only, client messages are ignored
+		}
+	}
+	receiver.leave(spectator)
+	conn.Close()
+	logger.Printf("spectator %s left \n", conn.conn.RemoteAddr())
+}
+
+func (receiver *SpectatorServer) join(spectator *spectator) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.spectators = append(receiver.spectators, spectator)
+	if receiver.view != nil {
+		receiver.stream.Repaint() //late joiner need full frame
+		return
+	}
+	width, height := receiver.size()
+	receiver.stream, _ = output.NewConsoleOutputStream(receiver, width, height)
+	receiver.view, _ = NewRenderWithOutput(100, receiver.stream)
+	receiver.Render.Attach(receiver.view, nil)
+}
+
+func (receiver *SpectatorServer) leave(spectator *spectator) {
+	receiver.mutex.Lock()
+	for i, candidate := range receiver.spectators {
+		if candidate == spectator {
+			receiver.spectators = append(receiver.spectators[:i], receiver.spectators[i+1:]...)
+			close(spectator.frames)
+			break
+		}
+	}
+	var stream *output.ConsoleOutputStream
+	if len(receiver.spectators) == 0 && receiver.view != nil {
+		receiver.Render.Detach(receiver.view)
+		stream = receiver.stream
+		receiver.view, receiver.stream = nil, nil
+	}
+	receiver.mutex.Unlock()
+	if stream != nil {
+		stream.Close() //outside lock, writer may wait in Write
+	}
+}
+
+// size cover whole location
+func (receiver *SpectatorServer) size() (int, int) {
+	bounds := receiver.Render.Bounds
+	width := int(math.Ceil(bounds.X+bounds.W)) + 1
+	height := int(math.Ceil(bounds.Y+bounds.H)) + 1
+	if width < SPECTATOR_MIN_WIDTH {
+		width = SPECTATOR_MIN_WIDTH
+	}
+	if height < SPECTATOR_MIN_HEIGHT {
+		height = SPECTATOR_MIN_HEIGHT
+	}
+	return width, height
+}
+
+func (receiver *SpectatorServer) Serve() {
+	if err := receiver.server.Serve(receiver.listener); err != nil && err != http.ErrServerClosed {
+		logger.Println("spectator server stopped: ", err)
+	}
+}
+
+func (receiver *SpectatorServer) Addr() net.Addr {
+	return receiver.listener.Addr()
+}
+
+// Close stop server and drop all spectators
+func (receiver *SpectatorServer) Close() error {
+	err := receiver.server.Close()
+	receiver.mutex.Lock()
+	for _, spectator := range receiver.spectators {
+		spectator.conn.Close() //hijacked, not closed by http server
+	}
+	receiver.mutex.Unlock()
+	return err
+}
+
+func NewSpectatorServer(addr string, render *MultiRender) (*SpectatorServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	instance := &SpectatorServer{
+		Render:     render,
+		listener:   listener,
+		spectators: make([]*spectator, 0, 2),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", instance.page)
+	files, _ := fs.Sub(spectatorFiles, "spectator")
+	mux.Handle("/term.js", http.FileServer(http.FS(files)))
+	mux.HandleFunc("/ws", instance.watch)
+	instance.server = &http.Server{Handler: mux}
+	return instance, nil
+}
@@ -0,0 +1,220 @@
+// Minimal read only terminal for spectator page: interprets the escape sequences game output produce
+// (cursor moves, clears, sgr colors) into a grid of spans. Served embedded, page works offline
+"use strict";
+
+var TERM_PALETTE = [
+    "#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+    "#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff"
+];
+
+function termColor256(index) {
+    if (index < 16) {
+        return TERM_PALETTE[index];
+    }
+    if (index < 232) {
+        index -= 16;
+        var level = function (value) { return value === 0 ? 0 : 55 + value * 40; };
+        return "rgb(" + level(Math.floor(index / 36)) + "," + level(Math.floor(index / 6) % 6) + "," + level(index % 6) + ")";
+    }
+    var gray = 8 + (index - 232) * 10;
+    return "rgb(" + gray + "," + gray + "," + gray + ")";
+}
+
+function Terminal(element, cols, rows) {
+    this.element = element;
+    this.cols = cols;
+    this.rows = rows;
+    this.x = 0;
+    this.y = 0;
+    this.style = {fg: null, bg: null, bold: false};
+    this.decoder = new TextDecoder("utf-8");
+    this.pending = ""; //unfinished escape sequence of previous chunk
+    this.lines = [];
+    this.dirty = [];
+    for (var y = 0; y < rows; y++) {
+        var line = document.createElement("div");
+        element.appendChild(line);
+        this.lines.push(line);
+    }
+    this.clear();
+    this.scheduled = false;
+}
+
+Terminal.prototype.blank = function () {
+    return {ch: " ", fg: null, bg: null, bold: false};
+};
+
+Terminal.prototype.clear = function () {
+    this.grid = [];
+    for (var y = 0; y < this.rows; y++) {
+        this.clearLine(y, 0);
+    }
+};
+
+Terminal.prototype.clearLine = function (y, from) {
+    if (y < 0 || y >= this.rows) {
+        return;
+    }
+    var row = this.grid[y] || (this.grid[y] = []);
+    for (var x = from; x < this.cols; x++) {
+        row[x] = this.blank();
+    }
+    this.dirty[y] = true;
+};
+
+Terminal.prototype.put = function (ch) {
+    if (this.x >= 0 && this.x < this.cols && this.y >= 0 && this.y < this.rows) {
+        this.grid[this.y][this.x] = {ch: ch, fg: this.style.fg, bg: this.style.bg, bold: this.style.bold};
+        this.dirty[this.y] = true;
+    }
+    this.x++;
+};
+
+Terminal.prototype.sgr = function (params) {
+    if (params.length === 0) {
+        params = [0];
+    }
+    for (var i = 0; i < params.length; i++) {
+        var code = params[i];
+        if (code === 0) {
+            this.style = {fg: null, bg: null, bold: false};
+        } else if (code === 1) {
+            this.style.bold = true;
+        } else if (code === 22) {
+            this.style.bold = false;
+        } else if (code >= 30 && code <= 37) {
+            this.style.fg = TERM_PALETTE[code - 30];
+        } else if (code >= 90 && code <= 97) {
+            this.style.fg = TERM_PALETTE[code - 82];
+        } else if (code === 39) {
+            this.style.fg = null;
+        } else if (code >= 40 && code <= 47) {
+            this.style.bg = TERM_PALETTE[code - 40];
+        } else if (code >= 100 && code <= 107) {
+            this.style.bg = TERM_PALETTE[code - 92];
+        } else if (code === 49) {
+            this.style.bg = null;
+        } else if ((code === 38 || code === 48) && params[i + 1] === 5) {
+            this.style[code === 38 ? "fg" : "bg"] = termColor256(params[i + 2] || 0);
+            i += 2;
+        } else if ((code === 38 || code === 48) && params[i + 1] === 2) {
+            this.style[code === 38 ? "fg" : "bg"] = "rgb(" + (params[i + 2] || 0) + "," + (params[i + 3] || 0) + "," + (params[i + 4] || 0) + ")";
+            i += 4;
+        }
+    }
+};
+
+Terminal.prototype.csi = function (body, command) {
+    if (body.charAt(0) === "?" || body.charAt(0) === ">" || body.charAt(0) === "<") {
+        return; //cursor visibility, mouse and keyboard modes mean nothing here
+    }
+    var params = body === "" ? [] : body.split(";").map(function (value) { return parseInt(value, 10) || 0; });
+    var first = params.length > 0 ? params[0] : 0;
+    switch (command) {
+    case "H":
+    case "f":
+        this.y = (params[0] || 1) - 1;
+        this.x = (params[1] || 1) - 1;
+        break;
+    case "A":
+        this.y -= first || 1;
+        break;
+    case "B":
+        this.y += first || 1;
+        break;
+    case "C":
+        this.x += first || 1;
+        break;
+    case "D":
+        this.x -= first || 1;
+        break;
+    case "J":
+        if (first === 2 || first === 3) {
+            this.clear();
+        }
+        break;
+    case "K":
+        this.clearLine(this.y, first === 2 ? 0 : this.x);
+        break;
+    case "m":
+        this.sgr(params);
+        break;
+    }
+};
+
+Terminal.prototype.write = function (data) {
+    var text = this.pending + (typeof data === "string" ? data : this.decoder.decode(data, {stream: true}));
+    this.pending = "";
+    for (var i = 0; i < text.length; i++) {
+        var ch = text.charAt(i);
+        if (ch === "\x1b") {
+            if (i + 1 >= text.length) {
+                this.pending = text.substring(i);
+                break;
+            }
+            if (text.charAt(i + 1) !== "[") {
+                i++; //two char sequence, not used by game
+                continue;
+            }
+            var end = i + 2;
+            while (end < text.length && !/[@-~]/.test(text.charAt(end))) {
+                end++;
+            }
+            if (end >= text.length) {
+                this.pending = text.substring(i);
+                break;
+            }
+            this.csi(text.substring(i + 2, end), text.charAt(end));
+            i = end;
+        } else if (ch === "\n") {
+            this.y++;
+        } else if (ch === "\r") {
+            this.x = 0;
+        } else if (ch === "\b") {
+            this.x = Math.max(this.x - 1, 0);
+        } else if (ch >= " ") {
+            this.put(ch);
+        }
+    }
+    this.schedule();
+};
+
+Terminal.prototype.schedule = function () {
+    if (this.scheduled) {
+        return;
+    }
+    this.scheduled = true;
+    var self = this;
+    window.requestAnimationFrame(function () {
+        self.scheduled = false;
+        self.paint();
+    });
+};
+
+Terminal.prototype.paint = function () {
+    for (var y = 0; y < this.rows; y++) {
+        if (!this.dirty[y]) {
+            continue;
+        }
+        this.dirty[y] = false;
+        var html = "", run = "", key = null, row = this.grid[y];
+        var flush = function () {
+            if (run !== "") {
+                html += key === "" ? run : "<span style=\"" + key + "\">" + run + "</span>";
+            }
+            run = "";
+        };
+        for (var x = 0; x < this.cols; x++) {
+            var cell = row[x];
+            var style = (cell.fg ? "color:" + cell.fg + ";" : "") + (cell.bg ? "background:" + cell.bg + ";" : "") +
+                (cell.bold ? "font-weight:bold;" : "");
+            if (style !== key) {
+                flush();
+                key = style;
+            }
+            run += cell.ch === "&" ? "&amp;" : cell.ch === "<" ? "&lt;" : cell.ch === ">" ? "&gt;" : cell.ch;
+        }
+        flush();
+        this.lines[y].innerHTML = html;
+    }
+};
@@ -0,0 +1,131 @@
+package main
+
+import (
+	output "GoConsoleBT/output"
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func wsDial(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: "+addr+"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	status, _ := reader.ReadString('\n')
+	if !strings.Contains(status, "101") {
+		t.Fatalf("handshake failed: %q", status)
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(line, "Sec-WebSocket-Accept") && !strings.Contains(line, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=") {
+			t.Errorf("wrong accept header %q", line)
+		}
+		if line == "\r\n" {
+			return conn, reader
+		}
+	}
+}
+
+// wsFrame wait for binary frame, render is driven meanwhile
+func wsFrame(t *testing.T, conn net.Conn, reader *bufio.Reader, render *MultiRender) string {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				render.Execute(0)
+			}
+		}
+	}()
+	defer close(done)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var head [2]byte
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[0] != 0x80|WS_OP_BINARY {
+		t.Fatalf("unexpected frame header %x", head)
+	}
+	length := uint64(head[1])
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(reader, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(reader, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	return string(payload)
+}
+
+func TestSpectatorStream(t *testing.T) {
+	backend, _ := output.NewConsoleOutputStream(ioutil.Discard, 80, 24)
+	primary, _ := NewRenderWithOutput(10, backend)
+	multi, _ := NewMultiRender(primary)
+	server, err := NewSpectatorServer("127.0.0.1:0", multi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go server.Serve()
+
+	first, firstReader := wsDial(t, server.Addr().String())
+	defer first.Close()
+	if frame := wsFrame(t, first, firstReader, multi); !strings.Contains(frame, "\033[2J") {
+		t.Errorf("first frame is not full: %q", frame)
+	}
+
+	second, secondReader := wsDial(t, server.Addr().String())
+	defer second.Close()
+	if frame := wsFrame(t, second, secondReader, multi); !strings.Contains(frame, "\033[2J") {
+		t.Errorf("late joiner frame is not full: %q", frame)
+	}
+}
+
+func TestSpectatorPageOffline(t *testing.T) {
+	backend, _ := output.NewConsoleOutputStream(ioutil.Discard, 80, 24)
+	primary, _ := NewRenderWithOutput(10, backend)
+	multi, _ := NewMultiRender(primary)
+	server, err := NewSpectatorServer("127.0.0.1:0", multi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go server.Serve()
+
+	for path, expected := range map[string]string{"/": "term.js", "/term.js": "Terminal.prototype.write"} {
+		response, err := http.Get("http://" + server.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK || !strings.Contains(string(body), expected) {
+			t.Errorf("%s status %d, %q not found", path, response.StatusCode, expected)
+		}
+		if strings.Contains(string(body), "https://") {
+			t.Errorf("%s depends on external resource", path)
+		}
+	}
+}
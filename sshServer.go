@@ -0,0 +1,289 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	output "GoConsoleBT/output"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	direct "github.com/buger/goterm"
+	"github.com/eiannone/keyboard"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	SSH_ESC_TIMEOUT   = 100 * time.Millisecond //lonely esc flushed after it, same as VTIME of local tty
+	SSH_JOIN_INTERVAL = time.Second
+	SSH_NAME_LIMIT    = 12
+)
+
+var SshNoPtyError = errors.New("ssh session without pty")
+
+type sshPtyRequest struct {
+	Term          string
+	Columns, Rows uint32
+	Width, Height uint32
+	Modes         string
+}
+
+type sshWindowChange struct {
+	Columns, Rows uint32
+	Width, Height uint32
+}
+
+// SSHServer let anyone join running game with plain ssh client, every session is new player
+// with own screen and keyboard. No auth, ssh user name is player name
+type SSHServer struct {
+	*Game
+	*GameConfig
+	Render          *MultiRender
+	OnPlayersChange func() //called after player join or leave
+	config          *ssh.ServerConfig
+	listener        net.Listener
+	sessions        int64
+}
+
+func (receiver *SSHServer) Serve() {
+	for {
+		conn, err := receiver.listener.Accept()
+		if err != nil {
+			logger.Println("ssh server stopped: ", err)
+			return
+		}
+		go receiver.handshake(conn)
+	}
+}
+
+func (receiver *SSHServer) handshake(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, receiver.config)
+	conn.SetDeadline(time.Time{})
+	if err != nil {
+		logger.Printf("ssh handshake with %s failed: %s \n", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channel supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			logger.Println(err)
+			continue
+		}
+		go receiver.session(channel, channelRequests, sshConn.User())
+	}
+}
+
+// session answer channel requests, game start on shell request
+func (receiver *SSHServer) session(channel ssh.Channel, requests <-chan *ssh.Request, user string) {
+	width, height, pty := 80, 24, false
+	var stream *output.ConsoleOutputStream
+	for request := range requests {
+		switch request.Type {
+		case "pty-req":
+			var payload sshPtyRequest
+			if err := ssh.Unmarshal(request.Payload, &payload); err == nil {
+				width, height, pty = int(payload.Columns), int(payload.Rows), true
+			}
+			request.Reply(pty, nil)
+		case "window-change":
+			var payload sshWindowChange
+			if err := ssh.Unmarshal(request.Payload, &payload); err == nil && stream != nil {
+				stream.Resize(int(payload.Columns), int(payload.Rows))
+			}
+		case "shell":
+			if stream != nil || !pty {
+				request.Reply(false, nil)
+				if !pty {
+					channel.Write([]byte(SshNoPtyError.Error() + ", use ssh -t\r\n"))
+					channel.Close()
+				}
+				continue
+			}
+			request.Reply(true, nil)
+			stream, _ = output.NewConsoleOutputStream(channel, width, height)
+			go receiver.play(channel, stream, user)
+		default:
+			if request.WantReply {
+				request.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// play join game as new player and drive it until ctrl+c or disconnect
+func (receiver *SSHServer) play(channel ssh.Channel, stream *output.ConsoleOutputStream, user string) {
+	defer channel.Close()
+	id := atomic.AddInt64(&receiver.sessions, 1)
+
+	parser, _ := NewKeyParser(1)
+	go receiver.read(channel, parser)
+	repeater, _ := NewKeyboardRepeater(parser.Events())
+	closing := repeater.Observe()
+
+	control, _ := controller.NewPlayerControl(repeater.Subscribe(), receiver.GameConfig.KeyBindings[0], receiver.GameConfig.Input.HoldConfig)
+	player, _ := NewPlayer(sshPlayerName(user, id), control)
+	player.CustomizeMap = &CustomizeMap{
+		"gun":   direct.MAGENTA,
+		"armor": direct.WHITE,
+		"track": direct.GREEN,
+	}
+
+	channel.Write([]byte("waiting for game start, ctrl+c to quit\r\n"))
+	ticker := time.NewTicker(SSH_JOIN_INTERVAL)
+	defer ticker.Stop()
+	var render *Render
+	join := func() {
+		if render != nil {
+			return
+		}
+		if err := receiver.Game.JoinPlayer(player); err != nil {
+			return
+		}
+		logger.Printf("ssh player %s joined \n", player.Name)
+		render, _ = NewRenderWithOutput(100, stream)
+		receiver.Render.Attach(render, func() (Point, bool) {
+			if unit := player.Unit; unit != nil {
+				return unit.GetXY(), true
+			}
+			return Point{}, false
+		})
+		if receiver.OnPlayersChange != nil {
+			receiver.OnPlayersChange()
+		}
+	}
+
+	join()
+loop:
+	for {
+		select {
+		case event, ok := <-closing:
+			if !ok || event.Key == keyboard.KeyCtrlC {
+				break loop
+			}
+		case <-ticker.C:
+			join()
+		}
+	}
+
+	if render != nil {
+		receiver.Game.LeavePlayer(player)
+		receiver.Render.Detach(render)
+		logger.Printf("ssh player %s left \n", player.Name)
+		if receiver.OnPlayersChange != nil {
+			receiver.OnPlayersChange()
+		}
+	}
+	stream.Close()
+	channel.Write([]byte("\033[2J\033[1;1H\033[?25h"))
+}
+
+// read feed session input to parser, parser events are closed on disconnect
+func (receiver *SSHServer) read(channel ssh.Channel, parser *KeyParser) {
+	defer close(parser.events)
+	input := make(chan []byte)
+	go func() {
+		defer close(input)
+		buf := make([]byte, 256)
+		for {
+			n, err := channel.Read(buf)
+			if n > 0 {
+				chunk := make([]byte, n)
+				copy(chunk, buf[:n])
+				input <- chunk
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	pending := make([]byte, 0, 256)
+	for {
+		var flush <-chan time.Time
+		if len(pending) > 0 {
+			flush = time.After(SSH_ESC_TIMEOUT)
+		}
+		select {
+		case chunk, ok := <-input:
+			if !ok {
+				return
+			}
+			pending = parser.Feed(pending, chunk)
+		case <-flush:
+			pending = parser.Flush(pending)
+		}
+	}
+}
+
+func (receiver *SSHServer) Addr() net.Addr {
+	return receiver.listener.Addr()
+}
+
+func (receiver *SSHServer) Close() error {
+	return receiver.listener.Close()
+}
+
+func sshPlayerName(user string, id int64) string {
+	if user == "" {
+		return "ssh" + strconv.FormatInt(id, 10)
+	}
+	if runes := []rune(user); len(runes) > SSH_NAME_LIMIT {
+		return string(runes[:SSH_NAME_LIMIT])
+	}
+	return user
+}
+
+// loadHostKey read pem host key, new ed25519 key is generated and saved if file not exist
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err = ioutil.WriteFile(path, data, 0600); err != nil {
+			return nil, err
+		}
+		logger.Printf("ssh host key generated: %s \n", path)
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+func NewSSHServer(addr, hostKey string, game *Game, render *MultiRender, config *GameConfig) (*SSHServer, error) {
+	signer, err := loadHostKey(hostKey)
+	if err != nil {
+		return nil, err
+	}
+	sshConfig := &ssh.ServerConfig{NoClientAuth: true}
+	sshConfig.AddHostKey(signer)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &SSHServer{
+		Game:       game,
+		GameConfig: config,
+		Render:     render,
+		config:     sshConfig,
+		listener:   listener,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	output "GoConsoleBT/output"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSHSession(t *testing.T) {
+	config, _ := NewDefaultGameConfig()
+	game, _ := NewGame(nil, nil)
+	backend, _ := output.NewConsoleOutputStream(ioutil.Discard, 80, 24)
+	primary, _ := NewRenderWithOutput(10, backend)
+	multi, _ := NewMultiRender(primary)
+
+	server, err := NewSSHServer("127.0.0.1:0", filepath.Join(t.TempDir(), "host_key"), game, multi, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go server.Serve()
+
+	client, err := ssh.Dial("tcp", server.Addr().String(), &ssh.ClientConfig{
+		User:            "friend",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, _ := session.StdinPipe()
+	stdout, _ := session.StdoutPipe()
+	if err := session.RequestPty("xterm", 24, 80, ssh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+	n, err := stdout.Read(buf)
+	if err != nil || !strings.HasPrefix(string(buf[:n]), "waiting for game start") {
+		t.Fatalf("unexpected greeting %q, err %v", buf[:n], err)
+	}
+
+	stdin.Write([]byte{3}) //ctrl+c
+	done := make(chan error)
+	go func() {
+		done <- session.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("session not closed on ctrl+c")
+	}
+	if len(game.GetPlayers()) != 0 {
+		t.Errorf("player joined game not in progress")
+	}
+}
+
+func TestViewportOffset(t *testing.T) {
+	for _, tc := range []struct {
+		pos, from, size float64
+		screen, expect  int
+	}{
+		{pos: 10, from: 0, size: 50, screen: 80, expect: 0},     //world fit screen
+		{pos: 10, from: 0, size: 200, screen: 80, expect: 0},    //near left edge
+		{pos: 100, from: 0, size: 200, screen: 80, expect: -60}, //centered
+		{pos: 190, from: 0, size: 200, screen: 80, expect: -120},
+	} {
+		if offset := viewportOffset(tc.pos, tc.from, tc.size, tc.screen); offset != tc.expect {
+			t.Errorf("viewport for %+v: expected %d, got %d", tc, tc.expect, offset)
+		}
+	}
+}
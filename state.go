@@ -74,6 +74,16 @@ func (receiver *State) Reset() error {
 	return receiver.Enter(receiver.defaultPath)
 }
 
+// GetPath return absolute path of current state, empty for nil state
+func (receiver *State) GetPath() string {
+	if receiver == nil {
+		return ""
+	}
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	return receiver.path
+}
+
 func (receiver *State) MoveTo(path string) error {
 	if DEBUG_STATE {
 		logger.Printf("attempt to move to state %s", path)
@@ -86,7 +86,11 @@ func (receiver *Tracker) MoveTo(x, y float64) error {
 		receiver.xIndex = zone.X
 		receiver.yIndex = zone.Y
 		receiver.IsNeedUpdateZone = true
-		go receiver.indexUpdate()
+		if receiver.Manager.Lockstep {
+			receiver.Manager.indexMoved(receiver)
+		} else {
+			go receiver.indexUpdate()
+		}
 	} else {
 		logger.Println(err)
 		return err
@@ -77,6 +77,12 @@ func (receiver *Unit) Execute(command controller.Command) error {
 		}
 	}
 
+	if command.CType == controller.CTYPE_ALT_FIRE && command.Action {
+		if receiver.Gun != nil {
+			receiver.Gun.AltFire() //no second slot is ok
+		}
+	}
+
 	if command.CType == controller.CTYPE_SPEED_FACTOR {
 		receiver.speedAccelerator = Point(command.Pos) //x y -> are they same
 	}
@@ -152,6 +158,7 @@ func (receiver *Unit) ReciveDamage(incoming Danger) {
 		return
 	}
 	receiver.HP -= damage
+	recordHit(receiver, nemesis, damage+minInt(receiver.HP, 0), receiver.HP <= 0)
 	if receiver.HP <= 0 {
 		receiver.Destroy(nemesis)
 	} else {
@@ -15,6 +15,7 @@ type Visioner struct {
 	queue        []Seen
 	mutex        sync.Mutex
 	total, empty int64
+	seen         []collider.Collideable //query buffer reused by every unit
 }
 
 func (receiver *Visioner) Add(object Seen) {
@@ -66,7 +67,9 @@ func (receiver *Visioner) Execute(timeLeft time.Duration) {
 		}
 		vision := object.GetVision()
 		vision.CollisionInfo().Clear()
-		for _, qObject := range receiver.collider.QueryRect(vision.GetRect()) {
+		x, y, w, h := vision.GetRect()
+		receiver.seen = receiver.collider.AppendRect(receiver.seen[:0], x, y, w, h)
+		for _, qObject := range receiver.seen {
 			vision.CollisionInfo().Add(qObject, nil)
 		}
 	}
@@ -80,6 +80,7 @@ func (receiver *Wall) ReciveDamage(incoming Danger) {
 		return
 	}
 	receiver.HP -= damage
+	recordHit(receiver, nemesis, damage+minInt(receiver.HP, 0), receiver.HP <= 0)
 	if receiver.HP <= 0 {
 		receiver.Destroy(nemesis)
 	} else {
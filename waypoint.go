@@ -0,0 +1,188 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	WAYPOINT_PRECISION   = 0.5 //distance to zone center counted as reached
+	WAYPOINT_STUCK_LIMIT = 30  //updates without progress before give up
+)
+
+var WaypointUnavailableError = errors.New("waypoint unavailable, no unit or navigation")
+
+// Waypoint drive player tank along navigation path, player get control back on arrival or on Cancel
+type Waypoint struct {
+	*Location
+	spawner  *SpawnManager
+	player   *Player
+	unit     *Unit
+	commands chan controller.Command
+	control  *controller.Control
+	path     []Zone
+	job      *NavJob
+	handed   bool //unit driven by waypoint
+	done     bool
+	last     Center
+	stuck    int
+	mutex    sync.Mutex
+}
+
+func (receiver *Waypoint) ReceivePath(path []Zone, jobId int64) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.done {
+		return
+	}
+	if len(path) == 0 {
+		logger.Printf("player %s waypoint unreachable \n", receiver.player.Name)
+		receiver.release()
+		return
+	}
+	receiver.path = path
+	receiver.spawner.HandOff(receiver.unit, receiver.control, func(previous controller.Controller, err error) {
+		receiver.mutex.Lock()
+		defer receiver.mutex.Unlock()
+		if err != nil {
+			receiver.release()
+			return
+		}
+		receiver.handed = true
+		if receiver.done { //canceled meanwhile, unit is bound to dead control
+			receiver.giveBack()
+			return
+		}
+		receiver.last = receiver.unit.GetCenter()
+		receiver.spawner.updater.Add(receiver)
+	})
+}
+
+func (receiver *Waypoint) Update(timeLeft time.Duration) error {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.done {
+		return nil
+	}
+	if receiver.player.Unit != receiver.unit || receiver.unit.destroyed {
+		receiver.release() //unit lost, despawn restore its control
+		return nil
+	}
+	center := receiver.unit.GetCenter()
+	target, err := receiver.Location.CenterByIndex(receiver.path[0].X, receiver.path[0].Y)
+	if err != nil {
+		receiver.finish()
+		return err
+	}
+	dx, dy := target.X-center.X, target.Y-center.Y
+	if math.Abs(dx) <= WAYPOINT_PRECISION && math.Abs(dy) <= WAYPOINT_PRECISION {
+		receiver.path = receiver.path[1:]
+		if len(receiver.path) == 0 {
+			receiver.finish()
+		}
+		return nil
+	}
+
+	if center.Equal(receiver.last, 0.01) {
+		if receiver.stuck++; receiver.stuck > WAYPOINT_STUCK_LIMIT {
+			logger.Printf("player %s waypoint blocked \n", receiver.player.Name)
+			receiver.finish()
+			return nil
+		}
+	} else {
+		receiver.stuck = 0
+	}
+	receiver.last = center
+
+	move := controller.Command{CType: controller.CTYPE_MOVE, Action: true}
+	if math.Abs(dy) > WAYPOINT_PRECISION { //only one direction at once
+		move.Pos.Y = math.Copysign(1, dy)
+	} else {
+		move.Pos.X = math.Copysign(1, dx)
+	}
+	receiver.send(move)
+	return nil
+}
+
+// Cancel stop driving, player control is returned
+func (receiver *Waypoint) Cancel() {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if receiver.job != nil {
+		receiver.job.Cancel()
+	}
+	if !receiver.done {
+		receiver.finish()
+	}
+}
+
+func (receiver *Waypoint) send(command controller.Command) {
+	select {
+	case receiver.commands <- command:
+	default: //unit busy, next update repeat
+	}
+}
+
+// finish stop unit and give it back to player, must be called under lock
+func (receiver *Waypoint) finish() {
+	if receiver.handed && !receiver.done {
+		receiver.send(controller.Command{CType: controller.CTYPE_MOVE, Pos: controller.PosIrrelevant, Action: false})
+	}
+	receiver.giveBack()
+	receiver.release()
+}
+
+// giveBack unit control to player (or his autopilot), must be called under lock
+func (receiver *Waypoint) giveBack() {
+	if !receiver.handed || receiver.player.Unit != receiver.unit {
+		return
+	}
+	var back controller.Controller = receiver.player.Control
+	if receiver.player.Autopilot != nil {
+		back = receiver.player.Autopilot
+	}
+	receiver.spawner.HandOff(receiver.unit, back, nil)
+}
+
+func (receiver *Waypoint) release() {
+	if receiver.done {
+		return
+	}
+	receiver.done = true
+	if receiver.handed {
+		receiver.spawner.updater.Remove(receiver)
+	}
+	close(receiver.commands)
+}
+
+// SetWaypoint schedule path for player tank to target, previous waypoint is canceled
+func (receiver *Game) SetWaypoint(player *Player, target Point) error {
+	unit := player.Unit
+	if unit == nil || unit.destroyed || receiver.Navigation == nil || receiver.Location == nil {
+		return WaypointUnavailableError
+	}
+	if player.Waypoint != nil {
+		player.Waypoint.Cancel()
+	}
+	from := receiver.Location.NearestZoneByCoordinate(Point(unit.GetCenter()))
+	to := receiver.Location.NearestZoneByCoordinate(target)
+	if from == to {
+		return nil
+	}
+	commands := make(chan controller.Command, 2)
+	control, _ := controller.NewRemoteControl(commands)
+	player.Waypoint = &Waypoint{
+		Location: receiver.Location,
+		spawner:  receiver.SpawnManager,
+		player:   player,
+		unit:     unit,
+		commands: commands,
+		control:  control,
+	}
+	job, err := receiver.Navigation.SchedulePath(from, to, player.Waypoint, NAV_PRIORITY_HIGH)
+	player.Waypoint.job = job
+	return err
+}
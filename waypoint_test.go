@@ -0,0 +1,33 @@
+package main
+
+import (
+	"GoConsoleBT/controller"
+	"testing"
+)
+
+func TestWaypointCanceledBeforeHandOff(t *testing.T) {
+	playerControl, _ := controller.NewNoneControl()
+	unit := slotTarget(Point{})
+	unit.Attributes = &Attributes{}
+	unit.ControlledObject = &ControlledObject{Control: playerControl}
+	updater, _ := NewUpdater(1)
+	spawner := &SpawnManager{
+		updater: updater,
+		spawned: map[ObjectInterface]bool{unit: true},
+		origin:  make(map[*Unit]unitOrigin),
+	}
+	player := &Player{Control: playerControl, Unit: unit, Name: "player"}
+	commands := make(chan controller.Command, 2)
+	control, _ := controller.NewRemoteControl(commands)
+	waypoint := &Waypoint{spawner: spawner, player: player, unit: unit, commands: commands, control: control}
+
+	waypoint.ReceivePath([]Zone{{X: 1}}, 1)
+	waypoint.Cancel() //before hand-off callback run
+	spawner.executeHandOff()
+	if unit.Control != controller.Controller(playerControl) {
+		t.Errorf("unit left with %T, player lost control", unit.Control)
+	}
+	if !waypoint.done || len(updater.queue) != 0 {
+		t.Error("waypoint still driving")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// minimal server side of RFC 6455, enough to push frames to browser
+const (
+	WS_OP_TEXT   = 0x1
+	WS_OP_BINARY = 0x2
+	WS_OP_CLOSE  = 0x8
+	WS_OP_PING   = 0x9
+	WS_OP_PONG   = 0xA
+
+	wsGuid         = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsMaxClientMsg = 4096
+)
+
+var (
+	WsHandshakeError = errors.New("not a websocket handshake")
+	WsClosedError    = errors.New("websocket closed")
+)
+
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	mutex  sync.Mutex
+}
+
+// Write send p as one binary message
+func (receiver *wsConn) Write(p []byte) (int, error) {
+	if err := receiver.writeFrame(WS_OP_BINARY, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (receiver *wsConn) writeFrame(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode //fin
+	switch {
+	case len(payload) < 126:
+		header[1] = byte(len(payload))
+	case len(payload) <= 0xFFFF:
+		header[1] = 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header[1] = 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if _, err := receiver.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := receiver.conn.Write(payload)
+	return err
+}
+
+// ReadMessage return next data message, control frames are answered here
+func (receiver *wsConn) ReadMessage() (opcode byte, payload []byte, err error) {
+	for {
+		var head [2]byte
+		if _, err = io.ReadFull(receiver.reader, head[:]); err != nil {
+			return 0, nil, err
+		}
+		opcode = head[0] & 0x0F
+		masked := head[1]&0x80 != 0
+		length := uint64(head[1] & 0x7F)
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err = io.ReadFull(receiver.reader, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err = io.ReadFull(receiver.reader, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		if length > wsMaxClientMsg {
+			return 0, nil, WsClosedError
+		}
+		var mask [4]byte
+		if masked {
+			if _, err = io.ReadFull(receiver.reader, mask[:]); err != nil {
+				return 0, nil, err
+			}
+		}
+		payload = make([]byte, length)
+		if _, err = io.ReadFull(receiver.reader, payload); err != nil {
+			return 0, nil, err
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+		switch opcode {
+		case WS_OP_CLOSE:
+			receiver.writeFrame(WS_OP_CLOSE, nil)
+			return opcode, nil, WsClosedError
+		case WS_OP_PING:
+			receiver.writeFrame(WS_OP_PONG, payload)
+		case WS_OP_PONG:
+		default:
+			return opcode, payload, nil
+		}
+	}
+}
+
+func (receiver *wsConn) Close() error {
+	return receiver.conn.Close()
+}
+
+func wsUpgrade(writer http.ResponseWriter, request *http.Request) (*wsConn, error) {
+	key := request.Header.Get("Sec-WebSocket-Key")
+	if key == "" || !strings.EqualFold(request.Header.Get("Upgrade"), "websocket") {
+		http.Error(writer, WsHandshakeError.Error(), http.StatusBadRequest)
+		return nil, WsHandshakeError
+	}
+	hijacker, ok := writer.(http.Hijacker)
+	if !ok {
+		http.Error(writer, WsHandshakeError.Error(), http.StatusInternalServerError)
+		return nil, WsHandshakeError
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha1.Sum([]byte(key + wsGuid))
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	buf.WriteString(base64.StdEncoding.EncodeToString(hash[:]))
+	buf.WriteString("\r\n\r\n")
+	if err = buf.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, reader: buf.Reader}, nil
+}